@@ -0,0 +1,481 @@
+// attach.go serves the attach socket, through which any number of client
+// terminals (`shine attach`, `prismctl attach`) see a live copy of the panel
+// and type into it. Output goes to the kitty panel and every client; input
+// from all of them is merged.
+//
+// Sizes are reconciled like tmux's default window-size "latest": the panel
+// is sized to the terminal that was most recently active, i.e. attached or
+// typed into. Clients attached with ignore_size never size the panel.
+//
+// With --headless there is no kitty panel at all, so prisms survive their
+// panel being closed and can be attached to again from a fresh one, much
+// like dtach. While nothing is attached output is dropped, the screen models
+// stay current, and the next client gets a full repaint at its size.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/attach"
+	"github.com/starbased-co/shine/pkg/paths"
+	"golang.org/x/sys/unix"
+)
+
+// attachWriteTimeout bounds how long a write to a stalled client may take
+// before it is dropped
+const attachWriteTimeout = time.Second
+
+// attachQueueSize is how many frames a client may fall behind by before it
+// is dropped
+const attachQueueSize = 256
+
+type attachFrame struct {
+	typ     byte
+	payload []byte
+}
+
+// attachClient is one terminal connected to the attach socket. Frames are
+// written by its own writeLoop, so a slow client holds up nobody else.
+type attachClient struct {
+	conn       net.Conn
+	out        chan attachFrame // closed by socketTerminal.remove
+	done       chan struct{}    // closed when writeLoop returns
+	size       unix.Winsize
+	readOnly   bool
+	ignoreSize bool
+	active     time.Time // last attach or input
+}
+
+// writeLoop writes the client's frames until it is detached or a write
+// fails
+func (c *attachClient) writeLoop() {
+	defer close(c.done)
+
+	for frame := range c.out {
+		c.conn.SetWriteDeadline(time.Now().Add(attachWriteTimeout))
+		if err := attach.WriteFrame(c.conn, frame.typ, frame.payload); err != nil {
+			log.Printf("Attach: dropping client: %v", err)
+			// serve notices the closed connection and removes the client
+			c.conn.Close()
+			return
+		}
+
+		if frame.typ == attach.MsgDetach {
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// socketTerminal is the RealTerminal of a prismctl with an attach socket:
+// the kitty panel's terminal, if any, plus every attached client
+type socketTerminal struct {
+	listener net.Listener
+	path     string
+	primary  RealTerminal // kitty panel PTY, nil when headless
+	input    chan []byte
+	pending  []byte // rest of the last input chunk, only touched by Read
+	closed   chan struct{}
+	once     sync.Once
+
+	mu            sync.Mutex
+	clients       []*attachClient
+	primaryActive time.Time
+	sizer         *attachClient // terminal the panel is sized to, nil = primary
+	lastSize      unix.Winsize  // used while nothing is attached when headless
+	onChange      func()        // called after the size or set of terminals changed
+}
+
+// listenAttach creates the attach socket at path. primary is the kitty
+// panel's terminal, nil for a headless prismctl.
+func listenAttach(path string, primary RealTerminal) (*socketTerminal, error) {
+	// A socket left behind by a crashed prismctl would make Listen fail
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	t := &socketTerminal{
+		listener: listener,
+		path:     path,
+		primary:  primary,
+		input:    make(chan []byte, 16),
+		closed:   make(chan struct{}),
+		lastSize: unix.Winsize{Col: 80, Row: 24},
+	}
+
+	go t.accept()
+	if primary != nil {
+		go t.readPrimary()
+	}
+
+	return t, nil
+}
+
+// setOnChange installs fn, called without any lock held whenever the
+// terminal needs to be resized and repainted
+func (t *socketTerminal) setOnChange(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onChange = fn
+}
+
+func (t *socketTerminal) accept() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Attach: accept error: %v", err)
+			}
+			return
+		}
+		go t.serve(conn)
+	}
+}
+
+// readPrimary feeds input typed into the kitty panel into the merged input
+func (t *socketTerminal) readPrimary() {
+	for {
+		buf := make([]byte, 4096)
+		n, err := t.primary.Read(buf)
+		if n > 0 {
+			t.touch(nil)
+			select {
+			case t.input <- buf[:n]:
+			case <-t.closed:
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF && !isExpectedPTYError(err) {
+				log.Printf("Attach: panel read error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// serve handles one client from its hello until it disconnects
+func (t *socketTerminal) serve(conn net.Conn) {
+	defer conn.Close()
+
+	typ, payload, err := attach.ReadFrame(conn)
+	if err != nil || typ != attach.MsgHello {
+		log.Printf("Attach: client did not say hello: %v", err)
+		return
+	}
+
+	var hello attach.Hello
+	if err := json.Unmarshal(payload, &hello); err != nil {
+		log.Printf("Attach: invalid hello: %v", err)
+		return
+	}
+
+	client := &attachClient{
+		conn:       conn,
+		out:        make(chan attachFrame, attachQueueSize),
+		done:       make(chan struct{}),
+		readOnly:   hello.ReadOnly,
+		ignoreSize: hello.IgnoreSize,
+		active:     time.Now(),
+	}
+	setClientSize(client, hello.Size)
+	go client.writeLoop()
+
+	t.mu.Lock()
+	t.clients = append(t.clients, client)
+	t.reconcileLocked()
+	t.mu.Unlock()
+
+	log.Printf("Attach: client attached (%dx%d, read-only: %v, ignore-size: %v)",
+		hello.Size.Cols, hello.Size.Rows, hello.ReadOnly, hello.IgnoreSize)
+	t.changed()
+
+	defer t.remove(client)
+
+	for {
+		typ, payload, err := attach.ReadFrame(conn)
+		if err != nil {
+			return
+		}
+
+		switch typ {
+		case attach.MsgInput:
+			if client.readOnly {
+				continue
+			}
+			t.touch(client)
+			select {
+			case t.input <- payload:
+			case <-t.closed:
+				return
+			}
+
+		case attach.MsgResize:
+			size, err := attach.DecodeSize(payload)
+			if err != nil {
+				log.Printf("Attach: %v", err)
+				continue
+			}
+
+			t.mu.Lock()
+			setClientSize(client, size)
+			sizing := t.sizer == client
+			if sizing {
+				t.lastSize = client.size
+			}
+			t.mu.Unlock()
+
+			if sizing {
+				t.changed()
+			}
+		}
+	}
+}
+
+func setClientSize(client *attachClient, size attach.Size) {
+	if size.Cols == 0 || size.Rows == 0 {
+		return
+	}
+	client.size = unix.Winsize{Col: size.Cols, Row: size.Rows, Xpixel: size.Xpixel, Ypixel: size.Ypixel}
+}
+
+// touch records input from client (nil = the kitty panel), which may make
+// it the terminal the panel is sized to
+func (t *socketTerminal) touch(client *attachClient) {
+	t.mu.Lock()
+	if client == nil {
+		t.primaryActive = time.Now()
+	} else {
+		client.active = time.Now()
+	}
+	changed := t.reconcileLocked()
+	t.mu.Unlock()
+
+	if changed {
+		t.changed()
+	}
+}
+
+// remove forgets a client that disconnected
+func (t *socketTerminal) remove(client *attachClient) {
+	t.mu.Lock()
+	found := false
+	for i, c := range t.clients {
+		if c == client {
+			t.clients = append(t.clients[:i], t.clients[i+1:]...)
+			close(client.out)
+			found = true
+			break
+		}
+	}
+	t.reconcileLocked()
+	t.mu.Unlock()
+
+	if found {
+		log.Printf("Attach: client detached")
+		t.changed()
+	}
+}
+
+// reconcileLocked picks the terminal the panel is sized to: the most
+// recently active one that may size it. It reports whether that changed.
+// Assumes caller holds t.mu lock
+func (t *socketTerminal) reconcileLocked() bool {
+	var sizer *attachClient
+	latest := t.primaryActive
+
+	for _, c := range t.clients {
+		if c.ignoreSize {
+			continue
+		}
+		// Without a kitty panel any client beats none
+		if c.active.After(latest) || (sizer == nil && t.primary == nil) {
+			sizer, latest = c, c.active
+		}
+	}
+
+	if sizer != nil {
+		t.lastSize = sizer.size
+	}
+
+	changed := sizer != t.sizer
+	t.sizer = sizer
+	return changed
+}
+
+// queueLocked queues a frame for client, dropping the client when it has
+// fallen too far behind
+// Assumes caller holds t.mu lock
+func (t *socketTerminal) queueLocked(client *attachClient, typ byte, payload []byte) {
+	select {
+	case client.out <- attachFrame{typ: typ, payload: payload}:
+	default:
+		log.Printf("Attach: dropping client %d frames behind", attachQueueSize)
+		// serve notices the closed connection and removes the client
+		client.conn.Close()
+	}
+}
+
+// detachLocked tells a client why it is being detached and drops it once
+// that has been written
+// Assumes caller holds t.mu lock
+func (t *socketTerminal) detachLocked(client *attachClient, reason string) {
+	t.queueLocked(client, attach.MsgDetach, []byte(reason))
+}
+
+func (t *socketTerminal) changed() {
+	t.mu.Lock()
+	fn := t.onChange
+	t.mu.Unlock()
+
+	if fn != nil {
+		fn()
+	}
+}
+
+// Read returns input from the kitty panel and every client that is not
+// read-only. Only the mirror goroutine reads.
+func (t *socketTerminal) Read(p []byte) (int, error) {
+	if len(t.pending) == 0 {
+		select {
+		case t.pending = <-t.input:
+		case <-t.closed:
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+// Write sends output to the kitty panel and queues it for every client. A
+// client that cannot keep up is dropped; with nothing attached, output is
+// dropped.
+func (t *socketTerminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	if len(t.clients) > 0 {
+		// Queued frames outlive the caller's buffer
+		frame := append([]byte(nil), p...)
+		for _, c := range t.clients {
+			t.queueLocked(c, attach.MsgOutput, frame)
+		}
+	}
+	t.mu.Unlock()
+
+	if t.primary != nil {
+		return t.primary.Write(p)
+	}
+	return len(p), nil
+}
+
+func (t *socketTerminal) Size() (*unix.Winsize, error) {
+	t.mu.Lock()
+	sizer, size := t.sizer, t.lastSize
+	t.mu.Unlock()
+
+	if sizer == nil && t.primary != nil {
+		return t.primary.Size()
+	}
+	return &size, nil
+}
+
+// Reset resets the kitty panel's terminal. Clients have no termios to
+// reset: they stay in raw mode while attached.
+func (t *socketTerminal) Reset() error {
+	if t.primary != nil {
+		return t.primary.Reset()
+	}
+	return nil
+}
+
+// Restore detaches every client, removes the socket and restores the kitty
+// panel's terminal
+func (t *socketTerminal) Restore() error {
+	t.once.Do(func() {
+		close(t.closed)
+		t.listener.Close()
+
+		t.mu.Lock()
+		clients := append([]*attachClient(nil), t.clients...)
+		for _, c := range clients {
+			t.detachLocked(c, "prismctl exited")
+		}
+		t.mu.Unlock()
+
+		// Give clients the chance to learn why before prismctl exits
+		deadline := time.NewTimer(attachWriteTimeout)
+		defer deadline.Stop()
+	wait:
+		for _, c := range clients {
+			select {
+			case <-c.done:
+			case <-deadline.C:
+				for _, c := range clients {
+					c.conn.Close()
+				}
+				break wait
+			}
+		}
+
+		os.Remove(t.path)
+	})
+
+	if t.primary != nil {
+		return t.primary.Restore()
+	}
+	return nil
+}
+
+// redraw resizes every prism to the real terminal and repaints the whole
+// screen, e.g. for a client that just attached
+func (s *supervisor) redraw() {
+	s.propagateResize()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasForeground() || s.picker != nil {
+		// propagateResize already drew the picker or the idle screen
+		return
+	}
+
+	if err := s.activateMirrorToForeground(); err != nil {
+		log.Printf("Warning: failed to repaint %s: %v", s.prismList[0].name, err)
+	}
+}
+
+// runAttach attaches this terminal to the prismctl of instance
+func runAttach(instance string) error {
+	reason, err := attach.Attach(paths.PrismAttachSocket(instance), os.Stdin, os.Stdout, attach.Options{
+		DetachKey:  attach.DefaultDetachKey,
+		Detachable: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if reason == "" {
+		reason = "detached"
+	}
+	fmt.Printf("\r\n[%s]\r\n", reason)
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/attach"
+	"golang.org/x/sys/unix"
+)
+
+// dialAttach connects a client of the given size to the attach socket
+func dialAttach(t *testing.T, path string, cols, rows uint16) net.Conn {
+	t.Helper()
+	return dialClient(t, path, attach.Hello{Size: attach.Size{Cols: cols, Rows: rows}})
+}
+
+// readFrame reads the next frame of the given type, skipping others
+func readFrame(t *testing.T, conn net.Conn, want byte) []byte {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		typ, payload, err := attach.ReadFrame(conn)
+		if err != nil {
+			t.Fatalf("ReadFrame() error waiting for %q: %v", want, err)
+		}
+		if typ == want {
+			return payload
+		}
+	}
+}
+
+// dialClient connects a client with the given hello to the attach socket
+func dialClient(t *testing.T, path string, hello attach.Hello) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := attach.WriteJSON(conn, attach.MsgHello, hello); err != nil {
+		t.Fatalf("failed to send hello: %v", err)
+	}
+	return conn
+}
+
+func termSize(t *testing.T, term RealTerminal) string {
+	t.Helper()
+	size, err := term.Size()
+	if err != nil {
+		t.Fatalf("Size() error: %v", err)
+	}
+	return fmt.Sprintf("%dx%d", size.Col, size.Row)
+}
+
+// readInput reads n bytes of merged input
+func readInput(t *testing.T, term RealTerminal, n int) string {
+	t.Helper()
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(term, buf); err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	return string(buf)
+}
+
+func TestSocketTerminal_Headless(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attach.sock")
+
+	term, err := listenAttach(path, nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	var changes atomic.Int32
+	term.setOnChange(func() { changes.Add(1) })
+
+	// Output with nothing attached is dropped
+	if n, err := term.Write([]byte("lost")); err != nil || n != 4 {
+		t.Errorf("Write() while detached = %d, %v, want dropped", n, err)
+	}
+	if got := termSize(t, term); got != "80x24" {
+		t.Errorf("Size() while detached = %s, want 80x24", got)
+	}
+
+	conn := dialAttach(t, path, 100, 30)
+	waitFor(t, func() bool { return changes.Load() == 1 })
+
+	if got := termSize(t, term); got != "100x30" {
+		t.Errorf("Size() = %s, want the client's 100x30", got)
+	}
+
+	term.Write([]byte("hello"))
+	if got := readFrame(t, conn, attach.MsgOutput); string(got) != "hello" {
+		t.Errorf("client output = %q, want hello", got)
+	}
+
+	attach.WriteFrame(conn, attach.MsgInput, []byte("typed"))
+	if got := readInput(t, term, 3); got != "typ" {
+		t.Errorf("Read() = %q, want typ", got)
+	}
+	if got := readInput(t, term, 2); got != "ed" {
+		t.Errorf("Read() = %q, want the rest of the frame", got)
+	}
+
+	attach.WriteJSON(conn, attach.MsgResize, attach.Size{Cols: 120, Rows: 40})
+	waitFor(t, func() bool { return changes.Load() == 2 })
+	if got := termSize(t, term); got != "120x40" {
+		t.Errorf("Size() after resize = %s, want 120x40", got)
+	}
+
+	// The last client to leave keeps its size for the next one
+	conn.Close()
+	waitFor(t, func() bool { return changes.Load() == 3 })
+	if got := termSize(t, term); got != "120x40" {
+		t.Errorf("Size() after detach = %s, want 120x40 kept", got)
+	}
+
+	last := dialAttach(t, path, 90, 20)
+	waitFor(t, func() bool { return changes.Load() == 4 })
+
+	term.Restore()
+	if reason := readFrame(t, last, attach.MsgDetach); string(reason) != "prismctl exited" {
+		t.Errorf("detach reason on exit = %q", reason)
+	}
+	if _, err := term.Read(make([]byte, 1)); err == nil {
+		t.Error("Read() after Restore() should fail")
+	}
+}
+
+// fakePanel stands in for the kitty panel's terminal
+type fakePanel struct {
+	in   *os.File
+	out  syncBuffer
+	size unix.Winsize
+}
+
+func (p *fakePanel) Read(b []byte) (int, error)   { return p.in.Read(b) }
+func (p *fakePanel) Write(b []byte) (int, error)  { return p.out.Write(b) }
+func (p *fakePanel) Size() (*unix.Winsize, error) { size := p.size; return &size, nil }
+func (p *fakePanel) Reset() error                 { return nil }
+func (p *fakePanel) Restore() error               { return nil }
+
+func TestSocketTerminal_MultipleClients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attach.sock")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer w.Close()
+	panel := &fakePanel{in: r, size: unix.Winsize{Col: 200, Row: 1}}
+
+	term, err := listenAttach(path, panel)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	if got := termSize(t, term); got != "200x1" {
+		t.Errorf("Size() with only the panel = %s, want 200x1", got)
+	}
+
+	// The latest terminal to attach sizes the panel, like tmux
+	writer := dialClient(t, path, attach.Hello{Size: attach.Size{Cols: 100, Rows: 30}})
+	waitFor(t, func() bool { return termSize(t, term) == "100x30" })
+
+	viewer := dialClient(t, path, attach.Hello{Size: attach.Size{Cols: 90, Rows: 20}, ReadOnly: true})
+	waitFor(t, func() bool { return termSize(t, term) == "90x20" })
+
+	// ignore_size clients never size the panel
+	dialClient(t, path, attach.Hello{Size: attach.Size{Cols: 300, Rows: 90}, IgnoreSize: true})
+	time.Sleep(20 * time.Millisecond)
+	if got := termSize(t, term); got != "90x20" {
+		t.Errorf("Size() after ignore_size client = %s, want 90x20", got)
+	}
+
+	// Output reaches the panel and every client
+	term.Write([]byte("frame"))
+	for _, conn := range []net.Conn{writer, viewer} {
+		if got := readFrame(t, conn, attach.MsgOutput); string(got) != "frame" {
+			t.Errorf("client output = %q, want frame", got)
+		}
+	}
+	if got := panel.out.String(); got != "frame" {
+		t.Errorf("panel output = %q, want frame", got)
+	}
+
+	// Read-only input is dropped; typing makes a terminal the latest
+	attach.WriteFrame(viewer, attach.MsgInput, []byte("v"))
+	attach.WriteFrame(writer, attach.MsgInput, []byte("w"))
+	if got := readInput(t, term, 1); got != "w" {
+		t.Errorf("Read() = %q, want the writer's input only", got)
+	}
+	waitFor(t, func() bool { return termSize(t, term) == "100x30" })
+
+	w.Write([]byte("p"))
+	if got := readInput(t, term, 1); got != "p" {
+		t.Errorf("Read() = %q, want the panel's input", got)
+	}
+	waitFor(t, func() bool { return termSize(t, term) == "200x1" })
+
+	// When the latest client detaches the next most recent one takes over
+	attach.WriteFrame(writer, attach.MsgInput, []byte("w"))
+	readInput(t, term, 1)
+	waitFor(t, func() bool { return termSize(t, term) == "100x30" })
+	writer.Close()
+	waitFor(t, func() bool { return termSize(t, term) == "200x1" })
+}
+
+// TestSocketTerminal_StalledClient tests that a client that stops reading
+// is dropped without holding up output to anyone else
+func TestSocketTerminal_StalledClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attach.sock")
+
+	term, err := listenAttach(path, nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	var changes atomic.Int32
+	term.setOnChange(func() { changes.Add(1) })
+
+	stalled := dialAttach(t, path, 80, 24)
+	reader := dialAttach(t, path, 80, 24)
+	waitFor(t, func() bool { return changes.Load() == 2 })
+
+	const writes, chunk = 2 * attachQueueSize, 4096
+
+	var received atomic.Int64
+	go func() {
+		reader.SetReadDeadline(time.Now().Add(10 * time.Second))
+		for {
+			typ, payload, err := attach.ReadFrame(reader)
+			if err != nil {
+				return
+			}
+			if typ == attach.MsgOutput {
+				received.Add(int64(len(payload)))
+			}
+		}
+	}()
+
+	// Written at the pace of the reader, which keeps up while the stalled
+	// client falls behind
+	data := []byte(strings.Repeat("x", chunk))
+	var slowest time.Duration
+	for i := 1; i <= writes; i++ {
+		start := time.Now()
+		term.Write(data)
+		slowest = max(slowest, time.Since(start))
+
+		deadline := time.Now().Add(time.Second)
+		for received.Load() < int64(i*chunk) {
+			if time.Now().After(deadline) {
+				t.Fatalf("reader received %d bytes, want %d", received.Load(), i*chunk)
+			}
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+	if slowest > attachWriteTimeout/2 {
+		t.Errorf("Write() took up to %v with a stalled client attached", slowest)
+	}
+
+	// The stalled client has been disconnected
+	waitFor(t, func() bool { return changes.Load() == 3 })
+	stalled.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.Copy(io.Discard, stalled); err != nil {
+		t.Errorf("stalled client read error = %v, want EOF once dropped", err)
+	}
+}
+
+func TestSupervisor_RedrawForAttachedClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attach.sock")
+
+	term, err := listenAttach(path, nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	sup := newSupervisor(term, nil, nil)
+	sup.setIdle(true, idleScreen{mode: "text"})
+	term.setOnChange(sup.redraw)
+
+	_, prism := startShell(t, "clock", "while :; do sleep 0.02; done", unix.SIGTERM, time.Second)
+	master, slave, err := allocatePTY()
+	if err != nil {
+		t.Fatalf("allocatePTY() error: %v", err)
+	}
+	defer slave.Close()
+	prism.ptyMaster = master
+	prism.output = startPrismOutput(master, 80, 24)
+	prism.state = prismBackground
+	sup.prismList = append(sup.prismList, prism)
+
+	// The panel is idle: a new client is shown the placeholder at its size,
+	// and the background prism is resized to match
+	conn := dialAttach(t, path, 60, 10)
+	if got := string(readFrame(t, conn, attach.MsgOutput)); !strings.Contains(got, idleMessage) || !strings.Contains(got, "clock") {
+		t.Errorf("attached client got %q, want the idle screen", got)
+	}
+
+	waitFor(t, func() bool {
+		cols, rows, err := ptySize(int(master.Fd()))
+		return err == nil && cols == 60 && rows == 10
+	})
+}
@@ -0,0 +1,280 @@
+// child.go serves the child socket, through which prisms talk back to the
+// prismctl running them. Every prism is started with the socket's path in
+// SHINE_PRISMCTL_SOCKET and its instance ID in SHINE_PRISM, and may ask to
+// be brought to the foreground, yield it, set a status string shown in
+// prism/list, request attention, ask where it stands (see lifecycle.go),
+// send heartbeats for its liveness probe (see liveness.go) or close the
+// whole panel.
+//
+// A prism can only act on itself: a connection is attributed by its peer
+// credentials, once when it is accepted, to the prism whose process, process
+// group or session the peer belongs to, never by a name it sends. Prisms lead
+// their own session, so this covers every process they spawn that has not
+// moved to a session of its own. The same attribution keeps prisms from the
+// privileged methods of the main socket (see handlers.go), whose path they
+// can work out from SHINE_PANEL.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/handler"
+	"github.com/starbased-co/shine/pkg/paths"
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+// maxPrismStatus bounds the status string a prism can set
+const maxPrismStatus = 256
+
+type childHandlers struct {
+	supervisor *supervisor
+	rpc        *rpcHandlers // the foreground and background operations
+}
+
+func newChildHandlers(sup *supervisor) handler.Map {
+	h := &childHandlers{
+		supervisor: sup,
+		rpc:        &rpcHandlers{supervisor: sup},
+	}
+
+	return handler.Map{
+		"child/foreground": handler.New(h.handleForeground),
+		"child/yield":      handler.New(h.handleYield),
+		"child/status":     handler.New(h.handleStatus),
+		"child/attention":  handler.New(h.handleAttention),
+		"child/exit-panel": handler.New(h.handleExitPanel),
+		"child/lifecycle":  handler.New(h.handleLifecycle),
+		"child/heartbeat":  handler.New(h.handleHeartbeat),
+	}
+}
+
+// startChildServer serves the child socket of instance and makes the
+// supervisor pass its path and the panel's name to prisms started from now on
+func startChildServer(instance string, sup *supervisor) (*rpc.Server, error) {
+	socketPath := paths.PrismChildSocket(instance)
+
+	opts := &jrpc2.ServerOptions{
+		Logger: func(text string) {
+			log.Printf("Child RPC: %s", text)
+		},
+	}
+
+	server := rpc.NewServer(socketPath, newChildHandlers(sup), opts, rpc.WithPeerContext(sup.peerContext))
+	if err := server.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start child RPC server: %w", err)
+	}
+
+	sup.mu.Lock()
+	sup.panel = instance
+	sup.childSocket = socketPath
+	sup.mu.Unlock()
+
+	log.Printf("Child RPC server listening on: %s", socketPath)
+
+	return server, nil
+}
+
+// callerKey is the context key of the prism a connection is attributed to
+type callerKey struct{}
+
+// caller is the prism a connection was attributed to when it was accepted
+type caller struct {
+	name string
+	pid  int
+}
+
+// peerContext attributes a connection to the prism whose process, process
+// group or session its peer belongs to. It runs once, when the connection is
+// accepted, so a peer PID reused later cannot be credited to another prism.
+func (s *supervisor) peerContext(ctx context.Context, cred *unix.Ucred) context.Context {
+	pid := int(cred.Pid)
+	pgid, _ := unix.Getpgid(pid)
+	sid, _ := unix.Getsid(pid)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.prismList {
+		if p.pid <= 1 {
+			continue
+		}
+		if p.pid == pid || p.pid == pgid || p.pid == sid {
+			return context.WithValue(ctx, callerKey{}, caller{name: p.name, pid: p.pid})
+		}
+	}
+
+	return ctx
+}
+
+// connPrism returns the name of the prism the connection of ctx was
+// attributed to, if any
+func connPrism(ctx context.Context) (string, bool) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	return c.name, ok
+}
+
+// callerPrism returns the name of the prism that sent the request in ctx
+func (s *supervisor) callerPrism(ctx context.Context) (string, error) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	if !ok {
+		cred, ok := rpc.PeerCred(ctx)
+		if !ok {
+			return "", rpc.ErrPermissionDenied("caller credentials unavailable")
+		}
+		return "", rpc.ErrPermissionDenied(fmt.Sprintf("process %d is not a prism of this panel", cred.Pid))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A prism restarted since the connection was accepted is a new process
+	if idx := s.findPrism(c.name); idx == -1 || s.prismList[idx].pid != c.pid {
+		return "", rpc.ErrPermissionDenied(fmt.Sprintf("prism %s has exited since connecting", c.name))
+	}
+
+	return c.name, nil
+}
+
+func (h *childHandlers) handleForeground(ctx context.Context) (*rpc.FgResult, error) {
+	name, err := h.supervisor.callerPrism(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Child RPC: %s requests the foreground", name)
+	return h.rpc.handleFg(ctx, &rpc.FgRequest{Name: name})
+}
+
+// handleYield sends the caller to the background. The reply reaches it once
+// it is resumed, as a background prism is suspended.
+func (h *childHandlers) handleYield(ctx context.Context) (*rpc.BgResult, error) {
+	name, err := h.supervisor.callerPrism(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Child RPC: %s yields the foreground", name)
+	return h.rpc.handleBg(ctx, &rpc.BgRequest{Name: name})
+}
+
+func (h *childHandlers) handleStatus(ctx context.Context, req *rpc.ChildStatusRequest) (*rpc.ChildResult, error) {
+	if len(req.Status) > maxPrismStatus {
+		return nil, rpc.ErrInvalidParams(fmt.Sprintf("status longer than %d bytes", maxPrismStatus))
+	}
+
+	name, err := h.supervisor.callerPrism(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h.supervisor.mu.Lock()
+	defer h.supervisor.mu.Unlock()
+
+	if idx := h.supervisor.findPrism(name); idx != -1 {
+		h.supervisor.prismList[idx].status = req.Status
+	}
+
+	return &rpc.ChildResult{Name: name}, nil
+}
+
+// handleAttention flags a prism that is not in the foreground until it is
+// brought there, and shows a desktop notification either way
+func (h *childHandlers) handleAttention(ctx context.Context, req *rpc.ChildAttentionRequest) (*rpc.ChildResult, error) {
+	name, err := h.supervisor.callerPrism(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Child RPC: %s requests attention: %s", name, req.Message)
+
+	h.supervisor.mu.Lock()
+	if idx := h.supervisor.findPrism(name); idx != -1 && (idx != 0 || !h.supervisor.hasForeground()) {
+		h.supervisor.prismList[idx].attention = true
+	}
+	h.supervisor.mu.Unlock()
+
+	go func() {
+		if err := desktopNotify("shine: "+name, req.Message); err != nil {
+			log.Printf("Warning: failed to send desktop notification: %v", err)
+		}
+	}()
+
+	return &rpc.ChildResult{Name: name}, nil
+}
+
+// handleHeartbeat records that the caller is still processing events
+func (h *childHandlers) handleHeartbeat(ctx context.Context) (*rpc.ChildResult, error) {
+	name, err := h.supervisor.callerPrism(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h.supervisor.mu.Lock()
+	defer h.supervisor.mu.Unlock()
+
+	if idx := h.supervisor.findPrism(name); idx != -1 && h.supervisor.prismList[idx].heartbeat != nil {
+		h.supervisor.prismList[idx].heartbeat.beat()
+	}
+
+	return &rpc.ChildResult{Name: name}, nil
+}
+
+func (h *childHandlers) handleExitPanel(ctx context.Context) (*rpc.ChildResult, error) {
+	name, err := h.supervisor.callerPrism(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Child RPC: %s closes the panel", name)
+
+	go h.supervisor.shutdown()
+
+	return &rpc.ChildResult{Name: name}, nil
+}
+
+// handleLifecycle tells the caller where it stands, e.g. after its
+// lifecycle signal
+func (h *childHandlers) handleLifecycle(ctx context.Context) (*rpc.LifecycleResult, error) {
+	name, err := h.supervisor.callerPrism(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h.supervisor.mu.Lock()
+	defer h.supervisor.mu.Unlock()
+
+	idx := h.supervisor.findPrism(name)
+	if idx == -1 {
+		return nil, rpc.ErrPrismNotFound(name)
+	}
+	state := h.supervisor.lifecycleOf(idx)
+
+	return &rpc.LifecycleResult{
+		Name:       name,
+		Foreground: state.foreground,
+		Focused:    state.focused,
+		Hidden:     state.hidden,
+	}, nil
+}
+
+// childEnv returns the variables that identify a prism to itself and point
+// it at the child socket
+// Assumes caller holds s.mu lock
+func (s *supervisor) childEnv(name string) []string {
+	env := []string{
+		rpc.EnvPrism + "=" + name,
+		rpc.EnvApp + "=" + s.instanceApp(name),
+	}
+	if s.panel != "" {
+		env = append(env, rpc.EnvPanel+"="+s.panel)
+	}
+	if s.childSocket != "" {
+		env = append(env, rpc.EnvPrismctlSocket+"="+s.childSocket)
+	}
+	return env
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/creachadair/jrpc2"
+	"github.com/starbased-co/shine/pkg/prism"
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+func startChildSocket(t *testing.T, sup *supervisor) *rpc.ChildClient {
+	t.Helper()
+
+	sockPath := filepath.Join(t.TempDir(), "child.sock")
+	srv := rpc.NewServer(sockPath, newChildHandlers(sup), nil, rpc.WithPeerContext(sup.peerContext))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewChildClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewChildClient() error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+// helperEnv selects what the test binary does when run as a prism by
+// startHelperPrism
+const helperEnv = "PRISMCTL_TEST_HELPER"
+
+// TestHelperPrism is not a test: it is the prism started by
+// startHelperPrism, a real child of the package's own test binary
+func TestHelperPrism(t *testing.T) {
+	switch os.Getenv(helperEnv) {
+	case "":
+		t.Skip("only run as a prism")
+	case "exit-panel":
+		client, err := prism.Connect()
+		if err != nil {
+			os.Exit(2)
+		}
+		if _, err := client.ExitPanel(context.Background()); err != nil {
+			os.Exit(3)
+		}
+	case "sdk":
+		if _, err := prism.Run(helperModel{}); err != nil {
+			os.Exit(2)
+		}
+		os.Exit(0)
+	}
+
+	// Wait to be stopped
+	time.Sleep(30 * time.Second)
+	os.Exit(0)
+}
+
+// helperModel is the model of the "sdk" helper prism, which shows "ready"
+// until it is stopped
+type helperModel struct{}
+
+func (helperModel) Init() tea.Cmd                         { return nil }
+func (m helperModel) Update(tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
+func (helperModel) View() string                          { return "ready" }
+
+// startHelperPrism runs TestHelperPrism in its own process group as a prism
+// of the panel whose child socket is at sockPath
+func startHelperPrism(t *testing.T, name, helper, sockPath string) prismInstance {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperPrism$")
+	cmd.Env = append(os.Environ(), helperEnv+"="+helper, rpc.EnvPrismctlSocket+"="+sockPath)
+	cmd.SysProcAttr = &unix.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start %s: %v", name, err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	return prismInstance{
+		name:        name,
+		pid:         cmd.Process.Pid,
+		state:       prismForeground,
+		stopSignal:  unix.SIGTERM,
+		stopTimeout: time.Second,
+		exit:        newPrismExit(),
+	}
+}
+
+func TestChildSocket_RejectsOtherProcesses(t *testing.T) {
+	sup := newSupervisor(nil, nil, nil)
+
+	// A prism in its own process group, which this test process is not part of
+	_, prism := startShell(t, "clock", "sleep 30", unix.SIGTERM, time.Second)
+	sup.prismList = append(sup.prismList, prism)
+
+	client := startChildSocket(t, sup)
+
+	_, err := client.SetStatus(context.Background(), "impersonated")
+	if jrpc2.ErrorCode(err) != jrpc2.Code(rpc.CodePermissionDenied) {
+		t.Fatalf("SetStatus() from a foreign process = %v, want permission denied", err)
+	}
+
+	if status := sup.prismList[0].status; status != "" {
+		t.Errorf("status = %q, want it unchanged", status)
+	}
+}
+
+func TestChildSocket_AttributesAtConnect(t *testing.T) {
+	sup := newSupervisor(nil, nil, nil)
+
+	// Connected before the test process stands in for a prism
+	early := startChildSocket(t, sup)
+	ctx := context.Background()
+
+	if _, err := early.SetStatus(ctx, "early"); jrpc2.ErrorCode(err) != jrpc2.Code(rpc.CodePermissionDenied) {
+		t.Fatalf("SetStatus() from a foreign process = %v, want permission denied", err)
+	}
+
+	sup.mu.Lock()
+	sup.prismList = append(sup.prismList, prismInstance{name: "chat", pid: os.Getpid(), state: prismForeground})
+	sup.mu.Unlock()
+
+	client := startChildSocket(t, sup)
+
+	if _, err := early.SetStatus(ctx, "late"); jrpc2.ErrorCode(err) != jrpc2.Code(rpc.CodePermissionDenied) {
+		t.Errorf("SetStatus() on a connection accepted before the prism started = %v, want permission denied", err)
+	}
+	if _, err := client.SetStatus(ctx, "ok"); err != nil {
+		t.Fatalf("SetStatus() error: %v", err)
+	}
+
+	// chat restarted as another process: the connection was made by the old one
+	sup.mu.Lock()
+	sup.prismList[0].pid = os.Getppid()
+	sup.mu.Unlock()
+
+	if _, err := client.SetStatus(ctx, "stale"); jrpc2.ErrorCode(err) != jrpc2.Code(rpc.CodePermissionDenied) {
+		t.Errorf("SetStatus() after chat restarted = %v, want permission denied", err)
+	}
+	if status := sup.prismList[0].status; status != "ok" {
+		t.Errorf("status = %q, want ok", status)
+	}
+}
+
+func TestRPC_RefusesPrivilegedToPrisms(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	sup := newSupervisor(nil, nil, nil)
+	// The test process stands in for a prism
+	sup.prismList = append(sup.prismList, prismInstance{name: "chat", pid: os.Getpid(), state: prismForeground})
+
+	srv := rpc.NewServer(sockPath, newRPCHandlers(sup, nil), nil, rpc.WithPeerContext(sup.peerContext))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.List(ctx); err != nil {
+		t.Errorf("List() from a prism error: %v", err)
+	}
+	if _, err := client.Down(ctx, "chat"); jrpc2.ErrorCode(err) != jrpc2.Code(rpc.CodePermissionDenied) {
+		t.Errorf("Down() from a prism = %v, want permission denied", err)
+	}
+	if len(sup.prismList) != 1 {
+		t.Errorf("prisms = %d, want chat left running", len(sup.prismList))
+	}
+}
+
+func TestChildSocket_StatusAndAttention(t *testing.T) {
+	sup := newSupervisor(nil, nil, nil)
+
+	var notified string
+	done := make(chan struct{})
+	notify := desktopNotify
+	desktopNotify = func(summary, body string) error {
+		notified = summary + ": " + body
+		close(done)
+		return nil
+	}
+	defer func() { desktopNotify = notify }()
+
+	// The test process stands in for a background prism
+	sup.prismList = append(sup.prismList,
+		prismInstance{name: "bar", pid: 1, state: prismForeground},
+		prismInstance{name: "chat", pid: os.Getpid(), state: prismBackground},
+	)
+
+	client := startChildSocket(t, sup)
+	ctx := context.Background()
+
+	result, err := client.SetStatus(ctx, "3 unread")
+	if err != nil {
+		t.Fatalf("SetStatus() error: %v", err)
+	}
+	if result.Name != "chat" {
+		t.Errorf("SetStatus() attributed to %q, want chat", result.Name)
+	}
+
+	if _, err := client.SetStatus(ctx, strings.Repeat("x", maxPrismStatus+1)); jrpc2.ErrorCode(err) != jrpc2.Code(rpc.CodeInvalidParams) {
+		t.Errorf("SetStatus() with an oversized status = %v, want invalid params", err)
+	}
+
+	if _, err := client.RequestAttention(ctx, "new message"); err != nil {
+		t.Fatalf("RequestAttention() error: %v", err)
+	}
+	<-done
+	if notified != "shine: chat: new message" {
+		t.Errorf("notification = %q", notified)
+	}
+
+	h := &rpcHandlers{supervisor: sup}
+	list, err := h.handleList(ctx)
+	if err != nil {
+		t.Fatalf("handleList() error: %v", err)
+	}
+	for _, p := range list.Prisms {
+		if p.Name != "chat" {
+			continue
+		}
+		if p.Status != "3 unread" || !p.Attention {
+			t.Errorf("chat in prism/list = %+v, want its status and attention", p)
+		}
+	}
+}
+
+func TestChildSocket_Lifecycle(t *testing.T) {
+	sup := newSupervisor(nil, nil, nil)
+
+	// The test process stands in for a background prism
+	sup.prismList = append(sup.prismList,
+		prismInstance{name: "bar", pid: 1, state: prismForeground},
+		prismInstance{name: "chat", pid: os.Getpid(), state: prismBackground},
+	)
+
+	client := startChildSocket(t, sup)
+
+	result, err := client.Lifecycle(context.Background())
+	if err != nil {
+		t.Fatalf("Lifecycle() error: %v", err)
+	}
+	if result.Name != "chat" || result.Foreground || result.Focused || !result.Hidden {
+		t.Errorf("Lifecycle() = %+v, want chat hidden in the background", result)
+	}
+}
+
+func TestSupervisor_ChildEnv(t *testing.T) {
+	dir := t.TempDir()
+
+	term, err := listenAttach(filepath.Join(dir, "attach.sock"), nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	// Args are expanded before the prism starts, so the script reads the
+	// variables from a file
+	script := filepath.Join(dir, "chat.sh")
+	if err := os.WriteFile(script, []byte(`echo "$SHINE_PRISM $SHINE_APP $SHINE_PANEL $SHINE_PRISMCTL_SOCKET" > "`+dir+`/env-$SHINE_PRISM"`+"\nsleep 30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sup := newSupervisor(term, nil, nil)
+	sup.childSocket = filepath.Join(dir, "child.sock")
+	sup.panel = "panel-chat"
+	sup.registerApp("chat", appLaunch{path: "/bin/sh", args: []string{script}})
+
+	if err := sup.start("chat"); err != nil {
+		t.Fatalf("start() error: %v", err)
+	}
+	id, err := sup.startInstance("chat")
+	if err != nil {
+		t.Fatalf("startInstance() error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		for _, p := range sup.prismList {
+			unix.Kill(-p.pid, unix.SIGKILL)
+			var status unix.WaitStatus
+			unix.Wait4(p.pid, &status, 0, nil)
+		}
+	})
+
+	// The second instance is told which app it runs
+	want := id + " chat panel-chat " + sup.childSocket
+	waitFor(t, func() bool {
+		data, err := os.ReadFile(filepath.Join(dir, "env-"+id))
+		return err == nil && strings.TrimSpace(string(data)) == want
+	})
+}
+
+// TestChildSocket_ExitPanel tests that child/exit-panel ends prismctl's
+// signal loop once its prisms have been stopped
+func TestChildSocket_ExitPanel(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "child.sock")
+
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+	srv := rpc.NewServer(sockPath, newChildHandlers(sup), nil, rpc.WithPeerContext(sup.peerContext))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	sh := newSignalHandler(sup)
+	defer sh.stop()
+
+	sup.mu.Lock()
+	chat := startHelperPrism(t, "chat", "exit-panel", sockPath)
+	sup.prismList = append(sup.prismList, chat)
+	sup.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		sh.run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("signal loop still running after child/exit-panel")
+	}
+
+	if !sup.isShuttingDown() {
+		t.Error("supervisor should have shut down")
+	}
+	select {
+	case <-chat.exit.done:
+	default:
+		t.Error("chat should have been stopped before the loop ended")
+	}
+}
@@ -6,10 +6,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/handler"
+	"github.com/starbased-co/shine/pkg/config"
 	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
 )
 
 type rpcHandlers struct {
@@ -24,20 +30,56 @@ func newRPCHandlers(sup *supervisor, stateMgr *StateManager) handler.Map {
 	}
 
 	return handler.Map{
-		"prism/configure":  handler.New(h.handleConfigure),
-		"prism/up":         handler.New(h.handleUp),
-		"prism/down":       handler.New(h.handleDown),
-		"prism/fg":         handler.New(h.handleFg),
-		"prism/bg":         handler.New(h.handleBg),
-		"prism/list":       handler.New(h.handleList),
-		"service/health":   handler.New(h.handleHealth),
-		"service/shutdown": handler.New(h.handleShutdown),
+		"prism/configure":   h.privileged(handler.New(h.handleConfigure)),
+		"prism/reconfigure": h.privileged(handler.New(h.handleReconfigure)),
+		"prism/up":          h.privileged(handler.New(h.handleUp)),
+		"prism/down":        h.privileged(handler.New(h.handleDown)),
+		"prism/fg":          h.privileged(handler.New(h.handleFg)),
+		"prism/bg":          h.privileged(handler.New(h.handleBg)),
+		"prism/list":        handler.New(h.handleList),
+		"prism/capture":     handler.New(h.handleCapture),
+		"prism/send-keys":   h.privileged(handler.New(h.handleSendKeys)),
+		"prism/signal":      h.privileged(handler.New(h.handleSignal)),
+		"prism/history":     handler.New(h.handleHistory),
+		"prism/record":      h.privileged(handler.New(h.handleRecord)),
+		"prism/visibility":  h.privileged(handler.New(h.handleVisibility)),
+		"service/health":    handler.New(h.handleHealth),
+		"service/shutdown":  h.privileged(handler.New(h.handleShutdown)),
+	}
+}
+
+// privileged refuses fn to prisms and the processes they spawn, which can
+// only act on themselves through the child socket (see child.go)
+func (h *rpcHandlers) privileged(fn handler.Func) handler.Func {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		if name, ok := connPrism(ctx); ok {
+			return nil, rpc.ErrPermissionDenied(fmt.Sprintf("prism %s cannot call %s; use the child socket", name, req.Method()))
+		}
+		return fn(ctx, req)
 	}
 }
 
 func (h *rpcHandlers) handleConfigure(ctx context.Context, req *rpc.ConfigureRequest) (*rpc.ConfigureResult, error) {
 	log.Printf("RPC: prism/configure with %d apps", len(req.Apps))
 
+	kind, panes, err := parseConfigureLayout(req)
+	if err != nil {
+		return nil, err
+	}
+
+	launches, err := parseLaunches(req.Apps)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.applyPanelSettings(req); err != nil {
+		return nil, err
+	}
+
+	if err := h.supervisor.setLayout(kind, panes); err != nil {
+		return nil, rpc.ErrOperationFailed("layout", err)
+	}
+
 	result := &rpc.ConfigureResult{
 		Started: make([]string, 0),
 		Failed:  make([]string, 0),
@@ -48,8 +90,8 @@ func (h *rpcHandlers) handleConfigure(ctx context.Context, req *rpc.ConfigureReq
 			continue
 		}
 
-		// Register the resolved path for this app
-		h.supervisor.registerApp(app.Name, app.Path)
+		// Register the resolved path, args, env, cwd and stop settings for this app
+		h.supervisor.registerApp(app.Name, launches[app.Name])
 
 		// Start the app (first one becomes foreground, rest background)
 		if err := h.supervisor.start(app.Name); err != nil {
@@ -61,36 +103,201 @@ func (h *rpcHandlers) handleConfigure(ctx context.Context, req *rpc.ConfigureReq
 		result.Started = append(result.Started, app.Name)
 	}
 
+	// In a split layout the first pane gets input focus
+	if kind != layoutSingle && len(result.Started) > 0 {
+		if err := h.supervisor.start(result.Started[0]); err != nil {
+			log.Printf("Failed to focus app %s: %v", result.Started[0], err)
+		}
+	}
+
+	return result, nil
+}
+
+func (h *rpcHandlers) handleReconfigure(ctx context.Context, req *rpc.ConfigureRequest) (*rpc.ReconfigureResult, error) {
+	log.Printf("RPC: prism/reconfigure with %d apps", len(req.Apps))
+
+	kind, panes, err := parseConfigureLayout(req)
+	if err != nil {
+		return nil, err
+	}
+	if !h.supervisor.layoutMatches(kind, panes) {
+		return nil, rpc.ErrInvalidParams("the layout and its panes cannot change while the panel is running")
+	}
+
+	launches, err := parseLaunches(req.Apps)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.applyPanelSettings(req); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, app := range req.Apps {
+		if app.Enabled {
+			names = append(names, app.Name)
+		}
+	}
+
+	added, removed, updated := h.supervisor.reconcileApps(names, launches)
+
+	result := &rpc.ReconfigureResult{
+		Started: make([]string, 0),
+		Stopped: make([]string, 0),
+		Updated: updated,
+		Failed:  make([]string, 0),
+	}
+
+	// Start added apps before stopping removed ones, so a panel whose apps
+	// are all replaced is never left empty
+	for _, name := range added {
+		if err := h.supervisor.launchInBackground(name); err != nil {
+			log.Printf("Failed to start app %s: %v", name, err)
+			result.Failed = append(result.Failed, name)
+			continue
+		}
+		result.Started = append(result.Started, name)
+	}
+
+	for _, name := range removed {
+		result.Stopped = append(result.Stopped, h.supervisor.stopApp(name)...)
+	}
+
 	return result, nil
 }
 
+// parseConfigureLayout returns the layout of a configure request and the
+// panes of its enabled apps
+func parseConfigureLayout(req *rpc.ConfigureRequest) (layoutKind, []paneSpec, error) {
+	kind, err := parseLayout(req.Layout)
+	if err != nil {
+		return layoutSingle, nil, rpc.ErrInvalidParams(err.Error())
+	}
+
+	var panes []paneSpec
+	for _, app := range req.Apps {
+		if app.Enabled {
+			panes = append(panes, paneSpec{name: app.Name, size: app.Size, weight: app.Weight})
+		}
+	}
+
+	return kind, panes, nil
+}
+
+// applyPanelSettings applies the prefix key, recording and idle settings of
+// a configure request
+func (h *rpcHandlers) applyPanelSettings(req *rpc.ConfigureRequest) error {
+	prefix, hasPrefix, err := config.ParsePrefixKey(req.Prefix)
+	if err != nil {
+		return rpc.ErrInvalidParams(err.Error())
+	}
+
+	idle, err := parseIdleScreen(req.Placeholder, req.PlaceholderText)
+	if err != nil {
+		return rpc.ErrInvalidParams(err.Error())
+	}
+
+	h.supervisor.setPrefix(prefix, hasPrefix)
+	h.supervisor.setRecord(req.Record)
+	h.supervisor.setIdle(req.KeepAlive, idle)
+
+	return nil
+}
+
+// parseLaunches validates the launch settings of apps
+func parseLaunches(apps []rpc.AppInfo) (map[string]appLaunch, error) {
+	launches := make(map[string]appLaunch, len(apps))
+	for _, app := range apps {
+		stopSignal, err := config.ParseStopSignal(app.StopSignal)
+		if err != nil {
+			return nil, rpc.ErrInvalidParams(fmt.Sprintf("app %s: %v", app.Name, err))
+		}
+
+		var stopTimeout time.Duration
+		if app.StopTimeout != "" {
+			if stopTimeout, err = time.ParseDuration(app.StopTimeout); err != nil || stopTimeout < 0 {
+				return nil, rpc.ErrInvalidParams(fmt.Sprintf("app %s: invalid stop_timeout %q", app.Name, app.StopTimeout))
+			}
+			if stopTimeout == 0 {
+				stopTimeout = stopNow
+			}
+		}
+
+		liveness, err := parseLiveness(app.Liveness)
+		if err != nil {
+			return nil, rpc.ErrInvalidParams(fmt.Sprintf("app %s: %v", app.Name, err))
+		}
+
+		if err := config.ValidateBackground(app.Background); err != nil {
+			return nil, rpc.ErrInvalidParams(fmt.Sprintf("app %s: %v", app.Name, err))
+		}
+
+		lifecycleSignal, err := config.ParseLifecycleSignal(app.LifecycleSignal)
+		if err != nil {
+			return nil, rpc.ErrInvalidParams(fmt.Sprintf("app %s: %v", app.Name, err))
+		}
+
+		var settings []byte
+		if len(app.Settings) > 0 {
+			if settings, err = json.Marshal(app.Settings); err != nil {
+				return nil, rpc.ErrInvalidParams(fmt.Sprintf("app %s: invalid settings: %v", app.Name, err))
+			}
+		}
+
+		launches[app.Name] = appLaunch{
+			path:        app.Path,
+			args:        app.Args,
+			env:         app.Env,
+			cwd:         app.Cwd,
+			stopSignal:  stopSignal,
+			stopTimeout: stopTimeout,
+			liveness:    liveness,
+			theme:       app.Theme,
+			settings:    string(settings),
+
+			runInBackground: app.Background == "run",
+			lifecycleSignal: lifecycleSignal,
+		}
+	}
+
+	return launches, nil
+}
+
 func (h *rpcHandlers) handleUp(ctx context.Context, req *rpc.UpRequest) (*rpc.UpResult, error) {
 	if req.Name == "" {
 		return nil, rpc.ErrInvalidParams("name is required")
 	}
 
-	log.Printf("RPC: prism/up %s", req.Name)
+	log.Printf("RPC: prism/up %s (new instance: %v)", req.Name, req.NewInstance)
 
-	if err := h.supervisor.start(req.Name); err != nil {
+	id := req.Name
+	if req.NewInstance {
+		var err error
+		if id, err = h.supervisor.startInstance(req.Name); err != nil {
+			return nil, rpc.ErrOperationFailed("start", err)
+		}
+	} else if err := h.supervisor.start(req.Name); err != nil {
 		return nil, rpc.ErrOperationFailed("start", err)
 	}
 
 	// Get current state after start
 	h.supervisor.mu.Lock()
-	idx := h.supervisor.findPrism(req.Name)
+	idx := h.supervisor.findPrism(id)
 	if idx == -1 {
 		h.supervisor.mu.Unlock()
-		return nil, rpc.ErrPrismNotFound(req.Name)
+		return nil, rpc.ErrPrismNotFound(id)
 	}
 
 	prism := h.supervisor.prismList[idx]
 	state := "bg"
-	if idx == 0 {
+	if prism.state == prismForeground {
 		state = "fg"
 	}
 	h.supervisor.mu.Unlock()
 
 	return &rpc.UpResult{
+		ID:    prism.name,
 		PID:   prism.pid,
 		State: state,
 	}, nil
@@ -101,14 +308,30 @@ func (h *rpcHandlers) handleDown(ctx context.Context, req *rpc.DownRequest) (*rp
 		return nil, rpc.ErrInvalidParams("name is required")
 	}
 
-	log.Printf("RPC: prism/down %s", req.Name)
+	log.Printf("RPC: prism/down %s (wait=%v)", req.Name, req.Wait)
 
-	if err := h.supervisor.killPrism(req.Name); err != nil {
+	exit, err := h.supervisor.killPrism(req.Name)
+	if err != nil {
 		return nil, rpc.ErrOperationFailed("kill", err)
 	}
 
+	if !req.Wait {
+		return &rpc.DownResult{
+			Stopped: true,
+		}, nil
+	}
+
+	select {
+	case <-exit.done:
+	case <-ctx.Done():
+		return nil, rpc.ErrOperationFailed("wait", ctx.Err())
+	}
+
 	return &rpc.DownResult{
-		Stopped: true,
+		Stopped:  true,
+		Exited:   true,
+		ExitCode: exit.exitCode,
+		Killed:   exit.killed.Load(),
 	}, nil
 }
 
@@ -128,7 +351,7 @@ func (h *rpcHandlers) handleFg(ctx context.Context, req *rpc.FgRequest) (*rpc.Fg
 	}
 
 	// Already foreground?
-	if idx == 0 {
+	if idx == 0 && h.supervisor.hasForeground() {
 		h.supervisor.mu.Unlock()
 		return &rpc.FgResult{
 			OK:    true,
@@ -153,29 +376,32 @@ func (h *rpcHandlers) handleBg(ctx context.Context, req *rpc.BgRequest) (*rpc.Bg
 		return nil, rpc.ErrInvalidParams("name is required")
 	}
 
-	log.Printf("RPC: prism/bg %s (not implemented - all non-foreground prisms are background)", req.Name)
+	log.Printf("RPC: prism/bg %s", req.Name)
 
 	h.supervisor.mu.Lock()
-	defer h.supervisor.mu.Unlock()
-
 	idx := h.supervisor.findPrism(req.Name)
+	h.supervisor.mu.Unlock()
+
 	if idx == -1 {
 		return nil, rpc.ErrPrismNotFound(req.Name)
 	}
 
-	// If not foreground, already in background
-	if idx != 0 {
-		return &rpc.BgResult{
-			OK:    true,
-			WasBg: true,
-		}, nil
+	wasBg, err := h.supervisor.background(req.Name)
+	if err != nil {
+		return nil, rpc.ErrOperationFailed("background", err)
 	}
 
-	// Cannot send foreground to background without bringing another forward
-	// This would require "suspend foreground" semantics which we don't have yet
+	h.supervisor.mu.Lock()
+	foreground := ""
+	if h.supervisor.hasForeground() {
+		foreground = h.supervisor.prismList[0].name
+	}
+	h.supervisor.mu.Unlock()
+
 	return &rpc.BgResult{
-		OK:    false,
-		WasBg: false,
+		OK:         true,
+		WasBg:      wasBg,
+		Foreground: foreground,
 	}, nil
 }
 
@@ -188,17 +414,31 @@ func (h *rpcHandlers) handleList(ctx context.Context) (*rpc.ListResult, error) {
 	prisms := make([]rpc.PrismInfo, 0, len(h.supervisor.prismList))
 	for i, p := range h.supervisor.prismList {
 		state := "bg"
-		if i == 0 {
+		if i == 0 && h.supervisor.hasForeground() {
 			state = "fg"
 		}
 
-		prisms = append(prisms, rpc.PrismInfo{
+		info := rpc.PrismInfo{
 			Name:     p.name,
+			App:      p.app,
 			PID:      p.pid,
 			State:    state,
-			UptimeMs: 0, // TODO: track start time
 			Restarts: 0, // TODO: track restarts
-		})
+		}
+		if !p.started.IsZero() {
+			info.UptimeMs = time.Since(p.started).Milliseconds()
+		}
+		if usage, ok := h.supervisor.usage[p.pid]; ok {
+			info.Sampled = true
+			info.CPUPercent = usage.cpuPercent
+			info.RSSBytes = usage.rssBytes
+			info.Threads = usage.threads
+		}
+		info.Health, info.HealthReason = h.supervisor.prismHealthState(p)
+		info.Status = p.status
+		info.Attention = p.attention
+
+		prisms = append(prisms, info)
 	}
 
 	return &rpc.ListResult{
@@ -206,6 +446,177 @@ func (h *rpcHandlers) handleList(ctx context.Context) (*rpc.ListResult, error) {
 	}, nil
 }
 
+func (h *rpcHandlers) handleHistory(ctx context.Context, req *rpc.HistoryRequest) (*rpc.HistoryResult, error) {
+	log.Printf("RPC: prism/history %s", req.Name)
+
+	h.supervisor.mu.Lock()
+	records := h.supervisor.exitHistory(req.Name)
+	h.supervisor.mu.Unlock()
+
+	exits := make([]rpc.ExitInfo, 0, len(records))
+	for _, r := range records {
+		info := rpc.ExitInfo{
+			Name:       r.name,
+			PID:        r.pid,
+			ExitCode:   r.exitCode,
+			CoreDumped: r.coreDumped,
+			RuntimeMs:  r.runtime().Milliseconds(),
+			ExitedMs:   r.exited.UnixMilli(),
+		}
+		if r.signal != 0 {
+			info.Signal = unix.SignalName(r.signal)
+		}
+		exits = append(exits, info)
+	}
+
+	return &rpc.HistoryResult{Exits: exits}, nil
+}
+
+func (h *rpcHandlers) handleCapture(ctx context.Context, req *rpc.CaptureRequest) (*rpc.CaptureResult, error) {
+	if req.Name == "" {
+		return nil, rpc.ErrInvalidParams("name is required")
+	}
+	if req.Lines < 0 || req.Bytes < 0 {
+		return nil, rpc.ErrInvalidParams("lines and bytes must not be negative")
+	}
+
+	log.Printf("RPC: prism/capture %s", req.Name)
+
+	h.supervisor.mu.Lock()
+	idx := h.supervisor.findPrism(req.Name)
+	if idx == -1 {
+		h.supervisor.mu.Unlock()
+		return nil, rpc.ErrPrismNotFound(req.Name)
+	}
+	output := h.supervisor.prismList[idx].output
+	h.supervisor.mu.Unlock()
+
+	content := output.capture(captureOptions{
+		lines:  req.Lines,
+		bytes:  req.Bytes,
+		strip:  req.Strip,
+		screen: req.Screen,
+	})
+
+	return &rpc.CaptureResult{
+		Name:    req.Name,
+		Content: content,
+	}, nil
+}
+
+func (h *rpcHandlers) handleSendKeys(ctx context.Context, req *rpc.SendKeysRequest) (*rpc.SendKeysResult, error) {
+	if req.Name == "" {
+		return nil, rpc.ErrInvalidParams("name is required")
+	}
+	if len(req.Keys) == 0 {
+		return nil, rpc.ErrInvalidParams("keys are required")
+	}
+
+	log.Printf("RPC: prism/send-keys %s (%d keys)", req.Name, len(req.Keys))
+
+	h.supervisor.mu.Lock()
+	idx := h.supervisor.findPrism(req.Name)
+	if idx == -1 {
+		h.supervisor.mu.Unlock()
+		return nil, rpc.ErrPrismNotFound(req.Name)
+	}
+	prism := h.supervisor.prismList[idx]
+	h.supervisor.mu.Unlock()
+
+	data := encodeKeys(req.Keys, req.Literal, prism.output.appCursorKeys())
+
+	// Background prisms are stopped, but the PTY buffers the input until
+	// they are resumed
+	n, err := prism.ptyMaster.Write(data)
+	if err != nil {
+		return nil, rpc.ErrOperationFailed("send-keys", err)
+	}
+
+	return &rpc.SendKeysResult{
+		Bytes: n,
+	}, nil
+}
+
+func (h *rpcHandlers) handleSignal(ctx context.Context, req *rpc.SignalRequest) (*rpc.SignalResult, error) {
+	if req.Name == "" {
+		return nil, rpc.ErrInvalidParams("name is required")
+	}
+	if req.Signal == "" {
+		return nil, rpc.ErrInvalidParams("signal is required")
+	}
+
+	sig, err := config.ParseSignal(req.Signal)
+	if err != nil {
+		return nil, rpc.ErrInvalidParams(err.Error())
+	}
+
+	log.Printf("RPC: prism/signal %s %s", req.Name, unix.SignalName(sig))
+
+	h.supervisor.mu.Lock()
+	found := h.supervisor.findPrism(req.Name) != -1
+	h.supervisor.mu.Unlock()
+	if !found {
+		return nil, rpc.ErrPrismNotFound(req.Name)
+	}
+
+	pid, err := h.supervisor.signalPrism(req.Name, sig)
+	if err != nil {
+		return nil, rpc.ErrOperationFailed("signal", err)
+	}
+
+	return &rpc.SignalResult{
+		PID:    pid,
+		Signal: unix.SignalName(sig),
+	}, nil
+}
+
+func (h *rpcHandlers) handleRecord(ctx context.Context, req *rpc.RecordRequest) (*rpc.RecordResult, error) {
+	if req.Name == "" {
+		return nil, rpc.ErrInvalidParams("name is required")
+	}
+
+	log.Printf("RPC: prism/record %s (enabled=%v)", req.Name, req.Enabled)
+
+	h.supervisor.mu.Lock()
+	idx := h.supervisor.findPrism(req.Name)
+	if idx == -1 {
+		h.supervisor.mu.Unlock()
+		return nil, rpc.ErrPrismNotFound(req.Name)
+	}
+	output := h.supervisor.prismList[idx].output
+	h.supervisor.mu.Unlock()
+
+	if !req.Enabled {
+		return &rpc.RecordResult{
+			Recording: false,
+			Path:      output.stopRecording(),
+		}, nil
+	}
+
+	path, err := output.startRecording(recordingPath(req.Name), req.Name)
+	if err != nil {
+		return nil, rpc.ErrOperationFailed("record", err)
+	}
+
+	return &rpc.RecordResult{
+		Recording: true,
+		Path:      path,
+	}, nil
+}
+
+// handleVisibility records the panel being hidden or shown by whatever
+// toggles it, so prisms can be told
+func (h *rpcHandlers) handleVisibility(ctx context.Context, req *rpc.VisibilityRequest) (*rpc.VisibilityResult, error) {
+	log.Printf("RPC: prism/visibility (hidden=%v)", req.Hidden)
+
+	changed := h.supervisor.setPanelHidden(req.Hidden)
+
+	return &rpc.VisibilityResult{
+		Hidden:  req.Hidden,
+		Changed: changed,
+	}, nil
+}
+
 func (h *rpcHandlers) handleHealth(ctx context.Context) (*rpc.HealthResult, error) {
 	log.Printf("RPC: service/health")
 
@@ -215,6 +626,7 @@ func (h *rpcHandlers) handleHealth(ctx context.Context) (*rpc.HealthResult, erro
 	return &rpc.HealthResult{
 		Healthy:    !h.supervisor.shuttingDown,
 		PrismCount: len(h.supervisor.prismList),
+		Unhealthy:  h.supervisor.unhealthyPrisms(),
 	}, nil
 }
 
@@ -29,7 +29,7 @@ var helpRegistry = map[string]*CommandHelp{
 		Name:     "usage",
 		Category: "General",
 		Synopsis: "General usage and command-line interface",
-		Usage:    "prismctl <prism-name> [component-name]",
+		Usage:    "prismctl <prism-name> [component-name] [--headless]",
 		Content:  usageHelp,
 		Related:  []string{"ipc", "signals"},
 		SeeAlso:  []string{"Terminal state management"},
@@ -0,0 +1,74 @@
+// history.go remembers prisms that exited. The last historySize exits are
+// kept with how each prism ended — exit code, the signal that killed it and
+// whether it dumped core — and how long it ran, so a widget that crashed
+// while nobody was looking can still be diagnosed from prism/history or
+// shine status.
+
+package main
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// historySize bounds the number of exits kept
+const historySize = 32
+
+// exitRecord is one prism exit
+type exitRecord struct {
+	name       string
+	pid        int
+	exitCode   int         // exit status, 128+N when killed by signal N
+	signal     unix.Signal // signal that killed the prism, 0 for a normal exit
+	coreDumped bool
+	started    time.Time
+	exited     time.Time
+}
+
+// runtime returns how long the prism ran, 0 if its start is unknown
+func (r exitRecord) runtime() time.Duration {
+	if r.started.IsZero() {
+		return 0
+	}
+	return r.exited.Sub(r.started)
+}
+
+// newExitRecord describes prism's exit with the given wait status
+func newExitRecord(prism prismInstance, status unix.WaitStatus) exitRecord {
+	record := exitRecord{
+		name:     prism.name,
+		pid:      prism.pid,
+		exitCode: exitStatusCode(status),
+		started:  prism.started,
+		exited:   time.Now(),
+	}
+	if status.Signaled() {
+		record.signal = status.Signal()
+		record.coreDumped = status.CoreDump()
+	}
+	return record
+}
+
+// recordExit adds an exit to the history, dropping the oldest one when full
+// Assumes caller holds s.mu lock
+func (s *supervisor) recordExit(record exitRecord) {
+	s.history = append(s.history, record)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+}
+
+// exitHistory returns the recorded exits, most recent first. A non-empty
+// name only returns that prism's exits.
+// Assumes caller holds s.mu lock
+func (s *supervisor) exitHistory(name string) []exitRecord {
+	records := make([]exitRecord, 0, len(s.history))
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if name != "" && s.history[i].name != name {
+			continue
+		}
+		records = append(records, s.history[i])
+	}
+	return records
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSupervisor_HistoryRecordsSignal(t *testing.T) {
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+
+	cmd, prism := startShell(t, "crasher", `sleep 0.1; kill -USR1 $$`, unix.SIGTERM, time.Second)
+	prism.started = time.Now()
+	sup.prismList = append(sup.prismList,
+		prismInstance{name: "bar", pid: 1, state: prismForeground},
+		prism,
+	)
+	reapInto(sup, cmd)
+
+	<-prism.exit.done
+
+	sup.mu.Lock()
+	records := sup.exitHistory("")
+	sup.mu.Unlock()
+
+	if len(records) != 1 {
+		t.Fatalf("history has %d exits, want 1", len(records))
+	}
+	r := records[0]
+	if r.name != "crasher" || r.pid != prism.pid {
+		t.Errorf("exit of %s (PID %d), want crasher (PID %d)", r.name, r.pid, prism.pid)
+	}
+	if r.signal != unix.SIGUSR1 {
+		t.Errorf("signal = %v, want SIGUSR1", r.signal)
+	}
+	if r.exitCode != 128+int(unix.SIGUSR1) {
+		t.Errorf("exitCode = %d, want %d", r.exitCode, 128+int(unix.SIGUSR1))
+	}
+	if r.runtime() <= 0 {
+		t.Errorf("runtime = %v, want the time since the prism started", r.runtime())
+	}
+}
+
+func TestSupervisor_HistoryIsBounded(t *testing.T) {
+	sup := newSupervisor(nil, nil, nil)
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	for i := 0; i < historySize+8; i++ {
+		sup.recordExit(exitRecord{name: fmt.Sprintf("prism-%d", i%2), pid: 1000 + i})
+	}
+
+	records := sup.exitHistory("")
+	if len(records) != historySize {
+		t.Fatalf("history has %d exits, want %d", len(records), historySize)
+	}
+	if records[0].pid != 1000+historySize+7 {
+		t.Errorf("most recent exit is PID %d, want %d", records[0].pid, 1000+historySize+7)
+	}
+
+	for _, r := range sup.exitHistory("prism-1") {
+		if r.name != "prism-1" {
+			t.Errorf("exitHistory(prism-1) returned an exit of %s", r.name)
+		}
+	}
+}
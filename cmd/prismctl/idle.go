@@ -0,0 +1,180 @@
+// idle.go draws the placeholder shown while no prism is in the foreground:
+// after prism/bg demoted the only running prism, or after the last prism
+// exited in a keep_alive panel. Input is dropped until a prism is brought to
+// the foreground with prism/fg, prism/up or a prefix key.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const idleMessage = "no foreground prism"
+
+// idleScreen is the configured placeholder
+type idleScreen struct {
+	mode string             // "text" (default), "blank" or "apps"
+	text *template.Template // "text" mode, nil = default message
+}
+
+// idleData is what a placeholder_text template can refer to
+type idleData struct {
+	Panel      string
+	Background []string // running prisms, most recently used first
+	Apps       []string // configured apps, by name
+}
+
+// parseIdleScreen builds the placeholder from its configuration
+func parseIdleScreen(mode, text string) (idleScreen, error) {
+	screen := idleScreen{mode: mode}
+
+	switch mode {
+	case "", "text":
+		screen.mode = "text"
+	case "blank", "apps":
+		return screen, nil
+	default:
+		return screen, fmt.Errorf("invalid placeholder %q", mode)
+	}
+
+	if text == "" {
+		return screen, nil
+	}
+
+	tmpl, err := template.New("placeholder").Parse(text)
+	if err != nil {
+		return screen, fmt.Errorf("invalid placeholder_text: %w", err)
+	}
+	screen.text = tmpl
+
+	return screen, nil
+}
+
+// lines returns the placeholder text; the first line is drawn in full
+// intensity, the rest dimmed
+func (i idleScreen) lines(data idleData, rows int) []string {
+	switch i.mode {
+	case "blank":
+		return nil
+
+	case "apps":
+		running := make(map[string]bool, len(data.Background))
+		for _, name := range data.Background {
+			running[name] = true
+		}
+
+		lines := []string{"start an app with prism/up"}
+		for _, name := range data.Apps {
+			if running[name] {
+				name += " (running)"
+			}
+			lines = append(lines, name)
+		}
+		return lines
+	}
+
+	if i.text != nil {
+		var b strings.Builder
+		err := i.text.Execute(&b, data)
+		if err == nil {
+			return strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+		}
+		log.Printf("Warning: failed to render placeholder_text: %v", err)
+	}
+
+	lines := []string{idleMessage}
+	if len(data.Background) > 0 && rows >= 3 {
+		lines = append(lines, "")
+		lines = append(lines, data.Background...)
+	}
+	return lines
+}
+
+// renderIdle draws the placeholder on a cols×rows screen, centred
+func renderIdle(cols, rows int, screen idleScreen, data idleData) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x1b[0m\x1b[?25l\x1b[?7l\x1b[H\x1b[2J")
+
+	lines := screen.lines(data, rows)
+	if len(lines) > rows {
+		lines = lines[:rows]
+	}
+
+	top := (rows-len(lines))/2 + 1
+	for i, line := range lines {
+		line = runewidth.Truncate(line, cols, "")
+		left := (cols-runewidth.StringWidth(line))/2 + 1
+
+		fmt.Fprintf(&b, "\x1b[%d;%dH", top+i, left)
+		if i == 0 {
+			b.WriteString(line)
+		} else {
+			fmt.Fprintf(&b, "\x1b[2m%s\x1b[22m", line)
+		}
+	}
+
+	b.WriteString("\x1b[?7h")
+	return b.Bytes()
+}
+
+// setIdle configures the placeholder and whether prismctl keeps running
+// after the last prism exits
+func (s *supervisor) setIdle(keepAlive bool, screen idleScreen) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keepAlive = keepAlive
+	s.idle = screen
+}
+
+// showIdle shows the placeholder. In a split layout the panes stay on screen
+// and only lose focus.
+// Assumes caller holds s.mu lock
+func (s *supervisor) showIdle() {
+	if s.layout != nil {
+		s.layout.focus("")
+		return
+	}
+
+	winsize, err := s.term.Size()
+	if err != nil {
+		log.Printf("Warning: failed to get Real PTY size for idle screen: %v", err)
+		return
+	}
+	cols, rows := int(winsize.Col), int(winsize.Row)
+
+	frame := append(s.enterModes(overlayModes), renderIdle(cols, rows, s.idle, s.idleData())...)
+	if _, err := s.term.Write(frame); err != nil {
+		log.Printf("Warning: failed to draw idle screen: %v", err)
+	}
+}
+
+// Assumes caller holds s.mu lock
+func (s *supervisor) idleData() idleData {
+	data := idleData{
+		Background: make([]string, len(s.prismList)),
+		Apps:       make([]string, 0, len(s.apps)),
+	}
+
+	if s.stateManager != nil {
+		data.Panel = s.stateManager.instance
+	}
+
+	for i, p := range s.prismList {
+		data.Background[i] = p.name
+	}
+
+	for name := range s.apps {
+		data.Apps = append(data.Apps, name)
+	}
+	sort.Strings(data.Apps)
+
+	return data
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+func TestRenderIdle(t *testing.T) {
+	data := idleData{Panel: "bar", Background: []string{"clock"}, Apps: []string{"chat", "clock"}}
+
+	text, err := parseIdleScreen("", "")
+	if err != nil {
+		t.Fatalf("parseIdleScreen() error: %v", err)
+	}
+	out := string(renderIdle(40, 10, text, data))
+	for _, want := range []string{idleMessage, "clock"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("renderIdle(default) missing %q: %q", want, out)
+		}
+	}
+
+	// A one-line bar only has room for the message
+	out = string(renderIdle(40, 1, text, data))
+	if !strings.Contains(out, idleMessage) || strings.Contains(out, "clock") {
+		t.Errorf("renderIdle(default) on one row = %q, want the message only", out)
+	}
+
+	tmpl, err := parseIdleScreen("text", "{{.Panel}}: {{len .Background}} waiting")
+	if err != nil {
+		t.Fatalf("parseIdleScreen() error: %v", err)
+	}
+	if out := string(renderIdle(40, 1, tmpl, data)); !strings.Contains(out, "bar: 1 waiting") {
+		t.Errorf("renderIdle(template) = %q, want %q", out, "bar: 1 waiting")
+	}
+
+	apps, _ := parseIdleScreen("apps", "")
+	out = string(renderIdle(40, 10, apps, data))
+	if !strings.Contains(out, "chat") || !strings.Contains(out, "clock (running)") {
+		t.Errorf("renderIdle(apps) = %q, want chat and clock (running)", out)
+	}
+
+	blank, _ := parseIdleScreen("blank", "")
+	if out := string(renderIdle(40, 10, blank, data)); strings.Contains(out, idleMessage) {
+		t.Errorf("renderIdle(blank) = %q, want an empty screen", out)
+	}
+
+	if _, err := parseIdleScreen("fancy", ""); err == nil {
+		t.Error("parseIdleScreen() should reject unknown modes")
+	}
+	if _, err := parseIdleScreen("text", "{{.Panel"); err == nil {
+		t.Error("parseIdleScreen() should reject invalid templates")
+	}
+}
+
+// processState returns the state letter from /proc/<pid>/stat, e.g. 'T'
+// for a stopped process
+func processState(t *testing.T, pid int) byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		t.Fatalf("failed to read process state: %v", err)
+	}
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return fields[0][0]
+}
+
+func TestPrismctlIPC_Bg(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+
+	var prisms []prismInstance
+	for _, name := range []string{"a", "b", "c"} {
+		_, prism := startShell(t, name, "while :; do sleep 0.02; done", unix.SIGTERM, time.Second)
+		prisms = append(prisms, prism)
+	}
+	prisms[0].state = prismForeground
+	sup.prismList = append(sup.prismList, prisms...)
+
+	srv := rpc.NewServer(sockPath, newRPCHandlers(sup, nil), nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	order := func() string {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		names := make([]string, len(sup.prismList))
+		for i, p := range sup.prismList {
+			names[i] = p.name
+		}
+		return strings.Join(names, ",")
+	}
+
+	// Demoting the foreground rotates it to the back of the MRU list
+	result, err := client.Bg(ctx, "a")
+	if err != nil {
+		t.Fatalf("Bg(a) error: %v", err)
+	}
+	if !result.OK || result.WasBg || result.Foreground != "b" {
+		t.Errorf("Bg(a) = %+v, want b promoted", result)
+	}
+	if got := order(); got != "b,c,a" {
+		t.Errorf("MRU order after Bg(a) = %s, want b,c,a", got)
+	}
+	waitFor(t, func() bool { return processState(t, prisms[0].pid) == 'T' })
+
+	result, err = client.Bg(ctx, "c")
+	if err != nil {
+		t.Fatalf("Bg(c) error: %v", err)
+	}
+	if !result.WasBg {
+		t.Errorf("Bg(c) of a background prism = %+v, want was_bg", result)
+	}
+
+	if _, err := client.Bg(ctx, "missing"); err == nil {
+		t.Error("Bg() of unknown prism should fail")
+	}
+}
+
+func TestSupervisor_BackgroundLastPrismIdles(t *testing.T) {
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+
+	_, prism := startShell(t, "clock", "while :; do sleep 0.02; done", unix.SIGTERM, time.Second)
+	prism.state = prismForeground
+	sup.prismList = append(sup.prismList, prism)
+
+	wasBg, err := sup.background("clock")
+	if err != nil || wasBg {
+		t.Fatalf("background() = %v, %v, want demoted", wasBg, err)
+	}
+
+	sup.mu.Lock()
+	idle := !sup.hasForeground() && len(sup.prismList) == 1
+	sup.mu.Unlock()
+	if !idle {
+		t.Fatal("panel should be idle with clock in the background")
+	}
+	waitFor(t, func() bool { return processState(t, prism.pid) == 'T' })
+
+	// prism/fg (start) brings it back from idle
+	if err := sup.start("clock"); err != nil {
+		t.Fatalf("start() error: %v", err)
+	}
+
+	sup.mu.Lock()
+	fg := sup.hasForeground()
+	sup.mu.Unlock()
+	if !fg {
+		t.Error("clock should be foreground again")
+	}
+	waitFor(t, func() bool { return processState(t, prism.pid) != 'T' })
+}
+
+func TestSupervisor_KeepAliveAfterLastExit(t *testing.T) {
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+	sup.setIdle(true, idleScreen{mode: "text"})
+
+	cmd, prism := startShell(t, "clock", "while :; do sleep 0.02; done", unix.SIGTERM, time.Second)
+	prism.state = prismForeground
+	sup.prismList = append(sup.prismList, prism)
+	reapInto(sup, cmd)
+
+	if _, err := sup.killPrism("clock"); err != nil {
+		t.Fatalf("killPrism() error: %v", err)
+	}
+	<-prism.exit.done
+
+	waitFor(t, func() bool {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		return len(sup.prismList) == 0
+	})
+
+	// Shutdown would have been started in the background
+	time.Sleep(50 * time.Millisecond)
+	if sup.isShuttingDown() {
+		t.Error("keep_alive panel should not shut down after its last prism exits")
+	}
+}
+
+// TestSupervisor_ExitDuringShutdown tests that a prism reaped once shutdown
+// has begun is left to shutdown() instead of idling the panel, PTY included
+func TestSupervisor_ExitDuringShutdown(t *testing.T) {
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+	sup.setIdle(true, idleScreen{mode: "text"})
+
+	// Stands in for the PTY master
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	cmd, prism := startShell(t, "clock", "while :; do sleep 0.02; done", unix.SIGTERM, time.Second)
+	prism.state = prismForeground
+	prism.ptyMaster = r
+	sup.prismList = append(sup.prismList, prism)
+	sup.shuttingDown = true
+	reapInto(sup, cmd)
+
+	cmd.Process.Kill()
+	<-prism.exit.done
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	if len(sup.prismList) != 1 {
+		t.Errorf("prismList has %d prisms, want clock left for shutdown()", len(sup.prismList))
+	}
+	if _, err := r.Stat(); err != nil {
+		t.Errorf("PTY master closed before shutdown() closes it: %v", err)
+	}
+}
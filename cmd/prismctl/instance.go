@@ -0,0 +1,60 @@
+// instance.go lets a panel run several copies of the same app. Every prism
+// is an instance with an ID that is unique within the panel, and RPC methods
+// address prisms by that ID. The first instance of an app is named after the
+// app; prism/up with new_instance launches further ones named "<app>:2",
+// "<app>:3" and so on, with the app's binary, args and env. Copies of a
+// binary that need different args or env are separate apps in the prism's
+// config, each with its own name.
+
+package main
+
+import "github.com/starbased-co/shine/pkg/rpc"
+
+// instanceApp returns the app an instance ID runs
+// Assumes caller holds s.mu lock
+func (s *supervisor) instanceApp(id string) string {
+	if _, ok := s.apps[id]; ok {
+		return id
+	}
+
+	if app, _, ok := rpc.SplitInstanceID(id); ok {
+		return app
+	}
+
+	return id
+}
+
+// nextInstanceID returns the ID for a new instance of app: the app's name
+// while no instance has it, otherwise the lowest free "<app>:N"
+// Assumes caller holds s.mu lock
+func (s *supervisor) nextInstanceID(app string) string {
+	if s.findPrism(app) == -1 {
+		return app
+	}
+
+	for n := 2; ; n++ {
+		id := rpc.InstanceID(app, n)
+		if s.findPrism(id) == -1 {
+			return id
+		}
+	}
+}
+
+// startInstance launches a new instance of the app that name runs, even if
+// an instance of it is already running, brings it to the foreground and
+// returns its ID
+func (s *supervisor) startInstance(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app := s.instanceApp(name)
+	id := s.nextInstanceID(app)
+	if err := s.launchAndForeground(id); err != nil {
+		return "", err
+	}
+	if id != app {
+		s.copies[id] = true
+	}
+
+	return id, nil
+}
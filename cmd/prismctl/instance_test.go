@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+func TestSupervisor_InstanceIDs(t *testing.T) {
+	sup := newSupervisor(nil, nil, nil)
+	sup.registerApp("clock", appLaunch{path: "/bin/sh"})
+	sup.registerApp("clock:utc", appLaunch{path: "/bin/sh"})
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"clock", "clock"},
+		{"clock:2", "clock"},
+		{"clock:utc", "clock:utc"},
+		{"htop:3", "htop"},
+		{"clock:1", "clock:1"},
+		{"clock:", "clock:"},
+	}
+	for _, tt := range tests {
+		if got := sup.instanceApp(tt.id); got != tt.want {
+			t.Errorf("instanceApp(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+
+	if id := sup.nextInstanceID("clock"); id != "clock" {
+		t.Errorf("nextInstanceID() with no instance = %q, want clock", id)
+	}
+
+	sup.prismList = append(sup.prismList,
+		prismInstance{name: "clock", app: "clock"},
+		prismInstance{name: "clock:3", app: "clock"},
+	)
+	if id := sup.nextInstanceID("clock"); id != "clock:2" {
+		t.Errorf("nextInstanceID() = %q, want the lowest free clock:2", id)
+	}
+}
+
+func TestPrismctlIPC_UpNewInstance(t *testing.T) {
+	dir := t.TempDir()
+	sockPath := filepath.Join(dir, "prism.sock")
+
+	term, err := listenAttach(filepath.Join(dir, "attach.sock"), nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	sup := newSupervisor(term, nil, nil)
+	sup.registerApp("clock", appLaunch{path: "/bin/sh", args: []string{"-c", "sleep 30"}})
+	t.Cleanup(func() {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		for _, p := range sup.prismList {
+			unix.Kill(-p.pid, unix.SIGKILL)
+			var status unix.WaitStatus
+			unix.Wait4(p.pid, &status, 0, nil)
+		}
+	})
+
+	srv := rpc.NewServer(sockPath, newRPCHandlers(sup, nil), nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	first, err := client.Up(ctx, "clock")
+	if err != nil {
+		t.Fatalf("Up() error: %v", err)
+	}
+	if first.ID != "clock" {
+		t.Errorf("first instance ID = %q, want clock", first.ID)
+	}
+
+	// Without new_instance the running instance is reused
+	again, err := client.Up(ctx, "clock")
+	if err != nil {
+		t.Fatalf("Up() error: %v", err)
+	}
+	if again.PID != first.PID {
+		t.Errorf("Up() started PID %d, want the running PID %d", again.PID, first.PID)
+	}
+
+	second, err := client.UpNew(ctx, "clock")
+	if err != nil {
+		t.Fatalf("UpNew() error: %v", err)
+	}
+	if second.ID != "clock:2" || second.PID == first.PID || second.State != "fg" {
+		t.Errorf("UpNew() = %+v, want a new foreground clock:2", second)
+	}
+
+	list, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(list.Prisms) != 2 {
+		t.Fatalf("List() returned %d prisms, want 2", len(list.Prisms))
+	}
+	for _, p := range list.Prisms {
+		if p.App != "clock" {
+			t.Errorf("prism %s runs app %q, want clock", p.Name, p.App)
+		}
+	}
+
+	// Instances are addressed by ID
+	fg, err := client.Fg(ctx, "clock")
+	if err != nil || fg.WasFg {
+		t.Fatalf("Fg(clock) = %+v, %v, want clock brought back", fg, err)
+	}
+
+	sup.mu.Lock()
+	foreground := sup.prismList[0].name
+	sup.mu.Unlock()
+	if foreground != "clock" {
+		t.Errorf("foreground = %q, want clock", foreground)
+	}
+
+	// Without new_instance no instance is launched under an ID of its own
+	if _, err := client.Up(ctx, "clock:7"); err == nil {
+		t.Error("Up(clock:7) should not launch an instance new_instance did not")
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	if len(sup.prismList) != 2 {
+		t.Errorf("%d prisms running, want 2", len(sup.prismList))
+	}
+	if !sup.copies["clock:2"] {
+		t.Error("clock:2 should be launched again when restarted")
+	}
+}
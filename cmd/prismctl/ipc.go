@@ -29,7 +29,7 @@ func startRPCServer(instance string, supervisor *supervisor, stateMgr *StateMana
 		},
 	}
 
-	server := rpc.NewServer(socketPath, handlers, opts)
+	server := rpc.NewServer(socketPath, handlers, opts, rpc.WithPeerContext(supervisor.peerContext))
 
 	if err := server.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start RPC server: %w", err)
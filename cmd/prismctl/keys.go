@@ -0,0 +1,196 @@
+// keys.go implements tmux-style prefix key handling on the input path. After
+// the prefix key, a single key selects an action:
+//
+//	n, →      next prism (cycles through all prisms, oldest first)
+//	p, ←      previous prism (the most recently used one)
+//	1-9       prism by number, in start order
+//	x         kill the foreground prism
+//	w         open the prism picker
+//	prefix    send the prefix key itself to the foreground prism
+//
+// Any other key after the prefix is discarded.
+
+package main
+
+import (
+	"log"
+	"sort"
+)
+
+type keyAction int
+
+const (
+	keyNext keyAction = iota
+	keyPrev
+	keySelect
+	keyKill
+	keyPicker
+)
+
+type keyCommand struct {
+	action keyAction
+	index  int // 0-based prism number for keySelect
+}
+
+type prefixKeys struct {
+	prefix      byte
+	afterPrefix bool // prefix seen, waiting for the command key
+	skipEscape  bool // discarding the rest of an escape sequence
+	onCommand   func(keyCommand)
+}
+
+func newPrefixKeys(prefix byte, onCommand func(keyCommand)) *prefixKeys {
+	return &prefixKeys{
+		prefix:    prefix,
+		onCommand: onCommand,
+	}
+}
+
+// scan consumes data up to and including the first complete command. It
+// returns the bytes to forward to the prism, the command if one was found,
+// and the unconsumed remainder. State carries over between calls, so a
+// prefix at the end of one read applies to the first key of the next.
+func (k *prefixKeys) scan(data []byte) (forward []byte, cmd keyCommand, found bool, rest []byte) {
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+
+		if k.skipEscape {
+			// CSI parameters and intermediates end at a final byte
+			if b >= 0x40 && b <= 0x7e {
+				k.skipEscape = false
+			}
+			continue
+		}
+
+		if !k.afterPrefix {
+			if b == k.prefix {
+				k.afterPrefix = true
+				continue
+			}
+			forward = append(forward, b)
+			continue
+		}
+
+		k.afterPrefix = false
+
+		switch {
+		case b == k.prefix:
+			forward = append(forward, b)
+		case b == 'n':
+			return forward, keyCommand{action: keyNext}, true, data[i+1:]
+		case b == 'p':
+			return forward, keyCommand{action: keyPrev}, true, data[i+1:]
+		case b >= '1' && b <= '9':
+			return forward, keyCommand{action: keySelect, index: int(b - '1')}, true, data[i+1:]
+		case b == 'x':
+			return forward, keyCommand{action: keyKill}, true, data[i+1:]
+		case b == 'w':
+			return forward, keyCommand{action: keyPicker}, true, data[i+1:]
+		case b == 0x1b && i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') && data[i+2] == 'C':
+			return forward, keyCommand{action: keyNext}, true, data[i+3:]
+		case b == 0x1b && i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') && data[i+2] == 'D':
+			return forward, keyCommand{action: keyPrev}, true, data[i+3:]
+		case b == 0x1b:
+			// Unbound escape sequence: drop it rather than leak its tail
+			k.skipEscape = i+1 < len(data) && (data[i+1] == '[' || data[i+1] == 'O')
+			if k.skipEscape {
+				i++
+			}
+		}
+	}
+
+	return forward, keyCommand{}, false, nil
+}
+
+// setPrefix installs the prefix key; ok is false to disable it
+func (s *supervisor) setPrefix(prefix byte, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = nil
+	if ok {
+		s.keys = newPrefixKeys(prefix, s.handleKeyCommand)
+	}
+
+	if s.mirror != nil {
+		s.mirror.setKeys(s.keys)
+	}
+}
+
+// prismNamesByStart returns running prism names in start order, which is the
+// numbering used by prefix+digit and the picker
+// Assumes caller holds s.mu lock
+func (s *supervisor) prismNamesByStart() []string {
+	prisms := make([]prismInstance, len(s.prismList))
+	copy(prisms, s.prismList)
+	sort.Slice(prisms, func(i, j int) bool {
+		return prisms[i].seq < prisms[j].seq
+	})
+
+	names := make([]string, len(prisms))
+	for i, p := range prisms {
+		names[i] = p.name
+	}
+	return names
+}
+
+// handleKeyCommand runs on the mirror goroutine without any lock held
+func (s *supervisor) handleKeyCommand(cmd keyCommand) {
+	switch cmd.action {
+	case keyPicker:
+		s.openPicker()
+		return
+	case keyKill:
+		s.mu.Lock()
+		if !s.hasForeground() {
+			s.mu.Unlock()
+			return
+		}
+		target := s.prismList[0].name
+		s.mu.Unlock()
+
+		log.Printf("Prefix key: killing foreground %s", target)
+		if _, err := s.killPrism(target); err != nil {
+			log.Printf("Prefix key: failed to kill %s: %v", target, err)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targetIdx := -1
+	switch cmd.action {
+	case keyNext:
+		targetIdx = len(s.prismList) - 1
+	case keyPrev:
+		targetIdx = 1
+		if !s.hasForeground() {
+			targetIdx = 0
+		}
+	case keySelect:
+		if names := s.prismNamesByStart(); cmd.index < len(names) {
+			targetIdx = s.findPrism(names[cmd.index])
+		}
+	}
+
+	s.switchTo(targetIdx)
+}
+
+// switchTo brings the running prism at targetIdx to the foreground. Unlike
+// start it never launches anything, so a stale selection is a no-op.
+// Assumes caller holds s.mu lock
+func (s *supervisor) switchTo(targetIdx int) {
+	if targetIdx < 0 || targetIdx >= len(s.prismList) {
+		return
+	}
+	if targetIdx == 0 && s.hasForeground() {
+		return
+	}
+
+	name := s.prismList[targetIdx].name
+	log.Printf("Prefix key: switching to %s", name)
+	if err := s.resumeToForeground(targetIdx); err != nil {
+		log.Printf("Prefix key: failed to switch to %s: %v", name, err)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrefixKeys_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		forward string
+		cmd     keyCommand
+		found   bool
+		rest    string
+	}{
+		{"plain input", "hello", "hello", keyCommand{}, false, ""},
+		{"next", "ab\x01nrest", "ab", keyCommand{action: keyNext}, true, "rest"},
+		{"previous", "\x01p", "", keyCommand{action: keyPrev}, true, ""},
+		{"select by number", "\x013", "", keyCommand{action: keySelect, index: 2}, true, ""},
+		{"kill", "\x01x", "", keyCommand{action: keyKill}, true, ""},
+		{"picker", "\x01wj", "", keyCommand{action: keyPicker}, true, "j"},
+		{"right arrow", "\x01\x1b[Cx", "", keyCommand{action: keyNext}, true, "x"},
+		{"left arrow", "\x01\x1bOD", "", keyCommand{action: keyPrev}, true, ""},
+		{"literal prefix", "a\x01\x01b", "a\x01b", keyCommand{}, false, ""},
+		{"unbound key dropped", "\x01zq", "q", keyCommand{}, false, ""},
+		{"unbound sequence dropped", "\x01\x1b[1;5Aq", "q", keyCommand{}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newPrefixKeys(0x01, nil)
+			forward, cmd, found, rest := k.scan([]byte(tt.input))
+
+			if string(forward) != tt.forward {
+				t.Errorf("forward = %q, want %q", forward, tt.forward)
+			}
+			if found != tt.found || cmd != tt.cmd {
+				t.Errorf("command = %+v (found %v), want %+v (found %v)", cmd, found, tt.cmd, tt.found)
+			}
+			if string(rest) != tt.rest {
+				t.Errorf("rest = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
+
+// A prefix at the end of one read applies to the first key of the next
+func TestPrefixKeys_ScanAcrossReads(t *testing.T) {
+	k := newPrefixKeys(0x01, nil)
+
+	forward, _, found, _ := k.scan([]byte("ab\x01"))
+	if string(forward) != "ab" || found {
+		t.Fatalf("first read: forward = %q, found = %v", forward, found)
+	}
+
+	_, cmd, found, _ := k.scan([]byte("n"))
+	if !found || cmd.action != keyNext {
+		t.Errorf("second read: command = %+v (found %v), want next", cmd, found)
+	}
+}
+
+func TestPrismPicker_Handle(t *testing.T) {
+	p := &prismPicker{names: []string{"clock", "bar", "spotify"}, current: "bar", selected: 1}
+
+	if choice, done := p.handle([]byte("j")); done || choice != "" {
+		t.Errorf("handle(j) = %q, %v, want still open", choice, done)
+	}
+	if p.selected != 2 {
+		t.Errorf("selected after j = %d, want 2", p.selected)
+	}
+
+	p.handle([]byte("\x1b[B"))
+	if p.selected != 0 {
+		t.Errorf("selected after down = %d, want 0 (wraps)", p.selected)
+	}
+
+	if choice, done := p.handle([]byte("\r")); !done || choice != "clock" {
+		t.Errorf("handle(Enter) = %q, %v, want clock", choice, done)
+	}
+	if choice, done := p.handle([]byte("3")); !done || choice != "spotify" {
+		t.Errorf("handle(3) = %q, %v, want spotify", choice, done)
+	}
+	if choice, done := p.handle([]byte("\x1b")); !done || choice != "" {
+		t.Errorf("handle(Esc) = %q, %v, want cancelled", choice, done)
+	}
+}
+
+func TestPrismPicker_Render(t *testing.T) {
+	p := &prismPicker{names: []string{"clock", "bar"}, current: "clock", selected: 1}
+
+	vertical := newVTScreen(20, 4)
+	vertical.Write(p.render(20, 4))
+	if got := vertical.lineText(0); got != "*1 clock" {
+		t.Errorf("vertical line 0 = %q, want %q", got, "*1 clock")
+	}
+	if got := vertical.lineText(1); got != " 2 bar" {
+		t.Errorf("vertical line 1 = %q, want %q", got, " 2 bar")
+	}
+
+	bar := newVTScreen(40, 1)
+	bar.Write(p.render(40, 1))
+	if got := bar.lineText(0); !strings.HasPrefix(got, "*1 clock   2 bar") {
+		t.Errorf("single-row picker = %q, want both prisms on one line", got)
+	}
+	if bar.grid()[0][12].attr.flags&attrReverse == 0 {
+		t.Error("selected prism should be highlighted")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/paths"
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+// appLaunch describes how to spawn an app, as received in prism/configure
+type appLaunch struct {
+	path string
+	args []string
+	env  map[string]string
+	cwd  string
+
+	stopSignal  unix.Signal   // zero = SIGTERM
+	stopTimeout time.Duration // zero = defaultStopTimeout, stopNow = SIGKILL at once
+
+	liveness *livenessProbe // nil = not probed
+
+	runInBackground bool        // keep running instead of SIGSTOP in the background
+	lifecycleSignal unix.Signal // zero = none, see lifecycle.go
+
+	theme    string // SHINE_THEME, empty = unset
+	settings string // SHINE_SETTINGS as a JSON object, empty = unset
+}
+
+// command builds the child command for binaryPath. Env values are expanded
+// against prismctl's environment; args and cwd are expanded against the
+// child's final environment, so they can refer to configured variables.
+func (l appLaunch) command(binaryPath string) *exec.Cmd {
+	env := os.Environ()
+
+	extra := make(map[string]string, len(l.env))
+	names := make([]string, 0, len(l.env))
+	for name, value := range l.env {
+		extra[name] = expandValue(value, os.Getenv)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+extra[name])
+	}
+	// Not expanded, and set last so configured env cannot shadow them
+	if l.theme != "" {
+		env = append(env, rpc.EnvTheme+"="+l.theme)
+	}
+	if l.settings != "" {
+		env = append(env, rpc.EnvSettings+"="+l.settings)
+	}
+
+	lookup := func(name string) string {
+		if value, ok := extra[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+
+	args := make([]string, len(l.args))
+	for i, arg := range l.args {
+		args[i] = expandValue(arg, lookup)
+	}
+
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Env = env
+	if l.cwd != "" {
+		cmd.Dir = expandValue(l.cwd, lookup)
+	}
+
+	return cmd
+}
+
+// expandValue expands a leading ~ and $VAR or ${VAR} references
+func expandValue(value string, lookup func(string) string) string {
+	return paths.ExpandHome(os.Expand(value, lookup))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppLaunch_Command(t *testing.T) {
+	t.Setenv("SHINE_TEST_CITY", "Berlin")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+
+	launch := appLaunch{
+		args: []string{"--city", "$SHINE_TEST_CITY", "--config", "${WEATHER_DIR}/weather.toml", "~/cache"},
+		env: map[string]string{
+			"WEATHER_DIR": "~/.config/weather",
+			"GREETING":    "hello $SHINE_TEST_CITY",
+			"SHINE_THEME": "shadowed",
+		},
+		cwd:      "~",
+		theme:    "nord",
+		settings: `{"city":"$SHINE_TEST_CITY"}`,
+	}
+
+	cmd := launch.command("/usr/bin/shine-weather")
+
+	wantArgs := []string{
+		"/usr/bin/shine-weather",
+		"--city", "Berlin",
+		"--config", filepath.Join(home, ".config/weather") + "/weather.toml",
+		filepath.Join(home, "cache"),
+	}
+	if len(cmd.Args) != len(wantArgs) {
+		t.Fatalf("Args = %q, want %q", cmd.Args, wantArgs)
+	}
+	for i := range wantArgs {
+		if cmd.Args[i] != wantArgs[i] {
+			t.Errorf("Args[%d] = %q, want %q", i, cmd.Args[i], wantArgs[i])
+		}
+	}
+
+	if cmd.Dir != home {
+		t.Errorf("Dir = %q, want %q", cmd.Dir, home)
+	}
+
+	env := make(map[string]string)
+	for _, kv := range cmd.Env {
+		name, value, _ := strings.Cut(kv, "=")
+		env[name] = value
+	}
+	if env["GREETING"] != "hello Berlin" {
+		t.Errorf("GREETING = %q, want %q", env["GREETING"], "hello Berlin")
+	}
+	if env["SHINE_TEST_CITY"] != "Berlin" {
+		t.Error("inherited environment should be passed to the child")
+	}
+	if env["SHINE_THEME"] != "nord" {
+		t.Errorf("SHINE_THEME = %q, want the configured theme", env["SHINE_THEME"])
+	}
+	if env["SHINE_SETTINGS"] != `{"city":"$SHINE_TEST_CITY"}` {
+		t.Errorf("SHINE_SETTINGS = %q, want the settings unexpanded", env["SHINE_SETTINGS"])
+	}
+}
+
+func TestAppLaunch_CommandDefaults(t *testing.T) {
+	cmd := appLaunch{}.command("/usr/bin/shine-clock")
+
+	if len(cmd.Args) != 1 {
+		t.Errorf("Args = %q, want only the binary", cmd.Args)
+	}
+	if cmd.Dir != "" {
+		t.Errorf("Dir = %q, want inherited", cmd.Dir)
+	}
+}
@@ -0,0 +1,418 @@
+// layout.go implements split-pane layouts. In a split layout every
+// configured app runs at the same time in its own sub-rectangle of the real
+// PTY: each child PTY is sized to its pane, and the compositor paints every
+// pane's screen model onto the real PTY at the pane's offset. Input still goes
+// to the foreground prism (prismList[0]), which is the focused pane.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type layoutKind int
+
+const (
+	layoutSingle layoutKind = iota
+	layoutHSplit            // panes side by side, left to right
+	layoutVSplit            // panes stacked, top to bottom
+)
+
+// compositorFrameInterval coalesces bursts of output into one repaint
+const compositorFrameInterval = 16 * time.Millisecond
+
+func parseLayout(layout string) (layoutKind, error) {
+	switch layout {
+	case "", "single":
+		return layoutSingle, nil
+	case "hsplit":
+		return layoutHSplit, nil
+	case "vsplit":
+		return layoutVSplit, nil
+	default:
+		return layoutSingle, fmt.Errorf("unknown layout %q", layout)
+	}
+}
+
+type paneSpec struct {
+	name   string
+	size   int // fixed size in cells, 0 = flexible
+	weight int // share of the remaining cells, 0 = 1
+}
+
+type paneRect struct {
+	x, y, cols, rows int
+}
+
+// computePanes divides a cols×rows area between specs along the split axis.
+// Fixed-size panes are allocated first, in order, and are clamped once the
+// space runs out. The remaining cells are shared between flexible panes in
+// proportion to their weights, with rounding leftovers going to the last one.
+func computePanes(kind layoutKind, specs []paneSpec, cols, rows int) []paneRect {
+	rects := make([]paneRect, len(specs))
+	if len(specs) == 0 {
+		return rects
+	}
+
+	total := cols
+	if kind == layoutVSplit {
+		total = rows
+	}
+
+	lengths := make([]int, len(specs))
+	remaining := total
+	totalWeight := 0
+	lastFlexible := -1
+	for i, spec := range specs {
+		if spec.size > 0 {
+			lengths[i] = min(spec.size, remaining)
+			remaining -= lengths[i]
+			continue
+		}
+		totalWeight += max(spec.weight, 1)
+		lastFlexible = i
+	}
+
+	if lastFlexible >= 0 {
+		flexible := remaining
+		for i, spec := range specs {
+			if spec.size > 0 {
+				continue
+			}
+			if i == lastFlexible {
+				lengths[i] = remaining
+				break
+			}
+			lengths[i] = flexible * max(spec.weight, 1) / totalWeight
+			remaining -= lengths[i]
+		}
+	}
+
+	offset := 0
+	for i, length := range lengths {
+		if kind == layoutVSplit {
+			rects[i] = paneRect{x: 0, y: offset, cols: cols, rows: length}
+		} else {
+			rects[i] = paneRect{x: offset, y: 0, cols: length, rows: rows}
+		}
+		offset += length
+	}
+
+	return rects
+}
+
+type pane struct {
+	paneSpec
+	rect   paneRect
+	output *prismOutput
+	dirty  atomic.Bool
+}
+
+type compositor struct {
+	mu      sync.Mutex
+	kind    layoutKind
+	panes   []*pane
+	cols    int
+	rows    int
+	focused string
+	held    bool    // painting paused while something else owns the screen
+	shown   vtModes // modes the real terminal is in, while not held
+	out     io.Writer
+	kick    chan struct{}
+	stop    chan struct{}
+}
+
+// newCompositor starts painting the panes of a split layout onto out, whose
+// modes are from
+func newCompositor(kind layoutKind, specs []paneSpec, out io.Writer, cols, rows int, from vtModes) *compositor {
+	c := &compositor{
+		kind:  kind,
+		shown: from,
+		out:   out,
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+
+	for _, spec := range specs {
+		c.panes = append(c.panes, &pane{paneSpec: spec})
+	}
+	c.resize(cols, rows)
+
+	go c.run()
+
+	return c
+}
+
+func (c *compositor) findPane(name string) *pane {
+	for _, p := range c.panes {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// matches reports whether this layout is of kind with panes for specs
+func (c *compositor) matches(kind layoutKind, specs []paneSpec) bool {
+	if c.kind != kind || len(c.panes) != len(specs) {
+		return false
+	}
+	for i, p := range c.panes {
+		if p.paneSpec != specs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// has reports whether name is shown in a pane of this layout
+func (c *compositor) has(name string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.findPane(name) != nil
+}
+
+// paneSize returns the size of the pane showing name
+func (c *compositor) paneSize(name string) (cols, rows int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.findPane(name)
+	if p == nil {
+		return 0, 0, false
+	}
+	return p.rect.cols, p.rect.rows, true
+}
+
+// resize recomputes every pane rectangle for a new real PTY size
+func (c *compositor) resize(cols, rows int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cols = cols
+	c.rows = rows
+
+	specs := make([]paneSpec, len(c.panes))
+	for i, p := range c.panes {
+		specs[i] = p.paneSpec
+	}
+	for i, rect := range computePanes(c.kind, specs, cols, rows) {
+		c.panes[i].rect = rect
+	}
+
+	c.invalidateLocked()
+}
+
+// attach shows output in the pane named name, replacing any previous output
+func (c *compositor) attach(name string, output *prismOutput) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.findPane(name)
+	if p == nil {
+		return
+	}
+
+	if p.output != nil && p.output != output {
+		p.output.setNotify(nil)
+	}
+	p.output = output
+	output.setNotify(func() {
+		p.dirty.Store(true)
+		c.wake()
+	})
+
+	p.dirty.Store(true)
+	c.wake()
+}
+
+// detach blanks the pane named name, e.g. after its prism exited
+func (c *compositor) detach(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.findPane(name)
+	if p == nil || p.output == nil {
+		return
+	}
+
+	p.output.setNotify(nil)
+	p.output = nil
+	p.dirty.Store(true)
+	c.wake()
+}
+
+// focus moves the real cursor and input modes to the pane named name
+func (c *compositor) focus(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.focused = name
+	c.invalidateLocked()
+}
+
+// hold pauses painting, e.g. while the prism picker is shown, and returns
+// the modes the compositor left the real terminal in. ok is false without a
+// split layout.
+func (c *compositor) hold() (modes vtModes, ok bool) {
+	if c == nil {
+		return vtModes{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.held = true
+	return c.shown, true
+}
+
+// release resumes painting with a full repaint of a real terminal whose
+// modes are from
+func (c *compositor) release(from vtModes) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.held = false
+	c.shown = from
+	c.invalidateLocked()
+}
+
+func (c *compositor) invalidateLocked() {
+	for _, p := range c.panes {
+		p.dirty.Store(true)
+	}
+	c.wake()
+}
+
+func (c *compositor) wake() {
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+// close stops painting and returns the modes the compositor left the real
+// terminal in, like hold
+func (c *compositor) close() (modes vtModes, ok bool) {
+	if c == nil {
+		return vtModes{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	for _, p := range c.panes {
+		if p.output != nil {
+			p.output.setNotify(nil)
+		}
+	}
+	return c.shown, true
+}
+
+func (c *compositor) run() {
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-c.kick:
+		}
+
+		c.paint()
+
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(compositorFrameInterval):
+		}
+	}
+}
+
+// paint repaints every dirty pane and then restores the focused pane's
+// cursor and input modes. Panes are drawn with the cursor hidden and
+// autowrap off, which stays off while the layout is shown.
+func (c *compositor) paint() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.stop:
+		return
+	default:
+	}
+	if c.held {
+		return
+	}
+
+	painting := c.shown
+	painting.cursorHidden = true
+	painting.autowrapOff = true
+
+	var b bytes.Buffer
+	b.WriteString(modeTransition(c.shown, painting))
+
+	shown := painting
+	var cursor string
+	for _, p := range c.panes {
+		if p.rect.cols == 0 || p.rect.rows == 0 {
+			continue
+		}
+
+		if p.output == nil {
+			if p.dirty.Swap(false) {
+				writeBlankRect(&b, p.rect)
+			}
+			continue
+		}
+
+		p.output.mu.Lock()
+		screen := p.output.screen
+		if p.dirty.Swap(false) {
+			screen.renderPane(&b, p.rect.x, p.rect.y)
+		}
+		if p.name == c.focused {
+			focused := screen.modes
+			shown.appCursorKeys = focused.appCursorKeys
+			shown.focusEvents = focused.focusEvents
+			shown.bracketedPaste = focused.bracketedPaste
+			shown.appKeypad = focused.appKeypad
+			shown.cursorHidden = focused.cursorHidden
+			cursor = sgrSequence(screen.cursor.pen) +
+				fmt.Sprintf("\x1b[%d;%dH", p.rect.y+screen.cursor.y+1, p.rect.x+screen.cursor.x+1)
+		}
+		p.output.mu.Unlock()
+	}
+	b.WriteString(cursor)
+	b.WriteString(modeTransition(painting, shown))
+	c.shown = shown
+
+	if _, err := c.out.Write(b.Bytes()); err != nil {
+		log.Printf("Compositor: failed to write to real PTY: %v", err)
+	}
+}
+
+func writeBlankRect(b *bytes.Buffer, rect paneRect) {
+	b.WriteString("\x1b[0m")
+	for y := 0; y < rect.rows; y++ {
+		fmt.Fprintf(b, "\x1b[%d;%dH\x1b[%dX", rect.y+y+1, rect.x+1, rect.cols)
+	}
+}
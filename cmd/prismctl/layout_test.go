@@ -0,0 +1,147 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputePanes(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  layoutKind
+		specs []paneSpec
+		cols  int
+		rows  int
+		want  []paneRect
+	}{
+		{
+			name:  "equal weights",
+			kind:  layoutHSplit,
+			specs: []paneSpec{{name: "a"}, {name: "b"}, {name: "c"}},
+			cols:  10,
+			rows:  1,
+			want:  []paneRect{{0, 0, 3, 1}, {3, 0, 3, 1}, {6, 0, 4, 1}},
+		},
+		{
+			name:  "fixed and weighted",
+			kind:  layoutHSplit,
+			specs: []paneSpec{{name: "clock", size: 8}, {name: "bar", weight: 3}, {name: "spotify", weight: 1}},
+			cols:  48,
+			rows:  1,
+			want:  []paneRect{{0, 0, 8, 1}, {8, 0, 30, 1}, {38, 0, 10, 1}},
+		},
+		{
+			name:  "vertical",
+			kind:  layoutVSplit,
+			specs: []paneSpec{{name: "top", size: 2}, {name: "rest"}},
+			cols:  20,
+			rows:  10,
+			want:  []paneRect{{0, 0, 20, 2}, {0, 2, 20, 8}},
+		},
+		{
+			name:  "fixed sizes clamped",
+			kind:  layoutHSplit,
+			specs: []paneSpec{{name: "a", size: 6}, {name: "b", size: 6}, {name: "c"}},
+			cols:  8,
+			rows:  1,
+			want:  []paneRect{{0, 0, 6, 1}, {6, 0, 2, 1}, {8, 0, 0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computePanes(tt.kind, tt.specs, tt.cols, tt.rows)
+			if len(got) != len(tt.want) {
+				t.Fatalf("computePanes() returned %d panes, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("pane %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	for layout, want := range map[string]layoutKind{
+		"":       layoutSingle,
+		"single": layoutSingle,
+		"hsplit": layoutHSplit,
+		"vsplit": layoutVSplit,
+	} {
+		got, err := parseLayout(layout)
+		if err != nil || got != want {
+			t.Errorf("parseLayout(%q) = %v, %v, want %v", layout, got, err, want)
+		}
+	}
+
+	if _, err := parseLayout("grid"); err == nil {
+		t.Error("parseLayout(\"grid\") should fail")
+	}
+}
+
+// The compositor must place each pane's screen at the pane's offset
+func TestCompositor_PaintsPanesAtOffsets(t *testing.T) {
+	var real syncBuffer
+	c := newCompositor(layoutHSplit, []paneSpec{{name: "left"}, {name: "right"}}, &real, 10, 1, vtModes{})
+	defer c.close()
+
+	left := &prismOutput{screen: newVTScreen(5, 1)}
+	right := &prismOutput{screen: newVTScreen(5, 1)}
+	left.screen.Write([]byte("abc"))
+	right.screen.Write([]byte("xyz"))
+
+	c.attach("left", left)
+	c.attach("right", right)
+	c.focus("right")
+
+	composed := newVTScreen(10, 1)
+	replay := func() string {
+		composed.reset()
+		composed.Write([]byte(real.String()))
+		return composed.lineText(0)
+	}
+
+	waitFor(t, func() bool { return replay() == "abc  xyz" })
+
+	if composed.cursor.x != 8 {
+		t.Errorf("cursor.x = %d, want 8 (end of focused pane)", composed.cursor.x)
+	}
+
+	c.detach("left")
+	waitFor(t, func() bool { return strings.TrimSpace(replay()) == "xyz" })
+}
+
+// The compositor must hand back the modes it painted the real terminal
+// into, so they are restored once the layout is gone
+func TestCompositor_TracksModes(t *testing.T) {
+	var real syncBuffer
+	from := vtModes{bracketedPaste: true}
+	c := newCompositor(layoutVSplit, []paneSpec{{name: "top"}}, &real, 10, 1, from)
+
+	top := &prismOutput{screen: newVTScreen(10, 1)}
+	top.screen.Write([]byte("\x1b[?1h\x1b[?25ltop"))
+	c.attach("top", top)
+	c.focus("top")
+
+	waitFor(t, func() bool { return strings.Contains(real.String(), "top") })
+
+	modes, ok := c.close()
+	want := vtModes{autowrapOff: true, cursorHidden: true, appCursorKeys: true}
+	if !ok || modes != want {
+		t.Errorf("close() = %+v, %v, want %+v", modes, ok, want)
+	}
+
+	// The terminal really is in those modes
+	replayed := newVTScreen(10, 1)
+	replayed.modes = from
+	replayed.Write([]byte(real.String()))
+	if replayed.modes != want {
+		t.Errorf("painted modes = %+v, want %+v", replayed.modes, want)
+	}
+
+	if restore := modeTransition(modes, vtModes{}); !strings.Contains(restore, "\x1b[?7h") || !strings.Contains(restore, "\x1b[?25h") {
+		t.Errorf("modeTransition() = %q, want autowrap and the cursor restored", restore)
+	}
+}
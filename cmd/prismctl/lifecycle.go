@@ -0,0 +1,179 @@
+// lifecycle.go tells prisms where they stand. A prism is in the foreground
+// while it is shown, focused while it also receives input and the panel has
+// keyboard focus, and hidden while it is not on screen: in the background,
+// or while the panel itself is hidden.
+//
+// Changes are delivered two ways. A prism that enabled focus reporting
+// (?1004) is sent CSI I when it gains focus and CSI O when it loses it, as a
+// terminal would; the real terminal's own focus reports are consumed by the
+// mirror and fed into the panel's focus. A prism configured with a
+// lifecycle_signal is also sent that signal on every change and can ask the
+// child socket (child/lifecycle) what changed.
+//
+// Background prisms are SIGSTOPped unless their app runs with background =
+// "run", in which case they keep running and are merely hidden.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	focusIn             = "\x1b[I"
+	focusOut            = "\x1b[O"
+	enableFocusReports  = "\x1b[?1004h"
+	disableFocusReports = "\x1b[?1004l"
+)
+
+// lifecycleState is what a prism was last told about itself
+type lifecycleState struct {
+	foreground bool // shown: in the foreground or a split layout pane
+	focused    bool // shown, receiving input, and the panel is focused
+	hidden     bool // not on screen
+}
+
+// lifecycleOf returns the state of the prism at idx
+// Assumes caller holds s.mu lock
+func (s *supervisor) lifecycleOf(idx int) lifecycleState {
+	p := s.prismList[idx]
+
+	input := idx == 0 && s.hasForeground()
+	foreground := input || s.layout.has(p.name)
+	hidden := !foreground || s.panelHidden
+
+	return lifecycleState{
+		foreground: foreground,
+		focused:    input && !hidden && s.panelFocused,
+		hidden:     hidden,
+	}
+}
+
+// updateLifecycle tells every prism whose state changed since it was last
+// told. It is called after anything that moves the foreground, focus or
+// visibility, and before a prism is suspended so the news is waiting for it
+// when it resumes.
+// Assumes caller holds s.mu lock
+func (s *supervisor) updateLifecycle() {
+	for i := range s.prismList {
+		p := &s.prismList[i]
+		now := s.lifecycleOf(i)
+		if now == p.lifecycle {
+			continue
+		}
+
+		if now.focused != p.lifecycle.focused && p.output.focusEvents() {
+			seq := focusOut
+			if now.focused {
+				seq = focusIn
+			}
+			if _, err := p.ptyMaster.Write([]byte(seq)); err != nil && !isExpectedPTYError(err) {
+				log.Printf("Warning: failed to send focus event to %s: %v", p.name, err)
+			}
+		}
+
+		// The prism's own process only: it is the one that asked for the
+		// signal and can query the child socket
+		if p.lifecycleSignal != 0 && p.pid > 1 {
+			if err := unix.Kill(p.pid, p.lifecycleSignal); err != nil {
+				log.Printf("Warning: failed to send lifecycle signal to %s: %v", p.name, err)
+			}
+		}
+
+		p.lifecycle = now
+	}
+}
+
+// setPanelFocus records whether the panel has keyboard focus
+func (s *supervisor) setPanelFocus(focused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.panelFocused == focused {
+		return
+	}
+
+	s.panelFocused = focused
+	s.updateLifecycle()
+}
+
+// setPanelHidden records whether the panel is hidden and reports whether
+// that changed
+func (s *supervisor) setPanelHidden(hidden bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.panelHidden == hidden {
+		return false
+	}
+
+	if hidden {
+		log.Printf("Panel hidden")
+	} else {
+		log.Printf("Panel shown")
+	}
+
+	s.panelHidden = hidden
+	s.updateLifecycle()
+	return true
+}
+
+// stripFocusEvents removes the real terminal's focus reports from input.
+// focus is +1 when the last one reported focus in, -1 for focus out and 0
+// when there were none.
+func stripFocusEvents(data []byte) (rest []byte, focus int) {
+	if !bytes.Contains(data, []byte("\x1b[")) {
+		return data, 0
+	}
+
+	rest = make([]byte, 0, len(data))
+	for len(data) > 0 {
+		switch {
+		case bytes.HasPrefix(data, []byte(focusIn)):
+			focus = 1
+			data = data[len(focusIn):]
+		case bytes.HasPrefix(data, []byte(focusOut)):
+			focus = -1
+			data = data[len(focusOut):]
+		default:
+			rest = append(rest, data[0])
+			data = data[1:]
+		}
+	}
+	return rest, focus
+}
+
+// focusReportingTerminal keeps focus reporting enabled on the panel's
+// terminal, so the panel's focus is known whatever the foreground prism
+// asks for. Prisms get focus events from updateLifecycle instead.
+type focusReportingTerminal struct {
+	RealTerminal
+}
+
+func newFocusReportingTerminal(term RealTerminal) (*focusReportingTerminal, error) {
+	if _, err := term.Write([]byte(enableFocusReports)); err != nil {
+		return nil, fmt.Errorf("failed to enable focus reporting: %w", err)
+	}
+	return &focusReportingTerminal{RealTerminal: term}, nil
+}
+
+// Write drops requests to disable focus reporting
+func (t *focusReportingTerminal) Write(p []byte) (int, error) {
+	if !bytes.Contains(p, []byte(disableFocusReports)) {
+		return t.RealTerminal.Write(p)
+	}
+
+	if _, err := t.RealTerminal.Write(bytes.ReplaceAll(p, []byte(disableFocusReports), nil)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (t *focusReportingTerminal) Restore() error {
+	t.RealTerminal.Write([]byte(disableFocusReports))
+	return t.RealTerminal.Restore()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestStripFocusEvents(t *testing.T) {
+	tests := []struct {
+		in    string
+		rest  string
+		focus int
+	}{
+		{"abc", "abc", 0},
+		{"\x1b[I", "", 1},
+		{"a\x1b[Ib\x1b[Oc", "abc", -1},
+		{"\x1b[O\x1b[I", "", 1},
+		{"\x1b[A\x1b[", "\x1b[A\x1b[", 0},
+	}
+
+	for _, tt := range tests {
+		rest, focus := stripFocusEvents([]byte(tt.in))
+		if string(rest) != tt.rest || focus != tt.focus {
+			t.Errorf("stripFocusEvents(%q) = %q, %d, want %q, %d", tt.in, rest, focus, tt.rest, tt.focus)
+		}
+	}
+}
+
+func TestMirror_ConsumesFocusReports(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	var focused []bool
+	state := &mirrorState{childPTY: w}
+	state.setFocusHandler(func(f bool) { focused = append(focused, f) })
+
+	state.route([]byte("a\x1b[Ob"))
+	state.route([]byte("\x1b[I"))
+
+	buf := make([]byte, 16)
+	n, _ := r.Read(buf)
+	if got := string(buf[:n]); got != "ab" {
+		t.Errorf("prism received %q, want the input without focus reports", got)
+	}
+	if len(focused) != 2 || focused[0] || !focused[1] {
+		t.Errorf("focus handler saw %v, want [false true]", focused)
+	}
+}
+
+func TestFocusReportingTerminal(t *testing.T) {
+	panel := &fakePanel{}
+
+	term, err := newFocusReportingTerminal(panel)
+	if err != nil {
+		t.Fatalf("newFocusReportingTerminal() error: %v", err)
+	}
+
+	// A prism turning focus reporting off must not blind the panel
+	frame := []byte("x\x1b[?1004ly")
+	if n, err := term.Write(frame); err != nil || n != len(frame) {
+		t.Errorf("Write() = %d, %v, want every byte consumed", n, err)
+	}
+	term.Restore()
+
+	if got := panel.out.String(); got != "\x1b[?1004hxy\x1b[?1004l" {
+		t.Errorf("panel output = %q", got)
+	}
+}
+
+func TestSupervisor_LifecycleEvents(t *testing.T) {
+	dir := t.TempDir()
+
+	term, err := listenAttach(filepath.Join(dir, "attach.sock"), nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	// editor records the focus events it receives; ticker counts the
+	// lifecycle signals it gets and keeps running in the background
+	editor := filepath.Join(dir, "editor.sh")
+	ticker := filepath.Join(dir, "ticker.sh")
+	scripts := map[string]string{
+		editor: "stty raw -echo\nprintf '\\033[?1004h'\nexec cat > " + dir + "/editor.in\n",
+		ticker: "trap 'echo usr1 >> " + dir + "/ticker.sig' USR1\n: > " + dir + "/ticker.ready\nwhile :; do sleep 0.05; done\n",
+	}
+	for path, script := range scripts {
+		if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sup := newSupervisor(term, nil, nil)
+	sup.registerApp("editor", appLaunch{path: "/bin/sh", args: []string{editor}})
+	sup.registerApp("ticker", appLaunch{path: "/bin/sh", args: []string{ticker}, runInBackground: true, lifecycleSignal: unix.SIGUSR1})
+
+	t.Cleanup(func() {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		for _, p := range sup.prismList {
+			unix.Kill(-p.pid, unix.SIGKILL)
+			var status unix.WaitStatus
+			unix.Wait4(p.pid, &status, 0, nil)
+		}
+	})
+
+	if err := sup.start("editor"); err != nil {
+		t.Fatalf("start(editor) error: %v", err)
+	}
+	waitFor(t, func() bool {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		return sup.prismList[0].output.focusEvents()
+	})
+
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+
+	// The editor loses focus to the ticker, and gets it back once resumed
+	if err := sup.start("ticker"); err != nil {
+		t.Fatalf("start(ticker) error: %v", err)
+	}
+	// Without its trap in place the signal would kill the ticker
+	waitFor(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "ticker.ready"))
+		return err == nil
+	})
+	if err := sup.start("editor"); err != nil {
+		t.Fatalf("start(editor) error: %v", err)
+	}
+	waitFor(t, func() bool { return read("editor.in") == "\x1b[O\x1b[I" })
+
+	// The ticker was told it is hidden, but kept running
+	waitFor(t, func() bool { return read("ticker.sig") == "usr1\n" })
+	sup.mu.Lock()
+	tickerIdx := sup.findPrism("ticker")
+	tickerPID, lifecycle := sup.prismList[tickerIdx].pid, sup.lifecycleOf(tickerIdx)
+	sup.mu.Unlock()
+	if !lifecycle.hidden || lifecycle.foreground {
+		t.Errorf("ticker lifecycle = %+v, want hidden", lifecycle)
+	}
+	if processState(t, tickerPID) == 'T' {
+		t.Error("ticker was stopped in the background despite background = run")
+	}
+
+	// Losing the panel's focus reaches the foreground prism only
+	sup.setPanelFocus(false)
+	waitFor(t, func() bool { return read("editor.in") == "\x1b[O\x1b[I\x1b[O" })
+
+	if !sup.setPanelHidden(true) || sup.setPanelHidden(true) {
+		t.Error("setPanelHidden() should report only the change")
+	}
+	sup.mu.Lock()
+	lifecycle = sup.lifecycleOf(0)
+	sup.mu.Unlock()
+	if !lifecycle.foreground || !lifecycle.hidden || lifecycle.focused {
+		t.Errorf("editor lifecycle in a hidden panel = %+v", lifecycle)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if got := read("ticker.sig"); got != "usr1\n" {
+		t.Errorf("ticker got %q, want no signal when nothing changed for it", got)
+	}
+}
@@ -0,0 +1,328 @@
+// liveness.go probes prisms that are running but may be hung. A deadlocked
+// prism keeps its PID, so waiting for it to exit does not catch it. An app
+// configured with a liveness probe is probed every interval while it runs:
+//
+//	output:    it wrote output within the timeout
+//	heartbeat: it called child/heartbeat on the child socket within the
+//	           timeout, as prisms built with prism.Run do every second
+//	exec:      a probe command exits 0 within the timeout
+//
+// Suspended background prisms cannot answer and are not probed. After
+// enough consecutive failures the prism is marked unhealthy, which shows in
+// prism/list and is reported to shined, and depending on the action a
+// desktop notification is sent or the prism is restarted.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/config"
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+const (
+	defaultProbeInterval = 10 * time.Second
+	defaultProbeTimeout  = 5 * time.Second
+	defaultProbeFailures = 3
+)
+
+// desktopNotify shows a desktop notification; replaced in tests
+var desktopNotify = func(summary, body string) error {
+	return exec.Command("notify-send", "--app-name=shine", summary, body).Run()
+}
+
+// livenessProbe is an app's parsed liveness configuration
+type livenessProbe struct {
+	probe    string // "output", "heartbeat" or "exec"
+	command  []string
+	interval time.Duration
+	timeout  time.Duration
+	failures int    // consecutive failures before the prism is unhealthy
+	action   string // "unhealthy", "notify" or "restart"
+}
+
+// prismHealth is the liveness state of a running prism
+type prismHealth struct {
+	failures  int // consecutive failed probes
+	unhealthy bool
+	reason    string // last probe failure
+}
+
+// parseLiveness converts a liveness probe received in prism/configure, nil
+// when the app has none
+func parseLiveness(info *rpc.LivenessInfo) (*livenessProbe, error) {
+	if info == nil {
+		return nil, nil
+	}
+
+	cfg := config.LivenessConfig{
+		Probe:    info.Probe,
+		Command:  info.Command,
+		Interval: info.Interval,
+		Timeout:  info.Timeout,
+		Failures: info.Failures,
+		Action:   info.Action,
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	probe := &livenessProbe{
+		probe:    info.Probe,
+		command:  info.Command,
+		interval: defaultProbeInterval,
+		timeout:  defaultProbeTimeout,
+		failures: defaultProbeFailures,
+		action:   "unhealthy",
+	}
+	// Validate has checked the durations
+	if info.Interval != "" {
+		probe.interval, _ = time.ParseDuration(info.Interval)
+	}
+	if info.Timeout != "" {
+		probe.timeout, _ = time.ParseDuration(info.Timeout)
+	}
+	if info.Failures > 0 {
+		probe.failures = info.Failures
+	}
+	if info.Action != "" {
+		probe.action = info.Action
+	}
+
+	return probe, nil
+}
+
+// watchLiveness probes prism until it exits
+func (s *supervisor) watchLiveness(prism prismInstance, probe livenessProbe) {
+	ticker := time.NewTicker(probe.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-prism.exit.done:
+			return
+		case <-ticker.C:
+		}
+
+		if !s.probeable(prism.pid) {
+			continue
+		}
+
+		s.reportProbe(prism, probe, probe.run(prism))
+	}
+}
+
+// probeable reports whether the prism with pid is running, i.e. neither
+// suspended in the background nor gone
+func (s *supervisor) probeable(pid int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.prismList {
+		if p.pid == pid {
+			return p.state == prismForeground || p.runInBackground || s.layout.has(p.name)
+		}
+	}
+	return false
+}
+
+// run probes prism once; a nil error means it is alive
+func (p livenessProbe) run(prism prismInstance) error {
+	switch p.probe {
+	case "output":
+		if silent := prism.output.silentFor(); silent > p.timeout {
+			return fmt.Errorf("no output for %s", silent.Round(time.Second))
+		}
+		return nil
+
+	case "heartbeat":
+		if since := prism.heartbeat.since(); since > p.timeout {
+			return fmt.Errorf("no heartbeat for %s", since.Round(time.Second))
+		}
+		return nil
+
+	case "exec":
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+		cmd.Env = append(os.Environ(),
+			rpc.EnvPrism+"="+prism.name,
+			"SHINE_PRISM_PID="+strconv.Itoa(prism.pid),
+		)
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("probe command timed out after %s", p.timeout)
+			}
+			return fmt.Errorf("probe command failed: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown probe %q", p.probe)
+}
+
+// reportProbe records a probe result and acts once the prism has failed
+// enough probes in a row
+func (s *supervisor) reportProbe(prism prismInstance, probe livenessProbe, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.findPID(prism.pid)
+	if idx == -1 {
+		// Exited while being probed
+		return
+	}
+
+	health, ok := s.health[prism.pid]
+	if !ok {
+		health = &prismHealth{}
+		s.health[prism.pid] = health
+	}
+
+	if err == nil {
+		health.failures = 0
+		if health.unhealthy {
+			log.Printf("Prism %s (PID %d) is healthy again", prism.name, prism.pid)
+			health.unhealthy = false
+			health.reason = ""
+			if s.notifyMgr != nil {
+				s.notifyMgr.OnPrismHealth(prism.name, true, "")
+			}
+		}
+		return
+	}
+
+	health.failures++
+	health.reason = err.Error()
+	log.Printf("Liveness probe failed for %s (PID %d, %d/%d): %v", prism.name, prism.pid, health.failures, probe.failures, err)
+
+	if health.unhealthy || health.failures < probe.failures {
+		return
+	}
+
+	health.unhealthy = true
+	log.Printf("Prism %s (PID %d) is unhealthy: %v", prism.name, prism.pid, err)
+
+	if s.notifyMgr != nil {
+		s.notifyMgr.OnPrismHealth(prism.name, false, health.reason)
+	}
+
+	switch probe.action {
+	case "notify":
+		reason := health.reason
+		go func() {
+			if err := desktopNotify("shine: "+prism.name+" is not responding", reason); err != nil {
+				log.Printf("Warning: failed to send desktop notification: %v", err)
+			}
+		}()
+
+	case "restart":
+		log.Printf("Restarting unhealthy prism %s (PID %d)", prism.name, prism.pid)
+		s.restarts[prism.name] = true
+		if err := s.stopLocked(s.prismList[idx]); err != nil {
+			delete(s.restarts, prism.name)
+			log.Printf("Warning: failed to restart %s: %v", prism.name, err)
+		}
+	}
+}
+
+// prismHealthState returns a prism's health for prism/list, empty when it
+// has no liveness probe
+// Assumes caller holds s.mu lock
+func (s *supervisor) prismHealthState(prism prismInstance) (state, reason string) {
+	if prism.liveness == nil {
+		return "", ""
+	}
+
+	health, ok := s.health[prism.pid]
+	if !ok || !health.unhealthy {
+		return "healthy", ""
+	}
+	return "unhealthy", health.reason
+}
+
+// unhealthyPrisms returns the names of prisms failing their liveness probe
+// Assumes caller holds s.mu lock
+func (s *supervisor) unhealthyPrisms() []string {
+	var names []string
+	for _, p := range s.prismList {
+		if health, ok := s.health[p.pid]; ok && health.unhealthy {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+// relaunch starts a prism stopped by its liveness probe, or by a
+// reconfiguration of its app, again. A prism that was not in the foreground
+// gives it back to the prism that had it. It reports whether the prism is
+// running again.
+// Assumes caller holds s.mu lock
+func (s *supervisor) relaunch(name string, wasForeground, unhealthy bool) bool {
+	focused := ""
+	if !wasForeground && s.hasForeground() {
+		focused = s.prismList[0].name
+	}
+
+	if err := s.launchAndForeground(name); err != nil {
+		log.Printf("Failed to restart prism %s: %v", name, err)
+		return false
+	}
+
+	log.Printf("Restarted prism %s (PID %d)", name, s.prismList[0].pid)
+
+	if focused != "" {
+		if err := s.resumeToForeground(s.findPrism(focused)); err != nil {
+			log.Printf("Warning: failed to refocus %s: %v", focused, err)
+		}
+	}
+
+	if s.notifyMgr != nil && unhealthy {
+		s.notifyMgr.OnPrismHealth(name, true, "restarted by liveness probe")
+	}
+
+	return true
+}
+
+// silentFor returns how long ago the prism last wrote output
+func (o *prismOutput) silentFor() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return time.Since(o.lastOutput)
+}
+
+// heartbeat is when a prism last called child/heartbeat, or was started
+type heartbeat struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newHeartbeat() *heartbeat {
+	return &heartbeat{last: time.Now()}
+}
+
+// beat records a heartbeat
+func (h *heartbeat) beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last = time.Now()
+}
+
+// since returns how long ago the last heartbeat was
+func (h *heartbeat) since() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return time.Since(h.last)
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseLiveness(t *testing.T) {
+	probe, err := parseLiveness(nil)
+	if err != nil || probe != nil {
+		t.Fatalf("parseLiveness(nil) = %v, %v, want no probe", probe, err)
+	}
+
+	probe, err = parseLiveness(&rpc.LivenessInfo{Probe: "output"})
+	if err != nil {
+		t.Fatalf("parseLiveness() error: %v", err)
+	}
+	if probe.interval != defaultProbeInterval || probe.timeout != defaultProbeTimeout ||
+		probe.failures != defaultProbeFailures || probe.action != "unhealthy" {
+		t.Errorf("defaults not applied: %+v", probe)
+	}
+
+	probe, err = parseLiveness(&rpc.LivenessInfo{Probe: "exec", Command: []string{"true"}, Interval: "1m", Timeout: "2s", Failures: 1, Action: "restart"})
+	if err != nil {
+		t.Fatalf("parseLiveness() error: %v", err)
+	}
+	if probe.interval != time.Minute || probe.timeout != 2*time.Second || probe.failures != 1 || probe.action != "restart" {
+		t.Errorf("settings not applied: %+v", probe)
+	}
+
+	if _, err := parseLiveness(&rpc.LivenessInfo{Probe: "exec"}); err == nil {
+		t.Error("parseLiveness() should reject an exec probe without a command")
+	}
+}
+
+func TestLivenessProbe_Output(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	prism := prismInstance{name: "clock", output: startPrismOutput(r, 20, 2)}
+	probe := livenessProbe{probe: "output", timeout: 50 * time.Millisecond}
+
+	if err := probe.run(prism); err != nil {
+		t.Errorf("run() right after start = %v, want alive", err)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if err := probe.run(prism); err == nil {
+		t.Error("run() after a silent timeout should fail")
+	}
+
+	w.Write([]byte("12:00"))
+	waitFor(t, func() bool { return screenLine(prism.output, 0) == "12:00" })
+	if err := probe.run(prism); err != nil {
+		t.Errorf("run() after output = %v, want alive", err)
+	}
+}
+
+func TestLivenessProbe_Heartbeat(t *testing.T) {
+	prism := prismInstance{name: "chat", heartbeat: newHeartbeat()}
+	probe := livenessProbe{probe: "heartbeat", timeout: 50 * time.Millisecond}
+
+	if err := probe.run(prism); err != nil {
+		t.Errorf("run() right after start = %v, want alive", err)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if err := probe.run(prism); err == nil {
+		t.Error("run() without a heartbeat within the timeout should fail")
+	}
+
+	prism.heartbeat.beat()
+	if err := probe.run(prism); err != nil {
+		t.Errorf("run() after a heartbeat = %v, want alive", err)
+	}
+}
+
+// TestLivenessProbe_HeartbeatPrism tests that a prism built with prism.Run
+// sends heartbeats over the child socket
+func TestLivenessProbe_HeartbeatPrism(t *testing.T) {
+	dir := t.TempDir()
+	term, err := listenAttach(filepath.Join(dir, "attach.sock"), nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	sup := newSupervisor(term, nil, nil)
+
+	sockPath := filepath.Join(dir, "child.sock")
+	srv := rpc.NewServer(sockPath, newChildHandlers(sup), nil, rpc.WithPeerContext(sup.peerContext))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+	sup.childSocket = sockPath
+
+	// A dumb TERM keeps bubbletea from waiting on a background color query
+	// that nothing here answers
+	sup.registerApp("sdk", appLaunch{
+		path:        os.Args[0],
+		args:        []string{"-test.run=^TestHelperPrism$"},
+		env:         map[string]string{helperEnv: "sdk", "TERM": "dumb"},
+		stopTimeout: time.Second,
+	})
+
+	if err := sup.start("sdk"); err != nil {
+		t.Fatalf("start() error: %v", err)
+	}
+
+	sup.mu.Lock()
+	prism := sup.prismList[0]
+	sup.mu.Unlock()
+	t.Cleanup(func() {
+		unix.Kill(-prism.pid, unix.SIGKILL)
+		var status unix.WaitStatus
+		unix.Wait4(prism.pid, &status, 0, nil)
+	})
+
+	// Launched longer ago than the timeout, so only a heartbeat passes
+	time.Sleep(100 * time.Millisecond)
+	probe := livenessProbe{probe: "heartbeat", timeout: 100 * time.Millisecond}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for probe.run(prism) != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("no heartbeat from the prism: %v", probe.run(prism))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLivenessProbe_Exec(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "probed")
+	prism := prismInstance{name: "weather", pid: 4242}
+
+	ok := livenessProbe{probe: "exec", command: []string{"sh", "-c", `echo "$SHINE_PRISM $SHINE_PRISM_PID" > ` + marker}, timeout: time.Second}
+	if err := ok.run(prism); err != nil {
+		t.Fatalf("run() error: %v", err)
+	}
+	data, err := os.ReadFile(marker)
+	if err != nil || strings.TrimSpace(string(data)) != "weather 4242" {
+		t.Errorf("probe environment = %q, %v, want weather 4242", data, err)
+	}
+
+	failing := livenessProbe{probe: "exec", command: []string{"false"}, timeout: time.Second}
+	if err := failing.run(prism); err == nil {
+		t.Error("run() of a failing command should fail")
+	}
+
+	hung := livenessProbe{probe: "exec", command: []string{"sleep", "5"}, timeout: 50 * time.Millisecond}
+	if err := hung.run(prism); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("run() of a hung command = %v, want a timeout", err)
+	}
+}
+
+// TestLivenessProbe_ExecWithReaper tests that the SIGCHLD handler leaves
+// exec probes to os/exec
+func TestLivenessProbe_ExecWithReaper(t *testing.T) {
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+
+	sh := newSignalHandler(sup)
+	done := make(chan struct{})
+	go func() {
+		sh.run()
+		close(done)
+	}()
+	defer func() {
+		sh.stop()
+		<-done
+	}()
+
+	_, prism := startShell(t, "clock", "sleep 30", unix.SIGTERM, time.Second)
+	sup.mu.Lock()
+	sup.prismList = append(sup.prismList, prism)
+	sup.mu.Unlock()
+
+	probe := livenessProbe{probe: "exec", command: []string{"true"}, timeout: time.Second}
+	for i := 0; i < 50; i++ {
+		if err := probe.run(prism); err != nil {
+			t.Fatalf("run() #%d = %v, want the probe to pass", i, err)
+		}
+	}
+
+	// A helper that exited before os/exec waits for it, with a SIGCHLD
+	// handled in between
+	helper := exec.Command("true")
+	if err := helper.Start(); err != nil {
+		t.Fatalf("failed to start helper: %v", err)
+	}
+	stat := filepath.Join("/proc", strconv.Itoa(helper.Process.Pid), "stat")
+	waitFor(t, func() bool {
+		data, err := os.ReadFile(stat)
+		return err == nil && strings.Contains(string(data), ") Z ")
+	})
+	unix.Kill(os.Getpid(), unix.SIGCHLD)
+	time.Sleep(50 * time.Millisecond)
+	if err := helper.Wait(); err != nil {
+		t.Errorf("Wait() = %v, want the helper left to os/exec", err)
+	}
+
+	// Prisms are still reaped
+	unix.Kill(-prism.pid, unix.SIGKILL)
+	select {
+	case <-prism.exit.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the killed prism was not reaped")
+	}
+}
+
+func TestSupervisor_UnhealthyAfterFailures(t *testing.T) {
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+
+	probe := livenessProbe{probe: "output", failures: 2, action: "unhealthy"}
+	_, prism := startShell(t, "clock", "while :; do sleep 0.02; done", unix.SIGTERM, time.Second)
+	prism.state = prismForeground
+	prism.liveness = &probe
+	sup.prismList = append(sup.prismList, prism)
+
+	health := func() (string, string) {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		return sup.prismHealthState(sup.prismList[0])
+	}
+
+	failure := errors.New("no output for 1m0s")
+
+	sup.reportProbe(prism, probe, failure)
+	if state, _ := health(); state != "healthy" {
+		t.Errorf("health after one failure = %q, want healthy", state)
+	}
+
+	sup.reportProbe(prism, probe, failure)
+	state, reason := health()
+	if state != "unhealthy" || reason != failure.Error() {
+		t.Errorf("health after two failures = %q (%q), want unhealthy", state, reason)
+	}
+
+	sup.mu.Lock()
+	unhealthy := sup.unhealthyPrisms()
+	sup.mu.Unlock()
+	if len(unhealthy) != 1 || unhealthy[0] != "clock" {
+		t.Errorf("unhealthyPrisms() = %v, want [clock]", unhealthy)
+	}
+
+	sup.reportProbe(prism, probe, nil)
+	if state, _ := health(); state != "healthy" {
+		t.Errorf("health after a passing probe = %q, want healthy", state)
+	}
+}
+
+func TestSupervisor_NotProbedWhileSuspended(t *testing.T) {
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+
+	_, prism := startShell(t, "clock", "while :; do sleep 0.02; done", unix.SIGTERM, time.Second)
+	sup.prismList = append(sup.prismList, prism)
+
+	if sup.probeable(prism.pid) {
+		t.Error("a suspended background prism should not be probed")
+	}
+
+	sup.prismList[0].state = prismForeground
+	if !sup.probeable(prism.pid) {
+		t.Error("the foreground prism should be probed")
+	}
+}
+
+func TestSupervisor_LivenessRestart(t *testing.T) {
+	term, err := listenAttach(filepath.Join(t.TempDir(), "attach.sock"), nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	sup := newSupervisor(term, nil, nil)
+
+	probe := &livenessProbe{probe: "output", interval: time.Hour, failures: 1, action: "restart"}
+	sup.registerApp("hung", appLaunch{
+		path:        "/bin/sh",
+		args:        []string{"-c", "sleep 30"},
+		stopTimeout: time.Second,
+		liveness:    probe,
+	})
+
+	if err := sup.start("hung"); err != nil {
+		t.Fatalf("start() error: %v", err)
+	}
+
+	sup.mu.Lock()
+	prism := sup.prismList[0]
+	sup.mu.Unlock()
+	reapPID(sup, prism.pid)
+
+	sup.reportProbe(prism, *probe, errors.New("no output for 1m0s"))
+	<-prism.exit.done
+
+	var restarted prismInstance
+	waitFor(t, func() bool {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		idx := sup.findPrism("hung")
+		if idx == -1 {
+			return false
+		}
+		restarted = sup.prismList[idx]
+		return restarted.pid != prism.pid
+	})
+	t.Cleanup(func() {
+		unix.Kill(-restarted.pid, unix.SIGKILL)
+		var status unix.WaitStatus
+		unix.Wait4(restarted.pid, &status, 0, nil)
+	})
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	if !sup.hasForeground() || sup.prismList[0].pid != restarted.pid {
+		t.Error("the restarted prism should be in the foreground again")
+	}
+	if state, _ := sup.prismHealthState(restarted); state != "healthy" {
+		t.Errorf("restarted prism health = %q, want healthy", state)
+	}
+	if records := sup.exitHistory("hung"); len(records) != 1 || records[0].pid != prism.pid {
+		t.Errorf("history = %+v, want the exit of the hung instance", records)
+	}
+	if sup.shuttingDown {
+		t.Error("restarting the only prism should not shut the panel down")
+	}
+}
+
+// reapPID stands in for the SIGCHLD handler for a child started by the
+// supervisor
+func reapPID(sup *supervisor, pid int) {
+	go func() {
+		var status unix.WaitStatus
+		if _, err := unix.Wait4(pid, &status, 0, nil); err == nil {
+			sup.handleChildExit(pid, status)
+		}
+	}()
+}
@@ -59,14 +59,58 @@ func main() {
 		os.Exit(1)
 	}
 
+	if os.Args[1] == "attach" {
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "usage: prismctl attach <instance>")
+			os.Exit(1)
+		}
+		if err := runAttach(os.Args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "prismctl attach: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	instanceName := os.Args[1]
-	log.Printf("prismctl starting (instance: %s)", instanceName)
+	headless := false
+	for _, arg := range os.Args[2:] {
+		if arg == "--headless" {
+			headless = true
+		}
+	}
+	log.Printf("prismctl starting (instance: %s, headless: %v)", instanceName, headless)
 
-	termState, err := newTerminalState()
-	if err != nil {
-		log.Fatalf("Failed to initialize terminal state: %v", err)
+	if err := os.MkdirAll(paths.RuntimeDir(), 0700); err != nil {
+		log.Fatalf("Failed to create runtime directory: %v", err)
+	}
+
+	// The kitty panel's terminal, unless headless
+	var panelTerm RealTerminal
+	if !headless {
+		termState, err := newTerminalState()
+		if err != nil {
+			log.Fatalf("Failed to initialize terminal state: %v", err)
+		}
+		log.Printf("Terminal state saved")
+
+		// The panel's focus is followed for the prisms' lifecycle
+		if panelTerm, err = newFocusReportingTerminal(termState); err != nil {
+			log.Printf("Warning: %v", err)
+			panelTerm = termState
+		}
+	}
+
+	term := panelTerm
+	attachTerm, err := listenAttach(paths.PrismAttachSocket(instanceName), panelTerm)
+	switch {
+	case err == nil:
+		term = attachTerm
+		log.Printf("Attach socket listening on: %s", paths.PrismAttachSocket(instanceName))
+	case headless:
+		log.Fatalf("Failed to create attach socket: %v", err)
+	default:
+		log.Printf("Warning: attaching disabled: %v", err)
 	}
-	log.Printf("Terminal state saved")
 
 	statePath := paths.PrismState(instanceName)
 	stateMgr, err := newStateManager(statePath, instanceName)
@@ -80,9 +124,15 @@ func main() {
 	defer notifyMgr.Close()
 	log.Printf("Notification manager started")
 
-	sup := newSupervisor(termState, stateMgr, notifyMgr)
+	sup := newSupervisor(term, stateMgr, notifyMgr)
+	if attachTerm != nil {
+		attachTerm.setOnChange(sup.redraw)
+	}
 
 	sigHandler := newSignalHandler(sup)
+	// Without a terminal there is nothing to hang up; SIGHUP would only
+	// come from whatever shell started prismctl
+	sigHandler.ignoreHangup = headless
 	defer sigHandler.stop()
 
 	rpcServer, err := startRPCServer(instanceName, sup, stateMgr)
@@ -91,6 +141,14 @@ func main() {
 	}
 	defer stopRPCServer(rpcServer)
 
+	childServer, err := startChildServer(instanceName, sup)
+	if err != nil {
+		log.Fatalf("Failed to start child RPC server: %v", err)
+	}
+	defer stopRPCServer(childServer)
+
+	go sup.sampleUsageEvery(usageSampleInterval)
+
 	log.Printf("prismctl running (PID %d), awaiting configuration via RPC", os.Getpid())
 	sigHandler.run()
 
@@ -1,6 +1,14 @@
-// mirror.go implements bidirectional I/O mirroring between the real PTY and
-// child PTYs. The mirror reflects user input to the foreground prism and
-// prism output back to the terminal.
+// mirror.go implements input mirroring between the real PTY and child PTYs.
+// The mirror reflects user input to the foreground prism. Prism output flows
+// the other way through each prism's output pump (see output.go), which keeps
+// draining background prisms too.
+//
+// A single reader owns the real PTY for the lifetime of the mirror; swapping
+// the foreground retargets it instead of starting a new reader, so no input
+// is lost to a stale goroutine. When a prefix key is configured the reader
+// intercepts it and dispatches the key that follows (see keys.go). Focus
+// reports from the real terminal are taken as the panel's focus and not
+// forwarded (see lifecycle.go).
 
 package main
 
@@ -12,29 +20,28 @@ import (
 	"os"
 	"strings"
 	"sync"
-	"time"
 )
 
 type mirrorState struct {
-	ctx      context.Context
-	cancel   context.CancelFunc
-	wg       sync.WaitGroup
-	active   bool
-	childPTY *os.File
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	active bool
+
+	mu       sync.Mutex
+	childPTY *os.File     // input target, nil drops input
+	keys     *prefixKeys  // nil when no prefix key is configured
+	capture  func([]byte) // receives all input while set, e.g. the prism picker
+	onFocus  func(bool)   // receives the real terminal's focus reports, nil passes them on
 }
 
-// activateMirror launches bidirectional copy between Real PTY and child PTY
-// Real PTY (stdin/stdout) ↔ child PTY master (foreground prism)
-func activateMirror(ctx context.Context, realPTY *os.File, childPTY *os.File) (*mirrorState, error) {
+// activateMirror launches the input copy from Real PTY to child PTY
+// Real terminal (stdin, or the attached client) → child PTY master (foreground prism)
+func activateMirror(ctx context.Context, realPTY io.Reader, childPTY *os.File) (*mirrorState, error) {
 	if realPTY == nil || childPTY == nil {
 		return nil, fmt.Errorf("cannot activate mirror with nil PTY")
 	}
 
-	// Clear any previous read deadline (from deactivateMirror)
-	if err := childPTY.SetReadDeadline(time.Time{}); err != nil {
-		log.Printf("Warning: failed to clear read deadline: %v", err)
-	}
-
 	mirrorCtx, cancel := context.WithCancel(ctx)
 
 	state := &mirrorState{
@@ -44,37 +51,121 @@ func activateMirror(ctx context.Context, realPTY *os.File, childPTY *os.File) (*
 		childPTY: childPTY,
 	}
 
-	state.wg.Add(2)
+	state.wg.Add(1)
 
 	// Real PTY → child PTY (user input to prism)
 	go func() {
 		defer state.wg.Done()
-		if _, err := io.Copy(childPTY, realPTY); err != nil {
-			// These errors are normal during shutdown/swap:
-			// - EOF: clean close
-			// - ErrClosedPipe: pipe closed
-			// - "input/output error": PTY closed (ENXIO/EIO)
-			if err != io.EOF && err != io.ErrClosedPipe && !isExpectedPTYError(err) {
-				log.Printf("Mirror (real→child) error: %v", err)
-			}
-		}
-	}()
 
-	// child PTY → Real PTY (prism output to terminal)
-	go func() {
-		defer state.wg.Done()
-		if _, err := io.Copy(os.Stdout, childPTY); err != nil {
-			if err != io.EOF && err != io.ErrClosedPipe && !isExpectedPTYError(err) {
-				log.Printf("Mirror (child→real) error: %v", err)
+		buf := make([]byte, 4096)
+		for {
+			n, err := realPTY.Read(buf)
+			if state.ctx.Err() != nil {
+				return
+			}
+			if n > 0 {
+				state.route(buf[:n])
+			}
+			if err != nil {
+				// These errors are normal during shutdown/swap:
+				// - EOF: clean close
+				// - ErrClosedPipe: pipe closed
+				// - "input/output error": PTY closed (ENXIO/EIO)
+				if err != io.EOF && err != io.ErrClosedPipe && !isExpectedPTYError(err) {
+					log.Printf("Mirror (real→child) error: %v", err)
+				}
+				return
 			}
 		}
 	}()
 
-	log.Printf("Mirror activated: Real PTY ↔ child PTY (fd %d)", childPTY.Fd())
+	log.Printf("Mirror activated: Real PTY → child PTY (fd %d)", childPTY.Fd())
 
 	return state, nil
 }
 
+// route delivers one read from the real PTY. Key commands run synchronously
+// so that input following a command (e.g. opening the picker) is routed
+// according to its outcome.
+func (state *mirrorState) route(data []byte) {
+	state.mu.Lock()
+	onFocus := state.onFocus
+	state.mu.Unlock()
+
+	if onFocus != nil {
+		var focus int
+		if data, focus = stripFocusEvents(data); focus != 0 {
+			onFocus(focus > 0)
+		}
+	}
+
+	for len(data) > 0 {
+		state.mu.Lock()
+		child, keys, capture := state.childPTY, state.keys, state.capture
+		state.mu.Unlock()
+
+		if capture != nil {
+			capture(data)
+			return
+		}
+
+		if keys == nil {
+			state.write(child, data)
+			return
+		}
+
+		forward, cmd, found, rest := keys.scan(data)
+		state.write(child, forward)
+		if found {
+			keys.onCommand(cmd)
+		}
+		data = rest
+	}
+}
+
+func (state *mirrorState) write(child *os.File, data []byte) {
+	if child == nil || len(data) == 0 {
+		return
+	}
+
+	if _, err := child.Write(data); err != nil && !isExpectedPTYError(err) {
+		log.Printf("Mirror (real→child) error: %v", err)
+	}
+}
+
+// retarget sends further input to childPTY; nil drops input
+func (state *mirrorState) retarget(childPTY *os.File) {
+	if state == nil {
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.childPTY = childPTY
+}
+
+func (state *mirrorState) setKeys(keys *prefixKeys) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.keys = keys
+}
+
+func (state *mirrorState) setFocusHandler(fn func(bool)) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.onFocus = fn
+}
+
+func (state *mirrorState) setCapture(fn func([]byte)) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.capture = fn
+}
+
 func deactivateMirror(state *mirrorState) {
 	if state == nil || !state.active {
 		return
@@ -82,11 +173,6 @@ func deactivateMirror(state *mirrorState) {
 
 	state.cancel()
 
-	// Force child PTY io.Copy to return by setting deadline
-	if state.childPTY != nil {
-		state.childPTY.SetReadDeadline(time.Unix(0, 0))
-	}
-
 	// Don't wait for goroutines - the stdin reader will be blocked until next input
 	state.active = false
 }
@@ -98,5 +184,5 @@ func isExpectedPTYError(err error) bool {
 	errStr := err.Error()
 	return strings.Contains(errStr, "input/output error") || // EIO
 		strings.Contains(errStr, "no such device") || // ENXIO
-		strings.Contains(errStr, "i/o timeout") // Deadline set during deactivateMirror
+		strings.Contains(errStr, "i/o timeout") // Read deadline expired
 }
@@ -322,3 +322,62 @@ func TestMirrorState_StructFields(t *testing.T) {
 	state.wg.Add(1)
 	state.wg.Done()
 }
+
+func TestMirror_RetargetAndPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	realR, realW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create real pipe: %v", err)
+	}
+	defer realW.Close()
+
+	firstR, firstW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create child pipe: %v", err)
+	}
+	defer firstR.Close()
+
+	secondR, secondW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create child pipe: %v", err)
+	}
+	defer secondR.Close()
+
+	state, err := activateMirror(ctx, realR, firstW)
+	if err != nil {
+		t.Fatalf("activateMirror() unexpected error: %v", err)
+	}
+	defer deactivateMirror(state)
+
+	commands := make(chan keyCommand, 1)
+	state.setKeys(newPrefixKeys(0x01, func(cmd keyCommand) {
+		state.retarget(secondW)
+		commands <- cmd
+	}))
+
+	realW.Write([]byte("one\x01ntwo"))
+
+	select {
+	case cmd := <-commands:
+		if cmd.action != keyNext {
+			t.Errorf("command = %+v, want next", cmd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("prefix command was not dispatched")
+	}
+
+	for _, tc := range []struct {
+		r    *os.File
+		want string
+	}{{firstR, "one"}, {secondR, "two"}} {
+		buf := make([]byte, len(tc.want))
+		tc.r.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := io.ReadFull(tc.r, buf); err != nil {
+			t.Fatalf("failed to read from child PTY: %v", err)
+		}
+		if string(buf) != tc.want {
+			t.Errorf("child read %q, want %q", buf, tc.want)
+		}
+	}
+}
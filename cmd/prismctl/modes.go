@@ -0,0 +1,191 @@
+// modes.go moves the real terminal between the mode states of the screens
+// shown on it. Every prism's vtScreen tracks the modes the prism has set, so
+// when the foreground changes prismctl knows both the modes the terminal is
+// in and the modes the next prism expects, and emits only the sequences that
+// differ: a prism that left mouse tracking on loses it, and a prism that had
+// bracketed paste or kitty keyboard flags on gets them back.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyboardStackSize bounds the kitty keyboard flag stack; like kitty, the
+// oldest entry is dropped when a push overflows it
+const keyboardStackSize = 8
+
+// keyboardStack is one screen's kitty keyboard protocol flag stack. The
+// zero value is the terminal's default: nothing pushed and no flags.
+type keyboardStack struct {
+	base    uint8 // flags while nothing is pushed
+	entries [keyboardStackSize]uint8
+	depth   int
+}
+
+// flags returns the flags in effect
+func (k *keyboardStack) flags() uint8 {
+	if k.depth == 0 {
+		return k.base
+	}
+	return k.entries[k.depth-1]
+}
+
+func (k *keyboardStack) push(flags int) {
+	if k.depth == keyboardStackSize {
+		copy(k.entries[:], k.entries[1:])
+		k.depth--
+	}
+	k.entries[k.depth] = uint8(flags)
+	k.depth++
+}
+
+// pop removes n entries; emptying the stack resets every flag
+func (k *keyboardStack) pop(n int) {
+	if n >= k.depth {
+		*k = keyboardStack{}
+		return
+	}
+	for i := 0; i < n; i++ {
+		k.depth--
+		k.entries[k.depth] = 0
+	}
+}
+
+// set changes the flags in effect: mode 1 replaces them, 2 sets the given
+// bits and 3 clears them
+func (k *keyboardStack) set(flags, mode int) {
+	current := &k.base
+	if k.depth > 0 {
+		current = &k.entries[k.depth-1]
+	}
+
+	switch mode {
+	case 1:
+		*current = uint8(flags)
+	case 2:
+		*current |= uint8(flags)
+	case 3:
+		*current &^= uint8(flags)
+	}
+}
+
+// sequence returns the sequences that build this stack on a terminal whose
+// stack is empty
+func (k keyboardStack) sequence() string {
+	var b strings.Builder
+	if k.base != 0 {
+		fmt.Fprintf(&b, "\x1b[=%d;1u", k.base)
+	}
+	for _, flags := range k.entries[:k.depth] {
+		fmt.Fprintf(&b, "\x1b[>%du", flags)
+	}
+	return b.String()
+}
+
+// transition returns the sequences that turn stack from into k
+func (k keyboardStack) transition(from keyboardStack) string {
+	if k == from {
+		return ""
+	}
+
+	var b strings.Builder
+	// Popping every entry empties the stack and resets the base flags
+	switch {
+	case from.depth > 0:
+		fmt.Fprintf(&b, "\x1b[<%du", from.depth)
+	case from.base != 0:
+		b.WriteString("\x1b[=0;1u")
+	}
+	b.WriteString(k.sequence())
+	return b.String()
+}
+
+// overlayModes are the modes prismctl's own screens, the idle placeholder
+// and the picker, are drawn in
+var overlayModes = vtModes{cursorHidden: true}
+
+// modeTransition returns the sequences that move a terminal in mode state
+// from to mode state to, leaving out every mode that is already right
+func modeTransition(from, to vtModes) string {
+	var b strings.Builder
+
+	decset := func(mode int, on bool) {
+		if on {
+			fmt.Fprintf(&b, "\x1b[?%dh", mode)
+		} else {
+			fmt.Fprintf(&b, "\x1b[?%dl", mode)
+		}
+	}
+
+	// Each screen has its own keyboard stack, which can only be changed
+	// while that screen is in use
+	alt := from.altScreen
+	for _, screen := range []bool{alt, !alt} {
+		fromStack, toStack := from.keyboard, to.keyboard
+		if screen {
+			fromStack, toStack = from.altKeyboard, to.altKeyboard
+		}
+		if fromStack == toStack {
+			continue
+		}
+		if screen != alt {
+			decset(1049, screen)
+			alt = screen
+		}
+		b.WriteString(toStack.transition(fromStack))
+	}
+	if alt != to.altScreen {
+		decset(1049, to.altScreen)
+	}
+
+	if from.appCursorKeys != to.appCursorKeys {
+		decset(1, to.appCursorKeys)
+	}
+	if from.originMode != to.originMode {
+		decset(6, to.originMode)
+	}
+	if from.autowrapOff != to.autowrapOff {
+		decset(7, !to.autowrapOff)
+	}
+	if from.cursorHidden != to.cursorHidden {
+		decset(25, !to.cursorHidden)
+	}
+	if from.focusEvents != to.focusEvents {
+		decset(1004, to.focusEvents)
+	}
+	if from.bracketedPaste != to.bracketedPaste {
+		decset(2004, to.bracketedPaste)
+	}
+	if from.mouseTracking != to.mouseTracking {
+		if to.mouseTracking != 0 {
+			decset(to.mouseTracking, true)
+		} else {
+			decset(from.mouseTracking, false)
+		}
+	}
+	if from.mouseSGR != to.mouseSGR {
+		decset(1006, to.mouseSGR)
+	}
+
+	if from.insertMode != to.insertMode {
+		if to.insertMode {
+			b.WriteString("\x1b[4h")
+		} else {
+			b.WriteString("\x1b[4l")
+		}
+	}
+	if from.appKeypad != to.appKeypad {
+		if to.appKeypad {
+			b.WriteString("\x1b=")
+		} else {
+			b.WriteString("\x1b>")
+		}
+	}
+	if from.cursorShape != to.cursorShape {
+		fmt.Fprintf(&b, "\x1b[%d q", to.cursorShape)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVTScreen_KeyboardProtocolAndCursorShape(t *testing.T) {
+	s := newVTScreen(10, 2)
+	s.Write([]byte("\x1b[5 q\x1b[>1u\x1b[>3u\x1b[=4;2u"))
+
+	if s.modes.cursorShape != 5 {
+		t.Errorf("cursorShape = %d, want 5", s.modes.cursorShape)
+	}
+	if got := s.modes.keyboard.flags(); got != 7 {
+		t.Errorf("keyboard flags = %d, want 7", got)
+	}
+
+	// The alternate screen has its own stack
+	s.Write([]byte("\x1b[?1049h\x1b[>31u"))
+	if got := s.modes.altKeyboard.flags(); got != 31 {
+		t.Errorf("alt keyboard flags = %d, want 31", got)
+	}
+	s.Write([]byte("\x1b[?1049l\x1b[<u"))
+	if got := s.modes.keyboard.flags(); got != 1 {
+		t.Errorf("keyboard flags after pop = %d, want 1", got)
+	}
+
+	// Popping past the bottom empties the stack
+	s.Write([]byte("\x1b[<5u"))
+	if s.modes.keyboard != (keyboardStack{}) {
+		t.Errorf("keyboard = %+v, want an empty stack", s.modes.keyboard)
+	}
+}
+
+func TestModeTransition(t *testing.T) {
+	states := map[string]string{
+		"default": "",
+		"mouse":   "\x1b[?1002h\x1b[?1006h\x1b[?25l\x1b[2 q",
+		"editor":  "\x1b[?1049h\x1b[?2004h\x1b[>1u\x1b[>5u\x1b[=8;2u\x1b[6 q",
+		"shell":   "\x1b[>3u\x1b[?1h\x1b=\x1b[?1004h\x1b[?1049h\x1b[>1u",
+		"wrapoff": "\x1b[?7l\x1b[4h\x1b[?1000h",
+	}
+
+	screen := func(setup string) *vtScreen {
+		s := newVTScreen(10, 2)
+		s.Write([]byte(setup))
+		return s
+	}
+
+	for fromName, fromSetup := range states {
+		for toName, toSetup := range states {
+			from, to := screen(fromSetup), screen(toSetup)
+
+			// A terminal left in from's modes ends up in exactly to's
+			real := screen(fromSetup)
+			seq := modeTransition(from.modes, to.modes)
+			real.Write([]byte(seq))
+			if real.modes != to.modes {
+				t.Errorf("%s → %s: modes = %+v, want %+v", fromName, toName, real.modes, to.modes)
+			}
+
+			if fromName == toName && seq != "" {
+				t.Errorf("%s → %s = %q, want nothing", fromName, toName, seq)
+			}
+		}
+	}
+}
+
+func TestModeTransition_OnlyChangesDifferences(t *testing.T) {
+	from := vtModes{bracketedPaste: true, mouseTracking: 1000, mouseSGR: true}
+	to := vtModes{bracketedPaste: true, focusEvents: true}
+
+	got := modeTransition(from, to)
+	want := "\x1b[?1004h\x1b[?1000l\x1b[?1006l"
+	if got != want {
+		t.Errorf("modeTransition() = %q, want %q", got, want)
+	}
+}
+
+// Bringing a prism back must not blanket-reset modes it still has on
+func TestPrismOutput_AttachKeepsModes(t *testing.T) {
+	s := newVTScreen(10, 2)
+	s.Write([]byte("\x1b[?2004h\x1b[>1u"))
+
+	repaint := string(s.renderFrom(s.modes))
+	for _, unwanted := range []string{"\x1b[?2004l", "\x1b[?2004h", "\x1b[<", "\x1b[>"} {
+		if strings.Contains(repaint, unwanted) {
+			t.Errorf("repaint contains %q: %q", unwanted, repaint)
+		}
+	}
+
+	repaint = string(s.renderFrom(overlayModes))
+	if !strings.Contains(repaint, "\x1b[>1u") {
+		t.Errorf("repaint from the overlay does not restore keyboard flags: %q", repaint)
+	}
+	if !strings.Contains(repaint, "\x1b[?2004h") {
+		t.Errorf("repaint from the overlay does not restore bracketed paste: %q", repaint)
+	}
+}
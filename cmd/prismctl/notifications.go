@@ -128,10 +128,10 @@ func (nm *NotificationManager) OnPrismStarted(name string, pid int) {
 	})
 }
 
-func (nm *NotificationManager) OnPrismStopped(name string, exitCode int) {
-	log.Printf("Notification: prism stopped %s (exit=%d)", name, exitCode)
+func (nm *NotificationManager) OnPrismStopped(name string, exitCode int, requested bool) {
+	log.Printf("Notification: prism stopped %s (exit=%d, requested=%v)", name, exitCode, requested)
 	nm.sendNotification(func(ctx context.Context, c *rpc.ShinedClient) error {
-		return c.NotifyPrismStopped(ctx, nm.instance, name, exitCode)
+		return c.NotifyPrismStopped(ctx, nm.instance, name, exitCode, requested)
 	})
 }
 
@@ -142,6 +142,13 @@ func (nm *NotificationManager) OnPrismCrashed(name string, exitCode, signal int)
 	})
 }
 
+func (nm *NotificationManager) OnPrismHealth(name string, healthy bool, reason string) {
+	log.Printf("Notification: prism health %s (healthy=%v)", name, healthy)
+	nm.sendNotification(func(ctx context.Context, c *rpc.ShinedClient) error {
+		return c.NotifyPrismHealth(ctx, nm.instance, name, healthy, reason)
+	})
+}
+
 func (nm *NotificationManager) OnForegroundChanged(from, to string) {
 	log.Printf("Notification: foreground changed %s → %s", from, to)
 	nm.sendNotification(func(ctx context.Context, c *rpc.ShinedClient) error {
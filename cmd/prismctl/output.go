@@ -0,0 +1,231 @@
+// output.go drains each prism's PTY master for the whole lifetime of the
+// prism. Output always feeds the prism's headless screen model; while the
+// prism is foreground it is also forwarded to the real PTY. Swapping the
+// foreground is therefore a matter of detaching one prism and attaching
+// another, which repaints the saved screen before going live.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type prismOutput struct {
+	mu       sync.Mutex
+	screen   *vtScreen
+	history  *scrollback   // raw output, for prism/capture
+	recorder *castRecorder // asciicast recording, nil when not recording
+	live     io.Writer     // real PTY while foreground, nil while background
+	notify   func()        // called after each chunk while shown in a split layout
+	done     chan struct{}
+
+	lastOutput time.Time // for the output liveness probe
+}
+
+// startPrismOutput starts draining master into a screen of the given size
+func startPrismOutput(master *os.File, cols, rows int) *prismOutput {
+	o := &prismOutput{
+		screen:  newVTScreen(cols, rows),
+		history: newScrollback(scrollbackSize),
+		done:    make(chan struct{}),
+
+		lastOutput: time.Now(),
+	}
+
+	go o.run(master)
+
+	return o
+}
+
+func (o *prismOutput) run(master *os.File) {
+	defer close(o.done)
+	defer o.stopRecording()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := master.Read(buf)
+		if n > 0 {
+			o.mu.Lock()
+			o.lastOutput = time.Now()
+			o.screen.Write(buf[:n])
+			o.history.Write(buf[:n])
+			if o.recorder != nil {
+				if rerr := o.recorder.output(buf[:n]); rerr != nil {
+					log.Printf("Output: recording stopped: %v", rerr)
+					o.recorder.close()
+					o.recorder = nil
+				}
+			}
+			if o.live != nil {
+				if _, werr := o.live.Write(buf[:n]); werr != nil {
+					log.Printf("Output: failed to write to real PTY: %v", werr)
+				}
+			}
+			if o.notify != nil {
+				o.notify()
+			}
+			o.mu.Unlock()
+		}
+		if err != nil {
+			// EOF/EIO when the child exits, ErrClosed when the PTY is closed
+			if err != io.EOF && !errors.Is(err, os.ErrClosed) && !isExpectedPTYError(err) {
+				log.Printf("Output: read error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// attach repaints the saved screen onto w and then forwards live output to
+// it. Both happen under the same lock, so no output is lost or duplicated
+// between the repaint and the first live write. from is the mode state w is
+// in; attaching again while live repaints every mode instead, since it is
+// how newly attached clients get the screen.
+func (o *prismOutput) attach(w io.Writer, from vtModes) error {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	repaint := o.screen.renderFrom(from)
+	if o.live != nil {
+		repaint = o.screen.render()
+	}
+
+	o.live = w
+	if _, err := w.Write(repaint); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// detach stops forwarding output; the screen model keeps being updated. If
+// output was being forwarded, the modes the prism left the real terminal in
+// are returned.
+func (o *prismOutput) detach() (vtModes, bool) {
+	if o == nil {
+		return vtModes{}, false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	wasLive := o.live != nil
+	o.live = nil
+	return o.screen.modes, wasLive
+}
+
+// setNotify installs fn to be called whenever new output has been parsed.
+// fn runs with the output lock held and must not block.
+func (o *prismOutput) setNotify(fn func()) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.notify = fn
+}
+
+// appCursorKeys reports whether the prism has enabled application cursor keys
+func (o *prismOutput) appCursorKeys() bool {
+	if o == nil {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.screen.modes.appCursorKeys
+}
+
+// focusEvents reports whether the prism has enabled focus reporting
+func (o *prismOutput) focusEvents() bool {
+	if o == nil {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.screen.modes.focusEvents
+}
+
+func (o *prismOutput) resize(cols, rows int) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.screen.resize(cols, rows)
+
+	if o.recorder != nil {
+		if err := o.recorder.resize(cols, rows); err != nil {
+			log.Printf("Output: failed to record resize: %v", err)
+		}
+	}
+}
+
+// startRecording starts an asciicast recording at path, beginning with the
+// current screen, and returns the path it records to, which has a suffix if
+// path exists. Recording again while already recording is a no-op.
+func (o *prismOutput) startRecording(path, title string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.recorder != nil {
+		return o.recorder.path, nil
+	}
+
+	recorder, err := newCastRecorder(path, title, o.screen.cols, o.screen.rows)
+	if err != nil {
+		return "", err
+	}
+	if err := recorder.output(o.screen.render()); err != nil {
+		recorder.close()
+		return "", err
+	}
+
+	o.recorder = recorder
+	return recorder.path, nil
+}
+
+// stopRecording finishes the recording, returning its path
+func (o *prismOutput) stopRecording() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.recorder == nil {
+		return ""
+	}
+
+	path := o.recorder.path
+	if err := o.recorder.close(); err != nil {
+		log.Printf("Output: failed to close recording %s: %v", path, err)
+	}
+	o.recorder = nil
+
+	return path
+}
+
+// recording returns the current recording path, empty when not recording
+func (o *prismOutput) recording() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.recorder == nil {
+		return ""
+	}
+	return o.recorder.path
+}
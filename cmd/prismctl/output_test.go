@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func screenLine(o *prismOutput, y int) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.screen.lineText(y)
+}
+
+func TestPrismOutput_FeedsScreenWhileDetached(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer w.Close()
+
+	out := startPrismOutput(r, 20, 2)
+
+	w.Write([]byte("background"))
+	waitFor(t, func() bool { return screenLine(out, 0) == "background" })
+
+	r.Close()
+	select {
+	case <-out.done:
+	case <-time.After(time.Second):
+		t.Error("output pump did not stop after PTY close")
+	}
+}
+
+func TestPrismOutput_AttachRepaintsThenGoesLive(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	out := startPrismOutput(r, 20, 2)
+
+	w.Write([]byte("saved"))
+	waitFor(t, func() bool { return screenLine(out, 0) == "saved" })
+
+	var real syncBuffer
+	if err := out.attach(&real, vtModes{}); err != nil {
+		t.Fatalf("attach() error: %v", err)
+	}
+	if !strings.Contains(real.String(), "saved") {
+		t.Errorf("attach() did not repaint saved screen, got %q", real.String())
+	}
+
+	w.Write([]byte("-live"))
+	waitFor(t, func() bool { return strings.HasSuffix(real.String(), "-live") })
+
+	out.detach()
+	w.Write([]byte("-hidden"))
+	waitFor(t, func() bool { return screenLine(out, 0) == "saved-live-hidden" })
+
+	if strings.Contains(real.String(), "hidden") {
+		t.Error("detached output should not reach the real PTY")
+	}
+}
+
+func TestPrismOutput_NilSafe(t *testing.T) {
+	var out *prismOutput
+
+	if err := out.attach(&bytes.Buffer{}, vtModes{}); err != nil {
+		t.Errorf("attach() on nil output = %v, want nil", err)
+	}
+	out.detach()
+	out.resize(10, 10)
+}
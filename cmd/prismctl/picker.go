@@ -0,0 +1,180 @@
+// picker.go implements the prism picker opened with prefix+w. While it is
+// open it takes over the panel and all input; the foreground prism keeps
+// running and is repainted when the picker closes.
+//
+//	1-9              choose a prism by number
+//	j, k, ↓, ↑, ←, →  move the selection
+//	Enter            choose the selected prism
+//	q, Esc, C-c      close without switching
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+)
+
+type prismPicker struct {
+	names    []string // in start order
+	current  string   // foreground prism when the picker opened
+	selected int
+}
+
+// handle applies input to the picker. done is true once the picker should
+// close; choice is the chosen prism, or empty when cancelled.
+func (p *prismPicker) handle(data []byte) (choice string, done bool) {
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+
+		if b == 0x1b {
+			if i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') {
+				switch data[i+2] {
+				case 'A', 'D':
+					p.move(-1)
+				case 'B', 'C':
+					p.move(1)
+				}
+				i += 2
+				continue
+			}
+			return "", true
+		}
+
+		switch {
+		case b >= '1' && b <= '9':
+			if idx := int(b - '1'); idx < len(p.names) {
+				return p.names[idx], true
+			}
+		case b == 'j':
+			p.move(1)
+		case b == 'k':
+			p.move(-1)
+		case b == '\r' || b == '\n':
+			return p.names[p.selected], true
+		case b == 'q' || b == 0x03:
+			return "", true
+		}
+	}
+
+	return "", false
+}
+
+func (p *prismPicker) move(delta int) {
+	p.selected = (p.selected + delta + len(p.names)) % len(p.names)
+}
+
+// render draws the picker on a cols×rows screen: one row per prism when they
+// fit, otherwise a single row (e.g. in a bar panel)
+func (p *prismPicker) render(cols, rows int) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x1b[0m\x1b[?25l\x1b[?7l\x1b[H\x1b[2J")
+
+	vertical := rows >= len(p.names)
+	for i, name := range p.names {
+		marker := " "
+		if name == p.current {
+			marker = "*"
+		}
+
+		if vertical {
+			fmt.Fprintf(&b, "\x1b[%d;1H", i+1)
+		} else if i > 0 {
+			b.WriteString("  ")
+		}
+
+		if i == p.selected {
+			b.WriteString("\x1b[7m")
+		}
+		fmt.Fprintf(&b, "%s%d %s", marker, i+1, name)
+		if i == p.selected {
+			b.WriteString("\x1b[27m")
+		}
+	}
+
+	return b.Bytes()
+}
+
+// openPicker shows the prism picker and routes all input to it
+func (s *supervisor) openPicker() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.prismList) == 0 || s.mirror == nil || s.picker != nil {
+		return
+	}
+
+	names := s.prismNamesByStart()
+	current := ""
+	if s.hasForeground() {
+		current = s.prismList[0].name
+	}
+	s.picker = &prismPicker{names: names, current: current}
+	for i, name := range names {
+		if name == current {
+			s.picker.selected = i
+		}
+	}
+
+	s.detachOutput(s.prismList[0])
+	if modes, ok := s.layout.hold(); ok {
+		s.shownModes = modes
+	}
+	s.drawPicker()
+
+	s.mirror.setCapture(s.pickerInput)
+}
+
+// drawPicker repaints the open picker
+// Assumes caller holds s.mu lock
+func (s *supervisor) drawPicker() {
+	if s.picker == nil {
+		return
+	}
+
+	winsize, err := s.term.Size()
+	if err != nil {
+		log.Printf("Warning: failed to get Real PTY size for picker: %v", err)
+		return
+	}
+	cols, rows := int(winsize.Col), int(winsize.Row)
+
+	frame := append(s.enterModes(overlayModes), s.picker.render(cols, rows)...)
+	if _, err := s.term.Write(frame); err != nil {
+		log.Printf("Warning: failed to draw picker: %v", err)
+	}
+}
+
+// pickerInput runs on the mirror goroutine without any lock held
+func (s *supervisor) pickerInput(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.picker == nil {
+		return
+	}
+
+	choice, done := s.picker.handle(data)
+	if !done {
+		s.drawPicker()
+		return
+	}
+
+	s.picker = nil
+	s.mirror.setCapture(nil)
+	s.layout.release(s.shownModes)
+
+	if len(s.prismList) == 0 {
+		return
+	}
+
+	if idx := s.findPrism(choice); idx > 0 || (idx == 0 && !s.hasForeground()) {
+		s.switchTo(idx)
+		return
+	}
+
+	// Cancelled or already foreground: repaint it (or the idle screen) over the picker
+	if err := s.activateMirrorToForeground(); err != nil {
+		log.Printf("Warning: failed to restore foreground after picker: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// process.go signals prisms. Prisms are started with Setsid, so each one
+// leads its own session and process group, whose ID is the prism's PID.
+// Signaling the group instead of the PID also reaches the helpers a prism
+// spawns (shell pipelines, hyprctl polls), so they are suspended, resumed
+// and stopped together with it.
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/sys/unix"
+)
+
+// signalGroup sends sig to the process group led by pid
+func signalGroup(pid int, sig unix.Signal) error {
+	// kill(-1) would signal every process we are allowed to
+	if pid <= 1 {
+		return fmt.Errorf("invalid process group %d", pid)
+	}
+	return unix.Kill(-pid, sig)
+}
+
+// signalPrism sends sig to a prism's process group. Suspension is managed
+// by prismctl, so SIGSTOP and SIGCONT are refused: prism/bg and prism/fg
+// move prisms between the background and the foreground.
+func (s *supervisor) signalPrism(prismName string, sig unix.Signal) (int, error) {
+	if sig == unix.SIGSTOP || sig == unix.SIGCONT {
+		return 0, fmt.Errorf("%s is managed by prismctl, use prism/bg or prism/fg", unix.SignalName(sig))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.findPrism(prismName)
+	if idx == -1 {
+		return 0, fmt.Errorf("prism not found: %s", prismName)
+	}
+	prism := s.prismList[idx]
+
+	log.Printf("Sending %s to prism %s (process group %d)", unix.SignalName(sig), prism.name, prism.pid)
+
+	if err := signalGroup(prism.pid, sig); err != nil {
+		return 0, fmt.Errorf("failed to send %s: %w", unix.SignalName(sig), err)
+	}
+
+	return prism.pid, nil
+}
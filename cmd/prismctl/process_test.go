@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+// readPID waits for a shell to write a PID to path
+func readPID(t *testing.T, path string) int {
+	t.Helper()
+	var pid int
+	waitFor(t, func() bool {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		return err == nil
+	})
+	return pid
+}
+
+// processGone reports whether pid has exited, even if nobody reaped it yet
+func processGone(pid int) bool {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return fields[0] == "Z"
+}
+
+func TestSupervisor_SignalsProcessGroup(t *testing.T) {
+	helperFile := filepath.Join(t.TempDir(), "helper.pid")
+
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+
+	script := fmt.Sprintf(`sleep 30 & echo $! > %s; while :; do sleep 0.02; done`, helperFile)
+	cmd, prism := startShell(t, "poller", script, unix.SIGTERM, time.Second)
+	prism.state = prismForeground
+	sup.prismList = append(sup.prismList, prism)
+	reapInto(sup, cmd)
+
+	helper := readPID(t, helperFile)
+	t.Cleanup(func() { unix.Kill(helper, unix.SIGKILL) })
+
+	// Suspending the prism suspends its helpers too
+	if _, err := sup.background("poller"); err != nil {
+		t.Fatalf("background() error: %v", err)
+	}
+	waitFor(t, func() bool { return processState(t, helper) == 'T' })
+
+	if err := sup.start("poller"); err != nil {
+		t.Fatalf("start() error: %v", err)
+	}
+	waitFor(t, func() bool { return processState(t, helper) != 'T' })
+
+	// Stopping the prism stops its helpers too
+	exit, err := sup.killPrism("poller")
+	if err != nil {
+		t.Fatalf("killPrism() error: %v", err)
+	}
+	<-exit.done
+	waitFor(t, func() bool { return processGone(helper) })
+}
+
+func TestPrismctlIPC_Signal(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+	marker := filepath.Join(t.TempDir(), "usr1")
+
+	sup := newSupervisor(nil, nil, nil)
+
+	script := fmt.Sprintf(`trap "echo usr1 > %s" USR1; while :; do sleep 0.02; done`, marker)
+	_, prism := startShell(t, "clock", script, unix.SIGTERM, time.Second)
+	sup.prismList = append(sup.prismList, prism)
+
+	srv := rpc.NewServer(sockPath, newRPCHandlers(sup, nil), nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	result, err := client.Signal(ctx, "clock", "usr1")
+	if err != nil {
+		t.Fatalf("Signal() error: %v", err)
+	}
+	if result.Signal != "SIGUSR1" || result.PID != prism.pid {
+		t.Errorf("Signal() = %+v, want SIGUSR1 to %d", result, prism.pid)
+	}
+	waitFor(t, func() bool {
+		_, err := os.Stat(marker)
+		return err == nil
+	})
+
+	for _, sig := range []string{"SIGSTOP", "cont", "SIGBOGUS", ""} {
+		if _, err := client.Signal(ctx, "clock", sig); err == nil {
+			t.Errorf("Signal(%q) should fail", sig)
+		}
+	}
+	if _, err := client.Signal(ctx, "missing", "SIGUSR1"); err == nil {
+		t.Error("Signal() of unknown prism should fail")
+	}
+}
@@ -17,20 +17,49 @@ func allocatePTY() (master, slave *os.File, err error) {
 }
 
 // syncTerminalSize copies terminal size from source FD to target FD
-// NOTE: will be important function for more complex multiplexing i.e. multi-app/split window
 func syncTerminalSize(sourceFd, targetFd int) error {
 	sourceWinsize, err := unix.IoctlGetWinsize(sourceFd, unix.TIOCGWINSZ)
 	if err != nil {
 		return fmt.Errorf("failed to get source terminal size: %w", err)
 	}
 
-	if err := unix.IoctlSetWinsize(targetFd, unix.TIOCSWINSZ, sourceWinsize); err != nil {
+	return setPTYSize(targetFd, sourceWinsize)
+}
+
+// setPTYSize sets the window size of the target FD
+func setPTYSize(targetFd int, winsize *unix.Winsize) error {
+	if err := unix.IoctlSetWinsize(targetFd, unix.TIOCSWINSZ, winsize); err != nil {
 		return fmt.Errorf("failed to set target terminal size: %w", err)
 	}
 
 	return nil
 }
 
+// syncPaneSize sets the target FD to a cols×rows pane of a terminal of the
+// source size, scaling the pixel size so cell dimensions stay the same
+func syncPaneSize(source *unix.Winsize, targetFd, cols, rows int) error {
+	paneWinsize := &unix.Winsize{
+		Row: uint16(rows),
+		Col: uint16(cols),
+	}
+	if source.Col > 0 && source.Row > 0 {
+		paneWinsize.Xpixel = uint16(int(source.Xpixel) * cols / int(source.Col))
+		paneWinsize.Ypixel = uint16(int(source.Ypixel) * rows / int(source.Row))
+	}
+
+	return setPTYSize(targetFd, paneWinsize)
+}
+
+// ptySize returns the window size of a PTY in cells
+func ptySize(fd int) (cols, rows int, err error) {
+	winsize, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get terminal size: %w", err)
+	}
+
+	return int(winsize.Col), int(winsize.Row), nil
+}
+
 func closePTY(master *os.File) error {
 	if master == nil {
 		return nil
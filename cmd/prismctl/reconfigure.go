@@ -0,0 +1,131 @@
+// reconfigure.go applies a new configuration to a running panel
+// (prism/reconfigure). The request is declarative: it carries the panel's
+// complete app set, as prism/configure does, and the supervisor works out
+// what changed. Added apps are launched in the background, instances of
+// removed apps are stopped, and instances of apps whose launch settings
+// changed (binary path, args, env, ...) are stopped and launched again with
+// the new ones. Apps that did not change are left alone.
+//
+// The layout cannot change while prisms are running, and in a split layout
+// neither can the panes; shined respawns the panel for that.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+)
+
+// layoutMatches reports whether the panel already has the given layout
+func (s *supervisor) layoutMatches(kind layoutKind, specs []paneSpec) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.layout == nil {
+		return kind == layoutSingle
+	}
+	return s.layout.matches(kind, specs)
+}
+
+// reconcileApps registers launches as the panel's apps, in place of the
+// current ones. It returns the apps that are new, the ones that are gone,
+// and the ones whose launch settings changed. Running instances of changed
+// apps are restarted; starting new apps and stopping old ones is left to
+// the caller, in that order.
+func (s *supervisor) reconcileApps(names []string, launches map[string]appLaunch) (added, removed, updated []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name := range s.apps {
+		if _, ok := launches[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, name := range names {
+		launch := launches[name]
+		current, ok := s.apps[name]
+		s.apps[name] = launch
+
+		switch {
+		case !ok:
+			added = append(added, name)
+		case !reflect.DeepEqual(current, launch):
+			updated = append(updated, name)
+			s.restartApp(name)
+		}
+	}
+
+	log.Printf("Reconfigured apps: added %v, removed %v, updated %v", added, removed, updated)
+
+	return added, removed, updated
+}
+
+// restartApp stops every instance of app, to be launched again with the
+// app's current settings once it has exited
+// Assumes caller holds s.mu lock
+func (s *supervisor) restartApp(app string) {
+	for _, prism := range s.prismList {
+		if prism.app != app {
+			continue
+		}
+
+		s.reloads[prism.name] = true
+		if err := s.stopLocked(prism); err != nil {
+			delete(s.reloads, prism.name)
+			log.Printf("Warning: failed to restart %s: %v", prism.name, err)
+		}
+	}
+}
+
+// stopApp unregisters app and stops its running instances, returning their
+// IDs
+func (s *supervisor) stopApp(app string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.apps, app)
+
+	var stopped []string
+	for i, prism := range s.prismList {
+		if prism.app != app {
+			continue
+		}
+
+		if err := s.stopLocked(prism); err != nil {
+			log.Printf("Warning: failed to stop %s: %v", prism.name, err)
+			continue
+		}
+		s.prismList[i].stopRequested = true
+		stopped = append(stopped, prism.name)
+	}
+
+	return stopped
+}
+
+// launchInBackground launches app without taking the foreground from the
+// prism that has it. With no prism in the foreground the app gets it.
+func (s *supervisor) launchInBackground(app string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	focused := ""
+	if s.hasForeground() {
+		focused = s.prismList[0].name
+	}
+
+	if err := s.launchAndForeground(app); err != nil {
+		return err
+	}
+
+	if focused != "" {
+		if err := s.resumeToForeground(s.findPrism(focused)); err != nil {
+			return fmt.Errorf("failed to refocus %s: %w", focused, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSupervisor_ReconcileApps(t *testing.T) {
+	term, err := listenAttach(filepath.Join(t.TempDir(), "attach.sock"), nil)
+	if err != nil {
+		t.Fatalf("listenAttach() error: %v", err)
+	}
+	defer term.Restore()
+
+	sup := newSupervisor(term, nil, nil)
+
+	sleeper := func(secs string) appLaunch {
+		return appLaunch{path: "/bin/sh", args: []string{"-c", "sleep " + secs}, stopTimeout: time.Second}
+	}
+	for _, name := range []string{"feed", "mail", "clock"} {
+		sup.registerApp(name, sleeper("30"))
+		if err := sup.start(name); err != nil {
+			t.Fatalf("start(%s) error: %v", name, err)
+		}
+	}
+
+	t.Cleanup(func() {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		for _, p := range sup.prismList {
+			unix.Kill(-p.pid, unix.SIGKILL)
+			var status unix.WaitStatus
+			unix.Wait4(p.pid, &status, 0, nil)
+		}
+	})
+
+	pids := make(map[string]int)
+	sup.mu.Lock()
+	for _, p := range sup.prismList {
+		pids[p.name] = p.pid
+	}
+	sup.mu.Unlock()
+	reapPID(sup, pids["feed"])
+	reapPID(sup, pids["mail"])
+
+	if !sup.layoutMatches(layoutSingle, nil) || sup.layoutMatches(layoutHSplit, nil) {
+		t.Error("layoutMatches() should match only the single layout")
+	}
+
+	// clock is unchanged, mail gets new args, news is added and feed removed
+	launches := map[string]appLaunch{
+		"clock": sleeper("30"),
+		"mail":  sleeper("31"),
+		"news":  sleeper("30"),
+	}
+	added, removed, updated := sup.reconcileApps([]string{"clock", "mail", "news"}, launches)
+	if !reflect.DeepEqual(added, []string{"news"}) || !reflect.DeepEqual(removed, []string{"feed"}) || !reflect.DeepEqual(updated, []string{"mail"}) {
+		t.Fatalf("reconcileApps() = %v, %v, %v, want [news], [feed], [mail]", added, removed, updated)
+	}
+
+	if err := sup.launchInBackground("news"); err != nil {
+		t.Fatalf("launchInBackground() error: %v", err)
+	}
+	if stopped := sup.stopApp("feed"); !reflect.DeepEqual(stopped, []string{"feed"}) {
+		t.Errorf("stopApp() = %v, want [feed]", stopped)
+	}
+
+	// mail is launched again with its new settings, feed is gone for good
+	waitFor(t, func() bool {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+		idx := sup.findPrism("mail")
+		return sup.findPrism("feed") == -1 && idx != -1 && sup.prismList[idx].pid != pids["mail"]
+	})
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	if sup.prismList[0].name != "clock" || sup.prismList[0].pid != pids["clock"] {
+		t.Errorf("foreground = %s (PID %d), want clock left alone", sup.prismList[0].name, sup.prismList[0].pid)
+	}
+	if sup.findPrism("news") == -1 {
+		t.Error("news was not started")
+	}
+	if _, ok := sup.apps["feed"]; ok {
+		t.Error("feed is still registered")
+	}
+	if sup.apps["mail"].args[1] != "sleep 31" {
+		t.Errorf("mail args = %q, want the new ones", sup.apps["mail"].args)
+	}
+}
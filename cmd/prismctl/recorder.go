@@ -0,0 +1,151 @@
+// recorder.go writes a prism's PTY output to an asciicast v2 file. The
+// recording starts with a repaint of the prism's current screen, so a
+// recording started mid-session plays back from what was on screen at the
+// time rather than from a blank terminal.
+//
+// See https://docs.asciinema.org/manual/asciicast/v2/ for the format.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/starbased-co/shine/pkg/paths"
+)
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+type castRecorder struct {
+	file    *os.File
+	path    string
+	start   time.Time
+	pending []byte // trailing bytes of an incomplete UTF-8 sequence
+}
+
+// recordingPath returns a new recording file path for prism. Recordings
+// started within the same second get a suffix, see createRecording.
+func recordingPath(prism string) string {
+	name := fmt.Sprintf("%s-%s.cast", prism, time.Now().Format("20060102-150405"))
+	return filepath.Join(paths.RecordingDir(), name)
+}
+
+func newCastRecorder(path, title string, cols, rows int) (*castRecorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	file, path, err := createRecording(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	r := &castRecorder{
+		file:  file,
+		path:  path,
+		start: time.Now(),
+	}
+
+	header := castHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": os.Getenv("TERM")},
+	}
+	if err := r.writeLine(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// createRecording creates the file at path, or at path with a "-2", "-3"
+// and so on suffix when it exists, so that an app crash-looping under
+// record = true keeps the recording of every crash. It returns the file
+// and its path.
+func createRecording(path string) (*os.File, string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	for n := 1; ; n++ {
+		candidate := path
+		if n > 1 {
+			candidate = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+
+		file, err := os.OpenFile(candidate, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return file, candidate, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, "", err
+		}
+	}
+}
+
+// output records data written by the prism. Bytes of a UTF-8 sequence split
+// across reads are held back until the sequence is complete.
+func (r *castRecorder) output(data []byte) error {
+	data = append(r.pending, data...)
+
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[cut:]...)
+
+	if cut == 0 {
+		return nil
+	}
+	return r.event("o", string(data[:cut]))
+}
+
+func (r *castRecorder) resize(cols, rows int) error {
+	return r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+func (r *castRecorder) event(kind, data string) error {
+	elapsed := time.Since(r.start).Seconds()
+	return r.writeLine([]any{elapsed, kind, data})
+}
+
+func (r *castRecorder) writeLine(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+func (r *castRecorder) close() error {
+	if len(r.pending) > 0 {
+		r.event("o", string(r.pending))
+		r.pending = nil
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readCast returns the header and events of an asciicast file
+func readCast(t *testing.T, path string) (castHeader, [][]any) {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open recording: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatal("recording has no header")
+	}
+
+	var header castHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("invalid header %q: %v", scanner.Text(), err)
+	}
+
+	var events [][]any
+	for scanner.Scan() {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		if len(event) != 3 {
+			t.Fatalf("event %q has %d fields, want 3", scanner.Text(), len(event))
+		}
+		events = append(events, event)
+	}
+
+	return header, events
+}
+
+func TestCastRecorder_Events(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recordings", "clock.cast")
+
+	r, err := newCastRecorder(path, "clock", 80, 24)
+	if err != nil {
+		t.Fatalf("newCastRecorder() error: %v", err)
+	}
+
+	// "é" split across two reads is held back until complete
+	r.output([]byte("caf\xc3"))
+	r.output([]byte("\xa9\r\n"))
+	r.resize(100, 30)
+	if err := r.close(); err != nil {
+		t.Fatalf("close() error: %v", err)
+	}
+
+	header, events := readCast(t, path)
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 || header.Title != "clock" {
+		t.Errorf("header = %+v, want version 2, 80x24, title clock", header)
+	}
+
+	want := [][2]string{{"o", "caf"}, {"o", "é\r\n"}, {"r", "100x30"}}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %v", len(events), len(want), events)
+	}
+	last := 0.0
+	for i, event := range events {
+		elapsed, _ := event[0].(float64)
+		if elapsed < last {
+			t.Errorf("event %d time %v goes backwards from %v", i, elapsed, last)
+		}
+		last = elapsed
+
+		if event[1] != want[i][0] || event[2] != want[i][1] {
+			t.Errorf("event %d = %q %q, want %q %q", i, event[1], event[2], want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestCastRecorder_KeepsExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clock-20250101-120000.cast")
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		r, err := newCastRecorder(path, "clock", 80, 24)
+		if err != nil {
+			t.Fatalf("newCastRecorder() error: %v", err)
+		}
+		r.output([]byte(fmt.Sprintf("run %d", i)))
+		r.close()
+		paths = append(paths, r.path)
+	}
+
+	dir := filepath.Dir(path)
+	want := []string{path, filepath.Join(dir, "clock-20250101-120000-2.cast"), filepath.Join(dir, "clock-20250101-120000-3.cast")}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("recording %d at %s, want %s", i, paths[i], want[i])
+			continue
+		}
+		if _, events := readCast(t, paths[i]); len(events) != 1 || events[0][2] != fmt.Sprintf("run %d", i) {
+			t.Errorf("recording %d events = %v, want its own output", i, events)
+		}
+	}
+}
+
+func TestPrismOutput_Recording(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer w.Close()
+
+	output := startPrismOutput(r, 20, 4)
+
+	w.Write([]byte("before\r\n"))
+	waitFor(t, func() bool { return screenLine(output, 0) == "before" })
+
+	path := filepath.Join(t.TempDir(), "bar.cast")
+	got, err := output.startRecording(path, "bar")
+	if err != nil {
+		t.Fatalf("startRecording() error: %v", err)
+	}
+	if got != path || output.recording() != path {
+		t.Errorf("startRecording() = %q, recording() = %q, want %q", got, output.recording(), path)
+	}
+
+	w.Write([]byte("after\r\n"))
+	waitFor(t, func() bool { return screenLine(output, 1) == "after" })
+	output.resize(30, 5)
+
+	if stopped := output.stopRecording(); stopped != path {
+		t.Errorf("stopRecording() = %q, want %q", stopped, path)
+	}
+	if output.recording() != "" {
+		t.Error("recording() should be empty after stopRecording()")
+	}
+
+	header, events := readCast(t, path)
+	if header.Width != 20 || header.Height != 4 {
+		t.Errorf("header size = %dx%d, want 20x4", header.Width, header.Height)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want repaint, output and resize: %v", len(events), events)
+	}
+
+	// The recording starts from the screen as it was, not from a blank terminal
+	if repaint, _ := events[0][2].(string); !strings.Contains(repaint, "before") {
+		t.Errorf("first event %q should repaint the current screen", repaint)
+	}
+	if events[1][1] != "o" || events[1][2] != "after\r\n" {
+		t.Errorf("second event = %v, want output %q", events[1], "after\r\n")
+	}
+	if events[2][1] != "r" || events[2][2] != "30x5" {
+		t.Errorf("third event = %v, want resize 30x5", events[2])
+	}
+}
@@ -0,0 +1,115 @@
+// scrollback.go keeps a bounded history of each prism's raw PTY output, so
+// prism/capture can show what a prism printed whether or not it was ever in
+// the foreground.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// scrollbackSize is the number of output bytes kept per prism
+const scrollbackSize = 256 * 1024
+
+// scrollback is a fixed-size ring of the most recent output bytes. It is not
+// safe for concurrent use; prismOutput guards it with its own lock.
+type scrollback struct {
+	buf  []byte
+	next int  // write position
+	full bool // buf has wrapped at least once
+}
+
+func newScrollback(size int) *scrollback {
+	return &scrollback{buf: make([]byte, size)}
+}
+
+func (r *scrollback) Write(p []byte) (int, error) {
+	n := len(p)
+	if n >= len(r.buf) {
+		copy(r.buf, p[n-len(r.buf):])
+		r.next = 0
+		r.full = true
+		return n, nil
+	}
+
+	copied := copy(r.buf[r.next:], p)
+	if copied < n {
+		copy(r.buf, p[copied:])
+		r.full = true
+	}
+	r.next = (r.next + n) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+
+	return n, nil
+}
+
+// Bytes returns a copy of the buffered output, oldest first
+func (r *scrollback) Bytes() []byte {
+	if !r.full {
+		return bytes.Clone(r.buf[:r.next])
+	}
+
+	out := make([]byte, 0, len(r.buf))
+	out = append(out, r.buf[r.next:]...)
+	return append(out, r.buf[:r.next]...)
+}
+
+type captureOptions struct {
+	lines  int  // last N lines, 0 = all
+	bytes  int  // last N bytes, takes precedence over lines
+	strip  bool // remove escape sequences
+	screen bool // visible screen as text instead of the output history
+}
+
+// capture returns the prism's recent output as selected by opts
+func (o *prismOutput) capture(opts captureOptions) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if opts.screen {
+		rows := make([]string, o.screen.rows)
+		for y := range rows {
+			rows[y] = o.screen.lineText(y)
+		}
+		text := strings.TrimRight(strings.Join(rows, "\n"), "\n")
+		return lastLines(text, opts.lines)
+	}
+
+	text := string(o.history.Bytes())
+	if opts.strip {
+		text = ansi.Strip(text)
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+
+	if opts.bytes > 0 {
+		if len(text) > opts.bytes {
+			text = text[len(text)-opts.bytes:]
+		}
+		return text
+	}
+
+	return lastLines(text, opts.lines)
+}
+
+// lastLines returns the last n lines of text; n <= 0 returns all of it
+func lastLines(text string, n int) string {
+	if n <= 0 {
+		return text
+	}
+
+	trimmed := strings.TrimSuffix(text, "\n")
+	idx := len(trimmed)
+	for i := 0; i < n; i++ {
+		idx = strings.LastIndexByte(trimmed[:idx], '\n')
+		if idx < 0 {
+			return text
+		}
+	}
+
+	return text[idx+1:]
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+func TestScrollback_Wraps(t *testing.T) {
+	r := newScrollback(8)
+
+	r.Write([]byte("abc"))
+	if got := string(r.Bytes()); got != "abc" {
+		t.Errorf("Bytes() = %q, want %q", got, "abc")
+	}
+
+	r.Write([]byte("defgh"))
+	if got := string(r.Bytes()); got != "abcdefgh" {
+		t.Errorf("Bytes() when exactly full = %q, want %q", got, "abcdefgh")
+	}
+
+	r.Write([]byte("ij"))
+	if got := string(r.Bytes()); got != "cdefghij" {
+		t.Errorf("Bytes() after wrap = %q, want %q", got, "cdefghij")
+	}
+
+	r.Write([]byte("0123456789"))
+	if got := string(r.Bytes()); got != "23456789" {
+		t.Errorf("Bytes() after oversized write = %q, want %q", got, "23456789")
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"a\nb\nc\n", 10, "a\nb\nc\n"},
+		{"a\nb\nc\n", 0, "a\nb\nc\n"},
+		{"", 5, ""},
+	}
+
+	for _, tt := range tests {
+		if got := lastLines(tt.text, tt.n); got != tt.want {
+			t.Errorf("lastLines(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPrismctlIPC_Capture(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	// A background prism whose output was never mirrored
+	sup := newSupervisor(nil, nil, nil)
+	output := startPrismOutput(r, 20, 4)
+	sup.prismList = append(sup.prismList,
+		prismInstance{name: "bar", pid: 1, state: prismForeground},
+		prismInstance{name: "clock", pid: 2, state: prismBackground, output: output},
+	)
+
+	w.Write([]byte("boot\r\n\x1b[1;32m12:00\x1b[0m\r\n\x1b[1;32m12:01\x1b[0m\r\n"))
+	waitFor(t, func() bool { return screenLine(output, 2) == "12:01" })
+
+	srv := rpc.NewServer(sockPath, newRPCHandlers(sup, nil), nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	result, err := client.Capture(ctx, &rpc.CaptureRequest{Name: "clock", Lines: 2, Strip: true})
+	if err != nil {
+		t.Fatalf("Capture() error: %v", err)
+	}
+	if result.Content != "12:00\n12:01\n" {
+		t.Errorf("Capture(lines=2, strip) = %q, want %q", result.Content, "12:00\n12:01\n")
+	}
+
+	result, err = client.Capture(ctx, &rpc.CaptureRequest{Name: "clock", Bytes: 16})
+	if err != nil {
+		t.Fatalf("Capture() error: %v", err)
+	}
+	if !strings.Contains(result.Content, "\x1b[0m") || len(result.Content) != 16 {
+		t.Errorf("Capture(bytes=16) = %q, want last 16 raw bytes", result.Content)
+	}
+
+	result, err = client.Capture(ctx, &rpc.CaptureRequest{Name: "clock", Screen: true})
+	if err != nil {
+		t.Fatalf("Capture() error: %v", err)
+	}
+	if result.Content != "boot\n12:00\n12:01" {
+		t.Errorf("Capture(screen) = %q, want %q", result.Content, "boot\n12:00\n12:01")
+	}
+
+	if _, err := client.Capture(ctx, &rpc.CaptureRequest{Name: "missing"}); err == nil {
+		t.Error("Capture() of unknown prism should fail")
+	}
+}
@@ -0,0 +1,194 @@
+// sendkeys.go encodes tmux-style key names for prism/send-keys. Keys are
+// written as a terminal would send them in legacy (xterm) mode, which kitty
+// also uses unless a prism opts into its keyboard protocol.
+//
+// A key is an optional chain of modifiers (C-, M-, S-) followed by a single
+// character or a key name: Enter, Tab, BTab, Escape, Space, BSpace, Up, Down,
+// Left, Right, Home, End, Insert, Delete, PageUp, PageDown and F1-F12.
+// Anything else is sent as literal text.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	modShift = 1
+	modAlt   = 2
+	modCtrl  = 4
+)
+
+// cursorKeys end in a letter and follow application cursor mode
+var cursorKeys = map[string]byte{
+	"up":    'A',
+	"down":  'B',
+	"right": 'C',
+	"left":  'D',
+	"home":  'H',
+	"end":   'F',
+}
+
+// tildeKeys are sent as CSI <code> ~
+var tildeKeys = map[string]int{
+	"insert":   2,
+	"ic":       2,
+	"delete":   3,
+	"dc":       3,
+	"pageup":   5,
+	"pgup":     5,
+	"ppage":    5,
+	"pagedown": 6,
+	"pgdn":     6,
+	"npage":    6,
+	"f5":       15,
+	"f6":       17,
+	"f7":       18,
+	"f8":       19,
+	"f9":       20,
+	"f10":      21,
+	"f11":      23,
+	"f12":      24,
+}
+
+// functionKeys F1-F4 are sent as SS3 <letter>
+var functionKeys = map[string]byte{
+	"f1": 'P',
+	"f2": 'Q',
+	"f3": 'R',
+	"f4": 'S',
+}
+
+var plainKeys = map[string]string{
+	"enter":     "\r",
+	"tab":       "\t",
+	"btab":      "\x1b[Z",
+	"escape":    "\x1b",
+	"esc":       "\x1b",
+	"space":     " ",
+	"bspace":    "\x7f",
+	"backspace": "\x7f",
+}
+
+// encodeKeys encodes each key, sending unknown names as literal text. When
+// literal is set every key is sent as text.
+func encodeKeys(keys []string, literal, appCursor bool) []byte {
+	var out []byte
+	for _, key := range keys {
+		if !literal {
+			if seq, ok := encodeKey(key, appCursor); ok {
+				out = append(out, seq...)
+				continue
+			}
+		}
+		out = append(out, key...)
+	}
+	return out
+}
+
+// encodeKey encodes a single named key; ok is false when key is not a key name
+func encodeKey(key string, appCursor bool) ([]byte, bool) {
+	mods := 0
+	base := key
+	for len(base) > 2 && base[1] == '-' {
+		switch base[0] {
+		case 'C':
+			mods |= modCtrl
+		case 'M':
+			mods |= modAlt
+		case 'S':
+			mods |= modShift
+		default:
+			return nil, false
+		}
+		base = base[2:]
+	}
+
+	if utf8.RuneCountInString(base) == 1 {
+		return encodeChar(base, mods)
+	}
+
+	name := strings.ToLower(base)
+
+	if final, ok := cursorKeys[name]; ok {
+		if mods != 0 {
+			return fmt.Appendf(nil, "\x1b[1;%d%c", mods+1, final), true
+		}
+		if appCursor {
+			return []byte{0x1b, 'O', final}, true
+		}
+		return []byte{0x1b, '[', final}, true
+	}
+
+	if code, ok := tildeKeys[name]; ok {
+		if mods != 0 {
+			return fmt.Appendf(nil, "\x1b[%d;%d~", code, mods+1), true
+		}
+		return fmt.Appendf(nil, "\x1b[%d~", code), true
+	}
+
+	if final, ok := functionKeys[name]; ok {
+		if mods != 0 {
+			return fmt.Appendf(nil, "\x1b[1;%d%c", mods+1, final), true
+		}
+		return []byte{0x1b, 'O', final}, true
+	}
+
+	if seq, ok := plainKeys[name]; ok {
+		if name == "tab" && mods&modShift != 0 {
+			seq = plainKeys["btab"]
+		}
+		if name == "space" && mods&modCtrl != 0 {
+			seq = "\x00"
+		}
+		if mods&modAlt != 0 {
+			seq = "\x1b" + seq
+		}
+		return []byte(seq), true
+	}
+
+	return nil, false
+}
+
+// encodeChar applies modifiers to a single character
+func encodeChar(char string, mods int) ([]byte, bool) {
+	if mods&modShift != 0 {
+		char = strings.ToUpper(char)
+	}
+
+	seq := []byte(char)
+	if mods&modCtrl != 0 {
+		if len(seq) != 1 {
+			return nil, false
+		}
+		c, ok := ctrlByte(seq[0])
+		if !ok {
+			return nil, false
+		}
+		seq = []byte{c}
+	}
+
+	if mods&modAlt != 0 {
+		seq = append([]byte{0x1b}, seq...)
+	}
+
+	return seq, true
+}
+
+// ctrlByte returns the control character a terminal sends for Ctrl+c
+func ctrlByte(c byte) (byte, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - 'a' + 1, true
+	case c >= '@' && c <= '_':
+		return c & 0x1f, true
+	case c == ' ':
+		return 0, true
+	case c == '?':
+		return 0x7f, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+func TestEncodeKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		appCursor bool
+		want      string
+		ok        bool
+	}{
+		{"Enter", false, "\r", true},
+		{"enter", false, "\r", true},
+		{"Tab", false, "\t", true},
+		{"S-Tab", false, "\x1b[Z", true},
+		{"Escape", false, "\x1b", true},
+		{"BSpace", false, "\x7f", true},
+		{"C-c", false, "\x03", true},
+		{"C-[", false, "\x1b", true},
+		{"C-Space", false, "\x00", true},
+		{"M-x", false, "\x1bx", true},
+		{"C-M-a", false, "\x1b\x01", true},
+		{"S-a", false, "A", true},
+		{"Up", false, "\x1b[A", true},
+		{"Up", true, "\x1bOA", true},
+		{"C-Left", true, "\x1b[1;5D", true},
+		{"S-Right", false, "\x1b[1;2C", true},
+		{"Home", false, "\x1b[H", true},
+		{"PageDown", false, "\x1b[6~", true},
+		{"M-Delete", false, "\x1b[3;3~", true},
+		{"F1", false, "\x1bOP", true},
+		{"F12", false, "\x1b[24~", true},
+		{"C-F5", false, "\x1b[15;5~", true},
+		{"x", false, "x", true},
+		{"hello", false, "", false},
+		{"a-b", false, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := encodeKey(tt.key, tt.appCursor)
+		if ok != tt.ok || string(got) != tt.want {
+			t.Errorf("encodeKey(%q, %v) = %q, %v, want %q, %v", tt.key, tt.appCursor, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEncodeKeys(t *testing.T) {
+	if got := string(encodeKeys([]string{"echo hi", "Enter", "C-c"}, false, false)); got != "echo hi\r\x03" {
+		t.Errorf("encodeKeys() = %q, want %q", got, "echo hi\r\x03")
+	}
+
+	if got := string(encodeKeys([]string{"Enter", "C-c"}, true, false)); got != "EnterC-c" {
+		t.Errorf("encodeKeys(literal) = %q, want %q", got, "EnterC-c")
+	}
+}
+
+func TestPrismctlIPC_SendKeys(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	// Keys go to background prisms too
+	sup := newSupervisor(nil, nil, nil)
+	sup.prismList = append(sup.prismList,
+		prismInstance{name: "bar", pid: 1, state: prismForeground},
+		prismInstance{name: "chat", pid: 2, state: prismBackground, ptyMaster: w},
+	)
+
+	srv := rpc.NewServer(sockPath, newRPCHandlers(sup, nil), nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	result, err := client.SendKeys(ctx, &rpc.SendKeysRequest{Name: "chat", Keys: []string{"hi", "Enter"}})
+	if err != nil {
+		t.Fatalf("SendKeys() error: %v", err)
+	}
+	if result.Bytes != 3 {
+		t.Errorf("SendKeys() Bytes = %d, want 3", result.Bytes)
+	}
+
+	buf := make([]byte, 3)
+	r.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("failed to read from PTY: %v", err)
+	}
+	if string(buf) != "hi\r" {
+		t.Errorf("PTY received %q, want %q", buf, "hi\r")
+	}
+
+	if _, err := client.SendKeys(ctx, &rpc.SendKeysRequest{Name: "missing", Keys: []string{"x"}}); err == nil {
+		t.Error("SendKeys() to unknown prism should fail")
+	}
+	if _, err := client.SendKeys(ctx, &rpc.SendKeysRequest{Name: "chat"}); err == nil {
+		t.Error("SendKeys() without keys should fail")
+	}
+}
@@ -8,8 +8,11 @@
 //
 // SIGCHLD (child state change)
 //   - Triggered when any child exits/stops
-//   - Reaps ALL exited children in a loop (Wait4 with WNOHANG)
+//   - Reaps every exited prism (Wait4 on each prism PID with WNOHANG)
+//   - Other children, such as exec probes and notify-send, are left to
+//     os/exec: reaping them here would make its Wait fail with ECHILD
 //   - Exit code: normal exit → status code, signal death → 128 + signal
+//   - The signal and core dump flag are kept in the exit history (history.go)
 //
 // SIGINT (Ctrl+C)
 //   - Kills ONLY the foreground prism (first in MRU list)
@@ -18,6 +21,7 @@
 //
 // SIGTERM/SIGHUP
 //   - Full graceful shutdown of all prisms
+//   - A headless prismctl ignores SIGHUP: it has no terminal to lose
 
 package main
 
@@ -30,8 +34,9 @@ import (
 )
 
 type signalHandler struct {
-	sigCh      chan os.Signal
-	supervisor *supervisor
+	sigCh        chan os.Signal
+	supervisor   *supervisor
+	ignoreHangup bool // headless: SIGHUP does not shut down
 }
 
 func newSignalHandler(sup *supervisor) *signalHandler {
@@ -51,8 +56,23 @@ func newSignalHandler(sup *supervisor) *signalHandler {
 	return sh
 }
 
+// run handles signals until prismctl shuts down, whether on a signal or on
+// a shutdown started elsewhere, such as service/shutdown, child/exit-panel
+// or the last prism exiting
 func (sh *signalHandler) run() {
-	for sig := range sh.sigCh {
+	for {
+		var sig os.Signal
+		select {
+		case <-sh.supervisor.shutdownCh:
+			sh.supervisor.waitShutdown()
+			return
+		case s, ok := <-sh.sigCh:
+			if !ok {
+				return
+			}
+			sig = s
+		}
+
 		switch sig {
 		case unix.SIGCHLD:
 			sh.handleSIGCHLD()
@@ -61,7 +81,14 @@ func (sh *signalHandler) run() {
 			if sh.handleSIGINT() {
 				return // Shutdown requested
 			}
-		case unix.SIGTERM, unix.SIGHUP:
+		case unix.SIGHUP:
+			if sh.ignoreHangup {
+				log.Printf("Received SIGHUP while headless, ignoring")
+				continue
+			}
+			sh.handleShutdown(sig)
+			return
+		case unix.SIGTERM:
 			sh.handleShutdown(sig)
 			return
 		case unix.SIGWINCH:
@@ -71,26 +98,22 @@ func (sh *signalHandler) run() {
 }
 
 func (sh *signalHandler) handleSIGCHLD() {
-	// Reap all exited children
-	for {
+	// Reap exited prisms only
+	for _, pid := range sh.supervisor.prismPIDs() {
 		var status unix.WaitStatus
-		pid, err := unix.Wait4(-1, &status, unix.WNOHANG, nil)
-		if err != nil || pid <= 0 {
-			// No more children to reap
-			break
+		if wpid, err := unix.Wait4(pid, &status, unix.WNOHANG, nil); err != nil || wpid <= 0 {
+			// Still running
+			continue
 		}
 
 		// Notify supervisor of child exit
-		exitCode := 0
-		if status.Exited() {
-			exitCode = status.ExitStatus()
-			log.Printf("Child %d exited with code %d", pid, exitCode)
-		} else if status.Signaled() {
-			exitCode = 128 + int(status.Signal())
+		if status.Signaled() {
 			log.Printf("Child %d terminated by signal %s", pid, status.Signal())
+		} else {
+			log.Printf("Child %d exited with code %d", pid, status.ExitStatus())
 		}
 
-		sh.supervisor.handleChildExit(pid, exitCode)
+		sh.supervisor.handleChildExit(pid, status)
 	}
 }
 
@@ -98,7 +121,8 @@ func (sh *signalHandler) handleSIGCHLD() {
 // Returns true if prismctl should shutdown (exit signal loop)
 func (sh *signalHandler) handleSIGINT() bool {
 	sh.supervisor.mu.Lock()
-	hasForeground := len(sh.supervisor.prismList) > 0
+	hasForeground := sh.supervisor.hasForeground()
+	hasPrisms := len(sh.supervisor.prismList) > 0
 	var foregroundName string
 	if hasForeground {
 		foregroundName = sh.supervisor.prismList[0].name
@@ -108,13 +132,17 @@ func (sh *signalHandler) handleSIGINT() bool {
 	if hasForeground {
 		// Kill foreground prism only
 		log.Printf("Ctrl+C: killing foreground prism: %s", foregroundName)
-		if err := sh.supervisor.killPrism(foregroundName); err != nil {
+		if _, err := sh.supervisor.killPrism(foregroundName); err != nil {
 			log.Printf("Failed to kill foreground prism: %v", err)
 		}
 
 		// Note: killPrism is async - handleChildExit will clean up
 		// User can press Ctrl+C again to exit if no more prisms
 		return false // Keep running, let signal loop process SIGCHLD
+	} else if hasPrisms {
+		// Idle with background prisms: they are only stopped explicitly
+		log.Printf("Ctrl+C: no foreground prism, ignoring")
+		return false
 	} else {
 		// No prisms running, shutdown prismctl
 		log.Printf("Ctrl+C: no prisms running, shutting down")
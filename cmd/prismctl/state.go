@@ -51,6 +51,11 @@ func (s *StateManager) OnPrismResumed(name string) {
 	s.OnForegroundChanged(name)
 }
 
+// OnUsageSampled publishes a prism's latest usage sample
+func (s *StateManager) OnUsageSampled(name string, usage prismUsage) {
+	s.writer.SetUsage(name, usage.cpuPercent, usage.rssBytes, usage.threads)
+}
+
 func (s *StateManager) UpdatePrism(index int, name string, pid int, fg bool, restarts uint8) {
 	stateVal := state.PrismStateBg
 	if fg {
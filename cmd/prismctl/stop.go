@@ -0,0 +1,196 @@
+// stop.go stops prisms gracefully. A prism is sent its stop signal (SIGTERM
+// unless configured otherwise) and given its stop timeout to exit before it
+// is sent SIGKILL; with a stop timeout of zero it is sent SIGKILL right away. Both go to the prism's whole process group, so helpers it
+// spawned are stopped with it (see process.go). A prism has only stopped
+// once its process has been reaped, which is what prismExit reports to
+// anyone waiting on it.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const defaultStopTimeout = 5 * time.Second
+
+// stopNow is the appLaunch stop timeout of stop_timeout = "0s", since zero
+// there means the default
+const stopNow time.Duration = -1
+
+// prismExit reports a prism's exit status once its process has been reaped
+type prismExit struct {
+	done     chan struct{}
+	once     sync.Once
+	exitCode int         // valid once done is closed
+	killed   atomic.Bool // SIGKILL was sent after the stop timeout
+}
+
+func newPrismExit() *prismExit {
+	return &prismExit{done: make(chan struct{})}
+}
+
+// finish records the exit status and wakes everyone waiting for the exit
+func (e *prismExit) finish(exitCode int) {
+	e.once.Do(func() {
+		e.exitCode = exitCode
+		close(e.done)
+	})
+}
+
+// exitStatusCode converts a wait status to an exit code: the status for a
+// normal exit, 128 + signal for a signal death
+func exitStatusCode(status unix.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
+
+// killPrism sends a prism its stop signal and returns without waiting for it
+// to exit. If it is still running after its stop timeout it is sent SIGKILL.
+// handleChildExit cleans up once the process has been reaped.
+func (s *supervisor) killPrism(prismName string) (*prismExit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targetIdx := s.findPrism(prismName)
+	if targetIdx == -1 {
+		return nil, fmt.Errorf("prism not found: %s", prismName)
+	}
+
+	target := s.prismList[targetIdx]
+	if err := s.stopLocked(target); err != nil {
+		return nil, err
+	}
+	s.prismList[targetIdx].stopRequested = true
+
+	return target.exit, nil
+}
+
+// stopLocked sends target its stop signal and escalates to SIGKILL after
+// its stop timeout
+// Assumes caller holds s.mu lock
+func (s *supervisor) stopLocked(target prismInstance) error {
+	log.Printf("Stopping prism %s (PID %d) with %s", target.name, target.pid, unix.SignalName(target.stopSignal))
+
+	if target.stopTimeout == 0 {
+		target.exit.killed.Store(true)
+		if err := signalGroup(target.pid, unix.SIGKILL); err != nil {
+			return fmt.Errorf("failed to send SIGKILL: %w", err)
+		}
+		return nil
+	}
+
+	// Resume first - suspended processes don't handle signals until continued
+	signalGroup(target.pid, unix.SIGCONT)
+
+	if err := signalGroup(target.pid, target.stopSignal); err != nil {
+		return fmt.Errorf("failed to send %s: %w", unix.SignalName(target.stopSignal), err)
+	}
+
+	go escalateStop(target)
+
+	return nil
+}
+
+// escalateStop sends SIGKILL to prism if it has not exited within its stop
+// timeout
+func escalateStop(prism prismInstance) {
+	timer := time.NewTimer(prism.stopTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-prism.exit.done:
+		return
+	case <-timer.C:
+	}
+
+	log.Printf("Prism %s (PID %d) still running after %v, sending SIGKILL", prism.name, prism.pid, prism.stopTimeout)
+	prism.exit.killed.Store(true)
+	if err := signalGroup(prism.pid, unix.SIGKILL); err != nil {
+		log.Printf("Warning: failed to SIGKILL %s: %v", prism.name, err)
+	}
+}
+
+// stopAll stops every prism at once and waits until each one has exited or
+// been killed after its stop timeout. s.mu is released while waiting, so
+// RPCs are not held up; the processes are reaped here or by the SIGCHLD
+// handler, whichever comes first.
+// Assumes caller holds s.mu lock
+func (s *supervisor) stopAll() {
+	// Resume all suspended prisms first - they don't handle signals while suspended
+	for _, prism := range s.prismList {
+		signalGroup(prism.pid, unix.SIGCONT)
+	}
+
+	pending := make([]prismInstance, 0, len(s.prismList))
+	for _, prism := range s.prismList {
+		sig := prism.stopSignal
+		if prism.stopTimeout == 0 {
+			sig = unix.SIGKILL
+			prism.exit.killed.Store(true)
+		}
+		log.Printf("Stopping prism %s (PID %d) with %s", prism.name, prism.pid, unix.SignalName(sig))
+
+		if err := signalGroup(prism.pid, sig); err != nil {
+			log.Printf("Warning: failed to send %s to %s: %v", unix.SignalName(sig), prism.name, err)
+			continue
+		}
+		pending = append(pending, prism)
+	}
+
+	start := time.Now()
+	for len(pending) > 0 {
+		remaining := pending[:0]
+		for _, prism := range pending {
+			if reapPrism(prism, unix.WNOHANG) {
+				continue
+			}
+
+			if time.Since(start) >= prism.stopTimeout && !prism.exit.killed.Load() {
+				log.Printf("Prism %s (PID %d) still running after %v, sending SIGKILL", prism.name, prism.pid, prism.stopTimeout)
+				prism.exit.killed.Store(true)
+				if err := signalGroup(prism.pid, unix.SIGKILL); err != nil {
+					log.Printf("Warning: failed to SIGKILL %s: %v", prism.name, err)
+				}
+			}
+			remaining = append(remaining, prism)
+		}
+
+		pending = remaining
+		if len(pending) > 0 {
+			s.mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			s.mu.Lock()
+		}
+	}
+}
+
+// reapPrism waits for prism's process with the given wait4 options and
+// reports whether it is gone. A process already reaped elsewhere counts as
+// gone; its exit is then reported by handleChildExit.
+func reapPrism(prism prismInstance, options int) bool {
+	var status unix.WaitStatus
+	pid, err := unix.Wait4(prism.pid, &status, options, nil)
+	for err == unix.EINTR {
+		pid, err = unix.Wait4(prism.pid, &status, options, nil)
+	}
+	if err != nil {
+		return true
+	}
+	if pid == 0 {
+		return false
+	}
+
+	exitCode := exitStatusCode(status)
+	log.Printf("Prism %s (PID %d) exited with code %d", prism.name, prism.pid, exitCode)
+	prism.exit.finish(exitCode)
+
+	return true
+}
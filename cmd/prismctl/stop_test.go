@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+	"golang.org/x/sys/unix"
+)
+
+// startShell starts a shell running script as a prism that stops with sig
+func startShell(t *testing.T, name, script string, sig unix.Signal, timeout time.Duration) (*exec.Cmd, prismInstance) {
+	t.Helper()
+
+	cmd := exec.Command("sh", "-c", script)
+	// Its own process group, like a prism started with Setsid
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start %s: %v", name, err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	// Give the shell time to install its traps
+	time.Sleep(50 * time.Millisecond)
+
+	return cmd, prismInstance{
+		name:        name,
+		pid:         cmd.Process.Pid,
+		state:       prismBackground,
+		stopSignal:  sig,
+		stopTimeout: timeout,
+		exit:        newPrismExit(),
+	}
+}
+
+// reapInto stands in for the SIGCHLD handler for a single child
+func reapInto(sup *supervisor, cmd *exec.Cmd) {
+	go func() {
+		cmd.Wait()
+		status := unix.WaitStatus(cmd.ProcessState.Sys().(syscall.WaitStatus))
+		sup.handleChildExit(cmd.Process.Pid, status)
+	}()
+}
+
+func TestPrismctlIPC_DownWait(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	sup := newSupervisor(nil, nil, nil)
+
+	graceful, gracefulPrism := startShell(t, "graceful", `trap "exit 3" HUP; while :; do sleep 0.02; done`, unix.SIGHUP, 5*time.Second)
+	stubborn, stubbornPrism := startShell(t, "stubborn", `trap "" TERM; while :; do sleep 0.02; done`, unix.SIGTERM, 100*time.Millisecond)
+
+	sup.prismList = append(sup.prismList,
+		prismInstance{name: "bar", pid: 1, state: prismForeground},
+		gracefulPrism,
+		stubbornPrism,
+	)
+	reapInto(sup, graceful)
+	reapInto(sup, stubborn)
+
+	srv := rpc.NewServer(sockPath, newRPCHandlers(sup, nil), nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	result, err := client.DownWait(ctx, "graceful")
+	if err != nil {
+		t.Fatalf("DownWait(graceful) error: %v", err)
+	}
+	if !result.Exited || result.ExitCode != 3 || result.Killed {
+		t.Errorf("DownWait(graceful) = %+v, want exit code 3 without SIGKILL", result)
+	}
+
+	result, err = client.DownWait(ctx, "stubborn")
+	if err != nil {
+		t.Fatalf("DownWait(stubborn) error: %v", err)
+	}
+	if !result.Exited || result.ExitCode != 128+int(unix.SIGKILL) || !result.Killed {
+		t.Errorf("DownWait(stubborn) = %+v, want SIGKILL after stop timeout", result)
+	}
+
+	sup.mu.Lock()
+	remaining := len(sup.prismList)
+	sup.mu.Unlock()
+	if remaining != 1 {
+		t.Errorf("prismList has %d entries after both exits, want 1", remaining)
+	}
+}
+
+func TestSupervisor_StopAll(t *testing.T) {
+	sup := newSupervisor(nil, nil, nil)
+
+	_, graceful := startShell(t, "graceful", `trap "exit 0" TERM; while :; do sleep 0.02; done`, unix.SIGTERM, 5*time.Second)
+	_, stubborn := startShell(t, "stubborn", `trap "" TERM; while :; do sleep 0.02; done`, unix.SIGTERM, 500*time.Millisecond)
+	_, killNow := startShell(t, "kill-now", `trap "" TERM; while :; do sleep 0.02; done`, unix.SIGTERM, 0)
+	sup.prismList = append(sup.prismList, graceful, stubborn, killNow)
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		sup.mu.Lock()
+		sup.stopAll()
+		sup.mu.Unlock()
+		close(done)
+	}()
+
+	// The supervisor stays usable while stubborn runs out its stop timeout
+	<-killNow.exit.done
+	locked := time.Now()
+	sup.mu.Lock()
+	sup.mu.Unlock()
+	if waited := time.Since(locked); waited > 100*time.Millisecond {
+		t.Errorf("waited %v for s.mu during stopAll()", waited)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("kill-now took %v to exit, want SIGKILL at once", elapsed)
+	}
+
+	<-done
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stopAll() took %v, want about the stubborn stop timeout", elapsed)
+	}
+
+	for _, prism := range []prismInstance{graceful, stubborn} {
+		select {
+		case <-prism.exit.done:
+		default:
+			t.Fatalf("%s was not reaped by stopAll()", prism.name)
+		}
+	}
+
+	if graceful.exit.exitCode != 0 || graceful.exit.killed.Load() {
+		t.Errorf("graceful exit = %d (killed=%v), want 0", graceful.exit.exitCode, graceful.exit.killed.Load())
+	}
+	for _, prism := range []prismInstance{stubborn, killNow} {
+		if prism.exit.exitCode != 128+int(unix.SIGKILL) || !prism.exit.killed.Load() {
+			t.Errorf("%s exit = %d (killed=%v), want SIGKILL", prism.name, prism.exit.exitCode, prism.exit.killed.Load())
+		}
+	}
+}
@@ -21,17 +21,37 @@ const (
 )
 
 type prismInstance struct {
-	name      string
+	name      string // instance ID, unique within the panel
+	app       string // app whose launch settings it runs
 	pid       int
 	state     prismState
 	ptyMaster *os.File
+	output    *prismOutput // drains ptyMaster into the prism's screen model
+	seq       int          // start order, numbers prisms for prefix keys
+	started   time.Time
+
+	stopSignal  unix.Signal
+	stopTimeout time.Duration
+	exit        *prismExit     // finished once the process has been reaped
+	liveness    *livenessProbe // nil when not probed
+	heartbeat   *heartbeat     // last child/heartbeat, for the heartbeat probe
+
+	status    string // set by the prism over the child socket
+	attention bool   // the prism requested attention while not in the foreground
+
+	stopRequested bool // stopped by prism/down or a key rather than exiting on its own
+
+	runInBackground bool           // not suspended in the background, only hidden
+	lifecycleSignal unix.Signal    // sent on lifecycle changes, zero = none
+	lifecycle       lifecycleState // what the prism was last told, see lifecycle.go
 }
 
 type supervisor struct {
 	mu           sync.Mutex
-	termState    *terminalState
-	prismList    []prismInstance // MRU list: [0] = foreground, [1] = most recent background, etc.
+	term         RealTerminal // kitty panel PTY, or attached clients when headless
+	prismList    []prismInstance // MRU list: [0] = foreground (unless idle), [1] = most recent background, etc.
 	shutdownCh   chan struct{}
+	shutdownDone chan struct{} // closed once shutdown() has completed
 	childExitCh  chan childExit
 	mirror       *mirrorState
 	mirrorCtx    context.Context
@@ -39,7 +59,25 @@ type supervisor struct {
 	shuttingDown bool
 	stateManager *StateManager
 	notifyMgr    *NotificationManager
-	appPaths     map[string]string // App name → resolved binary path
+	apps         map[string]appLaunch // App name → resolved binary path, args, env and cwd
+	layout       *compositor          // split layout, nil when one prism fills the panel
+	keys         *prefixKeys          // prefix key handling, nil when disabled
+	picker       *prismPicker         // open prism picker, nil when closed
+	record       bool                 // record every prism launched from now on
+	keepAlive    bool                 // keep running with no prisms, showing idle
+	idle         idleScreen           // placeholder shown while no prism is foreground
+	usage        map[int]prismUsage   // PID → last usage sample of the prism's process tree
+	history      []exitRecord         // recent exits, oldest first, at most historySize
+	shownModes   vtModes              // modes the real terminal is in while no prism output is live
+	health       map[int]*prismHealth // PID → liveness state of prisms that failed a probe
+	restarts     map[string]bool      // prisms stopped by their liveness probe, launched again on exit
+	reloads      map[string]bool      // instances of reconfigured apps, launched again on exit with the new settings
+	copies       map[string]bool      // "<app>:N" instances launched with new_instance, which start may launch again
+	childSocket  string               // path prisms reach the child socket at, empty when not served
+	panel        string               // panel instance name passed to prisms, empty when not served
+	panelFocused bool                 // the panel has keyboard focus, assumed until reported otherwise
+	panelHidden  bool                 // the panel was reported hidden with prism/visibility
+	nextSeq      int
 }
 
 type childExit struct {
@@ -47,20 +85,39 @@ type childExit struct {
 	exitCode int
 }
 
-func newSupervisor(termState *terminalState, stateMgr *StateManager, notifyMgr *NotificationManager) *supervisor {
+func newSupervisor(term RealTerminal, stateMgr *StateManager, notifyMgr *NotificationManager) *supervisor {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &supervisor{
-		termState:     termState,
+		term:          term,
 		prismList:     make([]prismInstance, 0),
 		shutdownCh:    make(chan struct{}),
+		shutdownDone:  make(chan struct{}),
 		childExitCh:   make(chan childExit, 1),
 		mirror:       nil,
 		mirrorCtx:    ctx,
 		mirrorCancel: cancel,
 		stateManager:  stateMgr,
 		notifyMgr:     notifyMgr,
-		appPaths:      make(map[string]string),
+		apps:          make(map[string]appLaunch),
+		usage:         make(map[int]prismUsage),
+		health:        make(map[int]*prismHealth),
+		restarts:      make(map[string]bool),
+		reloads:       make(map[string]bool),
+		copies:        make(map[string]bool),
+		panelFocused:  true,
+	}
+}
+
+// prismPIDs returns the PIDs of the prisms that have not been reaped
+func (s *supervisor) prismPIDs() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pids := make([]int, 0, len(s.prismList))
+	for _, p := range s.prismList {
+		pids = append(pids, p.pid)
 	}
+	return pids
 }
 
 func (s *supervisor) findPrism(name string) int {
@@ -72,10 +129,132 @@ func (s *supervisor) findPrism(name string) int {
 	return -1
 }
 
-func (s *supervisor) registerApp(name, path string) {
+func (s *supervisor) findPID(pid int) int {
+	for i, p := range s.prismList {
+		if p.pid == pid {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasForeground reports whether prismList[0] is in the foreground. It is not
+// while the panel shows the idle placeholder.
+// Assumes caller holds s.mu lock
+func (s *supervisor) hasForeground() bool {
+	return len(s.prismList) > 0 && s.prismList[0].state == prismForeground
+}
+
+func (s *supervisor) registerApp(name string, launch appLaunch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apps[name] = launch
+}
+
+// setLayout switches the panel to a split layout of the given panes. It must
+// be called before any prism is started.
+func (s *supervisor) setLayout(kind layoutKind, specs []paneSpec) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.prismList) > 0 {
+		return fmt.Errorf("cannot change layout with %d prisms running", len(s.prismList))
+	}
+
+	if modes, ok := s.layout.close(); ok {
+		s.shownModes = modes
+	}
+	s.layout = nil
+
+	if kind == layoutSingle {
+		return nil
+	}
+
+	winsize, err := s.term.Size()
+	if err != nil {
+		return err
+	}
+	cols, rows := int(winsize.Col), int(winsize.Row)
+
+	s.layout = newCompositor(kind, specs, s.term, cols, rows, s.shownModes)
+	log.Printf("Using split layout with %d panes (%dx%d)", len(specs), cols, rows)
+
+	return nil
+}
+
+// setRecord turns on recording for prisms launched from now on
+func (s *supervisor) setRecord(record bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.appPaths[name] = path
+	s.record = record
+}
+
+// suspendForeground moves the current foreground prism to the background.
+// Prisms shown in a split layout stay running and visible; they only lose
+// input focus.
+// Assumes caller holds s.mu lock
+func (s *supervisor) suspendForeground() {
+	if !s.hasForeground() {
+		return
+	}
+
+	old := s.prismList[0]
+	s.prismList[0].state = prismBackground
+
+	// Tell it before it is stopped, so the news is waiting when it resumes
+	s.updateLifecycle()
+
+	if s.layout.has(old.name) {
+		return
+	}
+
+	s.detachOutput(old)
+	if old.runInBackground {
+		log.Printf("Hiding current foreground %s (PID %d), which keeps running", old.name, old.pid)
+		return
+	}
+
+	log.Printf("Suspending current foreground %s (PID %d)", old.name, old.pid)
+	if err := signalGroup(old.pid, unix.SIGSTOP); err != nil {
+		log.Printf("Warning: failed to SIGSTOP %s: %v", old.name, err)
+	}
+}
+
+// detachOutput stops forwarding prism's output to the real terminal and
+// remembers the modes it left the terminal in
+// Assumes caller holds s.mu lock
+func (s *supervisor) detachOutput(prism prismInstance) {
+	if modes, wasLive := prism.output.detach(); wasLive {
+		s.shownModes = modes
+	}
+}
+
+// enterModes returns the sequences that move the real terminal from the
+// modes it is in to modes, changing only those that differ, and records
+// modes as the terminal's. The caller writes them, with whatever it draws
+// next, to the terminal.
+// Assumes caller holds s.mu lock
+func (s *supervisor) enterModes(modes vtModes) []byte {
+	seq := modeTransition(s.shownModes, modes)
+	s.shownModes = modes
+	return []byte(seq)
+}
+
+// syncPrismSize sizes a prism's PTY to its pane, or to the whole real PTY
+// outside a split layout
+func (s *supervisor) syncPrismSize(name string, ptyMaster *os.File) error {
+	winsize, err := s.term.Size()
+	if err != nil {
+		return err
+	}
+
+	if s.layout != nil {
+		if cols, rows, ok := s.layout.paneSize(name); ok {
+			return syncPaneSize(winsize, int(ptyMaster.Fd()), cols, rows)
+		}
+	}
+
+	return setPTYSize(int(ptyMaster.Fd()), winsize)
 }
 
 func (s *supervisor) startPrism(prismName string) error {
@@ -89,10 +268,15 @@ func (s *supervisor) start(prismName string) error {
 
 	targetIdx := s.findPrism(prismName)
 	if targetIdx == -1 {
+		// Only new_instance picks "<app>:N" IDs. start launches such an
+		// instance again, as when shined restarts it, once it has run.
+		if app := s.instanceApp(prismName); app != prismName && !s.copies[prismName] {
+			return fmt.Errorf("no instance %s: launch another instance of %s with new_instance", prismName, app)
+		}
 		return s.launchAndForeground(prismName)
 	}
 
-	if targetIdx == 0 {
+	if targetIdx == 0 && s.hasForeground() {
 		log.Printf("Prism %s already in foreground", prismName)
 		return nil
 	}
@@ -100,55 +284,68 @@ func (s *supervisor) start(prismName string) error {
 	return s.resumeToForeground(targetIdx)
 }
 
-// launchAndForeground launches a new prism and brings it to foreground
+// launchAndForeground launches a new prism and brings it to foreground.
+// prismName is the instance ID, which determines the app it runs.
 // Assumes caller holds s.mu lock
 func (s *supervisor) launchAndForeground(prismName string) error {
 	var binaryPath string
 	var err error
 
-	if path, ok := s.appPaths[prismName]; ok && path != "" {
-		binaryPath = path
+	// shutdown() releases s.mu while its prisms exit
+	if s.shuttingDown {
+		return fmt.Errorf("prismctl is shutting down")
+	}
+
+	app := s.instanceApp(prismName)
+	launch := s.apps[app]
+	if launch.path != "" {
+		binaryPath = launch.path
 	}
 
 	if binaryPath == "" {
-		binaryPath, err = exec.LookPath(prismName)
+		binaryPath, err = exec.LookPath(app)
 		if err != nil {
-			return fmt.Errorf("prism not found in PATH: %s (%w)", prismName, err)
+			return fmt.Errorf("prism not found in PATH: %s (%w)", app, err)
 		}
 	}
 
+	if s.layout != nil && !s.layout.has(prismName) {
+		return fmt.Errorf("prism %s has no pane in the split layout", prismName)
+	}
+
 	log.Printf("Launching new prism: %s (resolved to %s)", prismName, binaryPath)
 
-	if len(s.prismList) > 0 {
-		old := s.prismList[0]
-		log.Printf("Suspending current foreground %s (PID %d)", old.name, old.pid)
-		if err := unix.Kill(old.pid, unix.SIGSTOP); err != nil {
-			log.Printf("Warning: failed to SIGSTOP %s: %v", old.name, err)
-		}
-		s.prismList[0].state = prismBackground
-	}
+	s.suspendForeground()
 
 	ptyMaster, ptySlave, err := allocatePTY()
 	if err != nil {
 		return fmt.Errorf("failed to allocate PTY: %w", err)
 	}
 
-	if err := syncTerminalSize(int(os.Stdin.Fd()), int(ptyMaster.Fd())); err != nil {
+	if err := s.syncPrismSize(prismName, ptyMaster); err != nil {
 		closePTY(ptyMaster)
 		ptySlave.Close()
 		return fmt.Errorf("failed to sync terminal size: %w", err)
 	}
 
+	cols, rows, err := ptySize(int(ptyMaster.Fd()))
+	if err != nil {
+		closePTY(ptyMaster)
+		ptySlave.Close()
+		return err
+	}
+
 	// CRITICAL: Reset terminal state
 	log.Printf("Resetting terminal state")
-	if err := s.termState.resetTerminalState(); err != nil {
+	if err := s.term.Reset(); err != nil {
 		log.Printf("Warning: failed to reset terminal state: %v", err)
 	}
 
 	// Stabilization delay
 	time.Sleep(10 * time.Millisecond)
 
-	cmd := exec.Command(binaryPath)
+	cmd := launch.command(binaryPath)
+	cmd.Env = append(cmd.Env, s.childEnv(prismName)...)
 	cmd.Stdin = ptySlave
 	cmd.Stdout = ptySlave
 	cmd.Stderr = ptySlave
@@ -171,16 +368,57 @@ func (s *supervisor) launchAndForeground(prismName string) error {
 
 	newInstance := prismInstance{
 		name:      prismName,
+		app:       app,
 		pid:       pid,
 		state:     prismForeground,
 		ptyMaster: ptyMaster,
+		output:    startPrismOutput(ptyMaster, cols, rows),
+		seq:       s.nextSeq,
+		started:   time.Now(),
+		heartbeat: newHeartbeat(),
+
+		stopSignal:  launch.stopSignal,
+		stopTimeout: launch.stopTimeout,
+		exit:        newPrismExit(),
+		liveness:    launch.liveness,
+
+		runInBackground: launch.runInBackground,
+		lifecycleSignal: launch.lifecycleSignal,
+	}
+	if newInstance.stopSignal == 0 {
+		newInstance.stopSignal = unix.SIGTERM
 	}
+	switch newInstance.stopTimeout {
+	case 0:
+		newInstance.stopTimeout = defaultStopTimeout
+	case stopNow:
+		newInstance.stopTimeout = 0
+	}
+	s.nextSeq++
 	s.prismList = append([]prismInstance{newInstance}, s.prismList...)
+	// A new prism starts out knowing where it stands
+	s.prismList[0].lifecycle = s.lifecycleOf(0)
+
+	if s.layout != nil {
+		s.layout.attach(prismName, newInstance.output)
+	}
+
+	if s.record {
+		if path, err := newInstance.output.startRecording(recordingPath(prismName), prismName); err != nil {
+			log.Printf("Warning: failed to start recording %s: %v", prismName, err)
+		} else {
+			log.Printf("Recording %s to %s", prismName, path)
+		}
+	}
 
 	if err := s.activateMirrorToForeground(); err != nil {
 		log.Printf("Warning: failed to start mirror: %v", err)
 	}
 
+	if newInstance.liveness != nil {
+		go s.watchLiveness(newInstance, *newInstance.liveness)
+	}
+
 	if s.stateManager != nil {
 		s.stateManager.OnPrismStarted(prismName, pid, true)
 	}
@@ -193,87 +431,108 @@ func (s *supervisor) launchAndForeground(prismName string) error {
 }
 
 func (s *supervisor) resumeToForeground(targetIdx int) error {
-	target := s.prismList[targetIdx]
-	log.Printf("Resuming prism %s (PID %d) to foreground", target.name, target.pid)
+	previous := ""
+	if s.hasForeground() {
+		previous = s.prismList[0].name
+	}
 
-	if len(s.prismList) > 0 && targetIdx != 0 {
-		old := s.prismList[0]
-		log.Printf("Suspending current foreground %s (PID %d)", old.name, old.pid)
-		if err := unix.Kill(old.pid, unix.SIGSTOP); err != nil {
-			log.Printf("Warning: failed to SIGSTOP %s: %v", old.name, err)
-		}
-		s.prismList[0].state = prismBackground
+	if targetIdx != 0 {
+		s.suspendForeground()
 	}
 
+	return s.promote(targetIdx, previous)
+}
+
+// promote resumes the background prism at targetIdx and makes it the
+// foreground. previous names the prism that lost the foreground, empty when
+// the panel was idle.
+// Assumes caller holds s.mu lock and no prism is in the foreground
+func (s *supervisor) promote(targetIdx int, previous string) error {
+	target := s.prismList[targetIdx]
+	log.Printf("Resuming prism %s (PID %d) to foreground", target.name, target.pid)
+
 	// Resume the target prism
-	if err := unix.Kill(target.pid, unix.SIGCONT); err != nil {
+	if err := signalGroup(target.pid, unix.SIGCONT); err != nil {
 		log.Printf("Warning: failed to SIGCONT %s: %v", target.name, err)
 	}
 
 	log.Printf("Resetting terminal state")
-	if err := s.termState.resetTerminalState(); err != nil {
+	if err := s.term.Reset(); err != nil {
 		log.Printf("Warning: failed to reset terminal state: %v", err)
 	}
 
 	time.Sleep(10 * time.Millisecond)
 
-	if err := syncTerminalSize(int(os.Stdin.Fd()), int(target.ptyMaster.Fd())); err != nil {
+	if err := s.syncPrismSize(target.name, target.ptyMaster); err != nil {
 		log.Printf("Warning: failed to sync terminal size: %v", err)
 	}
 
 	s.prismList = append(s.prismList[:targetIdx], s.prismList[targetIdx+1:]...)
 
 	target.state = prismForeground
+	target.attention = false
 	s.prismList = append([]prismInstance{target}, s.prismList...)
 
 	log.Printf("Prism %s brought to foreground", target.name)
 
+	// The saved screen is repainted by swapMirror, so no SIGWINCH redraw is needed
 	if err := s.swapMirror(); err != nil {
 		log.Printf("Warning: failed to swap mirror: %v", err)
 	}
 
-	if err := unix.Kill(target.pid, unix.SIGWINCH); err != nil {
-		log.Printf("Warning: failed to send SIGWINCH for redraw: %v", err)
-	}
-
 	if s.stateManager != nil {
 		s.stateManager.OnForegroundChanged(target.name)
 	}
 
-	if s.notifyMgr != nil && len(s.prismList) > 1 {
-		previousFg := s.prismList[1].name
-		s.notifyMgr.OnForegroundChanged(previousFg, target.name)
+	if s.notifyMgr != nil {
+		s.notifyMgr.OnForegroundChanged(previous, target.name)
 	}
 
 	return nil
 }
 
-func (s *supervisor) killPrism(prismName string) error {
+// background sends the foreground prism to the back of the MRU list and
+// promotes the next prism, so repeated calls rotate through all of them.
+// With no other prism to promote the panel shows the idle placeholder.
+// wasBg is true when the prism was not in the foreground.
+func (s *supervisor) background(prismName string) (wasBg bool, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	targetIdx := s.findPrism(prismName)
 	if targetIdx == -1 {
-		return fmt.Errorf("prism not found: %s", prismName)
+		return false, fmt.Errorf("prism not found: %s", prismName)
 	}
 
-	target := s.prismList[targetIdx]
-	pid := target.pid
+	if targetIdx != 0 || !s.hasForeground() {
+		return true, nil
+	}
+
+	log.Printf("Sending %s to the background", prismName)
 
-	log.Printf("Killing prism %s (PID %d)", prismName, pid)
+	s.suspendForeground()
 
-	// Resume first - suspended processes ignore SIGTERM
-	unix.Kill(pid, unix.SIGCONT)
+	demoted := s.prismList[0]
+	s.prismList = append(s.prismList[1:], demoted)
 
-	if err := unix.Kill(pid, unix.SIGTERM); err != nil {
-		return fmt.Errorf("failed to send SIGTERM: %w", err)
+	if len(s.prismList) > 1 {
+		return false, s.promote(0, demoted.name)
 	}
 
-	// Don't wait - handleChildExit will clean up when SIGCHLD arrives
-	return nil
+	s.showIdle()
+
+	if s.stateManager != nil {
+		s.stateManager.OnForegroundChanged("")
+	}
+
+	if s.notifyMgr != nil {
+		s.notifyMgr.OnForegroundChanged(demoted.name, "")
+	}
+
+	return false, nil
 }
 
-func (s *supervisor) handleChildExit(pid, exitCode int) {
+func (s *supervisor) handleChildExit(pid int, status unix.WaitStatus) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -291,12 +550,32 @@ func (s *supervisor) handleChildExit(pid, exitCode int) {
 	}
 
 	exited := s.prismList[exitedIdx]
+	exitCode := exitStatusCode(status)
 	log.Printf("Child exited: %s (PID %d, code %d)", exited.name, pid, exitCode)
 
+	restart := s.restarts[exited.name]
+	reload := s.reloads[exited.name]
+	delete(s.restarts, exited.name)
+	delete(s.reloads, exited.name)
+	delete(s.health, pid)
+
+	record := newExitRecord(exited, status)
+	s.recordExit(record)
+
+	// shutdown() stops and cleans up every prism itself, closing its PTY
+	// master. A prism reaped meanwhile must not be relaunched, or redraw the
+	// restored terminal.
+	if s.shuttingDown {
+		exited.exit.finish(exitCode)
+		return
+	}
+
 	if err := closePTY(exited.ptyMaster); err != nil {
 		log.Printf("Warning: failed to close PTY master: %v", err)
 	}
 
+	exited.exit.finish(exitCode)
+
 	select {
 	case s.childExitCh <- childExit{pid: pid, exitCode: exitCode}:
 		log.Printf("Sent exit event to childExitCh for PID %d", pid)
@@ -304,29 +583,50 @@ func (s *supervisor) handleChildExit(pid, exitCode int) {
 		log.Printf("WARNING: Failed to send exit event - channel full or no listener for PID %d", pid)
 	}
 
-	if exitedIdx == 0 {
-		if s.mirror != nil {
-			deactivateMirror(s.mirror)
-			s.mirror = nil
-		}
+	wasForeground := exited.state == prismForeground
+	if wasForeground {
+		// Drop input until the next prism is brought to the foreground
+		s.mirror.retarget(nil)
+		s.detachOutput(exited)
 
-		if err := s.termState.resetTerminalState(); err != nil {
+		if err := s.term.Reset(); err != nil {
 			log.Printf("Error resetting terminal state after child exit: %v", err)
 		}
 	}
 
 	s.prismList = append(s.prismList[:exitedIdx], s.prismList[exitedIdx+1:]...)
 
+	if s.layout != nil {
+		s.layout.detach(exited.name)
+	}
+
 	if s.stateManager != nil {
 		s.stateManager.OnPrismStopped(exited.name)
 	}
 
-	if s.notifyMgr != nil {
-		if exitCode == 0 {
-			s.notifyMgr.OnPrismStopped(exited.name, exitCode)
+	// A prism restarted by its liveness probe or a reconfiguration is not
+	// reported as stopped, which could make shined's restart policy start it
+	// a second time
+	if s.notifyMgr != nil && !restart && !reload {
+		if exitCode == 0 || exited.stopRequested {
+			s.notifyMgr.OnPrismStopped(exited.name, exitCode, exited.stopRequested)
 		} else {
-			s.notifyMgr.OnPrismCrashed(exited.name, exitCode, 0)
+			s.notifyMgr.OnPrismCrashed(exited.name, exitCode, int(record.signal))
+		}
+	}
+
+	if restart || reload {
+		if s.relaunch(exited.name, wasForeground, restart) {
+			return
 		}
+		// A failed launch may have suspended the prism in the foreground
+		wasForeground = !s.hasForeground()
+	}
+
+	if len(s.prismList) == 0 && s.keepAlive {
+		log.Printf("Last prism exited, keeping panel alive")
+		s.showIdle()
+		return
 	}
 
 	if len(s.prismList) == 0 {
@@ -336,24 +636,26 @@ func (s *supervisor) handleChildExit(pid, exitCode int) {
 	}
 
 	// Auto-bring next to foreground if foreground exited
-	if exitedIdx == 0 && len(s.prismList) > 0 {
+	if wasForeground && len(s.prismList) > 0 {
 		time.Sleep(10 * time.Millisecond)
 
 		next := s.prismList[0]
 
 		// Resume the suspended background prism
-		if err := unix.Kill(next.pid, unix.SIGCONT); err != nil {
+		if err := signalGroup(next.pid, unix.SIGCONT); err != nil {
 			log.Printf("Warning: failed to SIGCONT %s: %v", next.name, err)
 		}
 
-		if err := syncTerminalSize(int(os.Stdin.Fd()), int(next.ptyMaster.Fd())); err != nil {
+		if err := s.syncPrismSize(next.name, next.ptyMaster); err != nil {
 			log.Printf("Warning: failed to sync terminal size: %v", err)
 		}
 
-		unix.Kill(next.pid, unix.SIGWINCH)
-
 		s.prismList[0].state = prismForeground
 
+		if err := s.activateMirrorToForeground(); err != nil {
+			log.Printf("Warning: failed to start mirror: %v", err)
+		}
+
 		log.Printf("Auto-resumed to foreground: %s (PID %d)", next.name, next.pid)
 	}
 }
@@ -364,10 +666,13 @@ func (s *supervisor) propagateResize() {
 	defer s.mu.Unlock()
 
 	if len(s.prismList) == 0 {
+		if s.keepAlive && s.picker == nil {
+			s.showIdle()
+		}
 		return
 	}
 
-	realWinsize, err := unix.IoctlGetWinsize(int(os.Stdin.Fd()), unix.TIOCGWINSZ)
+	realWinsize, err := s.term.Size()
 	if err != nil {
 		log.Printf("Warning: failed to get Real PTY size: %v", err)
 		return
@@ -375,16 +680,33 @@ func (s *supervisor) propagateResize() {
 
 	log.Printf("Propagating resize to %d prisms: %dx%d", len(s.prismList), realWinsize.Col, realWinsize.Row)
 
+	if s.layout != nil {
+		s.layout.resize(int(realWinsize.Col), int(realWinsize.Row))
+	}
+
 	for _, prism := range s.prismList {
-		if err := unix.IoctlSetWinsize(int(prism.ptyMaster.Fd()), unix.TIOCSWINSZ, realWinsize); err != nil {
+		if err := s.syncPrismSize(prism.name, prism.ptyMaster); err != nil {
 			log.Printf("Warning: failed to sync size to %s (PID %d): %v", prism.name, prism.pid, err)
 			continue
 		}
 
+		cols, rows, err := ptySize(int(prism.ptyMaster.Fd()))
+		if err != nil {
+			log.Printf("Warning: failed to read size of %s (PID %d): %v", prism.name, prism.pid, err)
+			continue
+		}
+		prism.output.resize(cols, rows)
+
 		if err := unix.Kill(prism.pid, unix.SIGWINCH); err != nil {
 			log.Printf("Warning: failed to send SIGWINCH to %s (PID %d): %v", prism.name, prism.pid, err)
 		}
 	}
+
+	s.drawPicker()
+
+	if !s.hasForeground() && s.picker == nil {
+		s.showIdle()
+	}
 }
 
 // shutdown performs graceful shutdown
@@ -398,6 +720,11 @@ func (s *supervisor) shutdown() {
 		return
 	}
 	s.shuttingDown = true
+	defer close(s.shutdownDone)
+
+	// Nothing is launched again from here on
+	clear(s.restarts)
+	clear(s.reloads)
 
 	log.Printf("Supervisor shutdown initiated")
 
@@ -410,35 +737,29 @@ func (s *supervisor) shutdown() {
 		s.mirrorCancel()
 	}
 
-	close(s.shutdownCh)
-
-	// Resume all suspended prisms first - they ignore SIGTERM while suspended
-	for _, prism := range s.prismList {
-		unix.Kill(prism.pid, unix.SIGCONT)
+	if modes, ok := s.layout.close(); ok {
+		s.shownModes = modes
 	}
 
-	for _, prism := range s.prismList {
-		log.Printf("Terminating prism %s (PID %d)", prism.name, prism.pid)
+	close(s.shutdownCh)
 
-		if err := unix.Kill(prism.pid, unix.SIGTERM); err != nil {
-			log.Printf("Warning: failed to send SIGTERM to %s: %v", prism.name, err)
-		}
-	}
+	s.stopAll()
 
-	// Wait briefly for graceful exits (match Kitty's signal delivery timing)
-	time.Sleep(20 * time.Millisecond)
+	// Leave the terminal the way the first prism found it
+	if len(s.prismList) > 0 {
+		s.detachOutput(s.prismList[0])
+	}
+	if _, err := s.term.Write(s.enterModes(vtModes{})); err != nil {
+		log.Printf("Warning: failed to reset terminal modes: %v", err)
+	}
 
 	for _, prism := range s.prismList {
-		if err := unix.Kill(prism.pid, unix.SIGKILL); err == nil {
-			log.Printf("Sent SIGKILL to %s (PID %d)", prism.name, prism.pid)
-		}
-
 		if err := closePTY(prism.ptyMaster); err != nil {
 			log.Printf("Warning: failed to close PTY master for %s: %v", prism.name, err)
 		}
 	}
 
-	if err := s.termState.restoreTerminalState(); err != nil {
+	if err := s.term.Restore(); err != nil {
 		log.Printf("Warning: failed to restore terminal state: %v", err)
 	}
 
@@ -447,6 +768,11 @@ func (s *supervisor) shutdown() {
 	fmt.Println("[ ] Exiting... ")
 }
 
+// waitShutdown returns once a shutdown in progress has completed
+func (s *supervisor) waitShutdown() {
+	<-s.shutdownDone
+}
+
 func (s *supervisor) isShuttingDown() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -458,24 +784,48 @@ func (s *supervisor) activateMirrorToForeground() error {
 		return fmt.Errorf("no prisms to connect to")
 	}
 
+	defer s.updateLifecycle()
+
+	// Every prism is in the background: drop input and show the placeholder
+	if !s.hasForeground() {
+		s.mirror.retarget(nil)
+		if s.picker == nil {
+			s.showIdle()
+		}
+		return nil
+	}
+
 	foreground := s.prismList[0]
-	if foreground.state != prismForeground {
-		return fmt.Errorf("internal error: position [0] not foreground")
+
+	// Real terminal → foreground.ptyMaster
+	if s.mirror == nil {
+		mirror, err := activateMirror(s.mirrorCtx, s.term, foreground.ptyMaster)
+		if err != nil {
+			return fmt.Errorf("failed to start mirror: %w", err)
+		}
+		mirror.setKeys(s.keys)
+		mirror.setFocusHandler(s.setPanelFocus)
+		s.mirror = mirror
+	} else {
+		s.mirror.retarget(foreground.ptyMaster)
 	}
 
-	if s.mirror != nil {
-		deactivateMirror(s.mirror)
-		s.mirror = nil
-		log.Printf("Stopped previous mirror before starting new one")
+	// The picker owns the screen; the foreground is repainted when it closes
+	if s.picker != nil {
+		return nil
 	}
 
-	// os.Stdin (Real PTY slave) ↔ foreground.ptyMaster
-	mirror, err := activateMirror(s.mirrorCtx, os.Stdin, foreground.ptyMaster)
-	if err != nil {
-		return fmt.Errorf("failed to start mirror: %w", err)
+	// In a split layout every pane is already on screen; only focus moves
+	if s.layout != nil {
+		s.layout.focus(foreground.name)
+		log.Printf("Mirror started to focused pane: %s (PID %d)", foreground.name, foreground.pid)
+		return nil
 	}
 
-	s.mirror = mirror
+	// Repaint the saved screen, then forward live output to the real terminal
+	if err := foreground.output.attach(s.term, s.shownModes); err != nil {
+		return fmt.Errorf("failed to repaint %s: %w", foreground.name, err)
+	}
 	log.Printf("Mirror started to foreground prism: %s (PID %d)", foreground.name, foreground.pid)
 
 	return nil
@@ -484,16 +834,8 @@ func (s *supervisor) activateMirrorToForeground() error {
 func (s *supervisor) swapMirror() error {
 	startTime := time.Now()
 
-	if s.mirror != nil {
-		deactivateMirror(s.mirror)
-		s.mirror = nil
-	}
-
-	// Clear screen AFTER stopping old mirror but BEFORE starting new one
-	// This ensures no race between clear and buffered output from background prism
-	// CSI 2 J = clear screen, CSI H = cursor home, CSI 0 m = reset all attributes
-	os.Stdout.WriteString("\x1b[2J\x1b[H\x1b[0m")
-
+	// No clear is needed: attaching the new foreground's output repaints its
+	// saved screen over whatever the previous prism left behind
 	if err := s.activateMirrorToForeground(); err != nil {
 		return err
 	}
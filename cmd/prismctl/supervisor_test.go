@@ -78,8 +78,8 @@ func TestNewSupervisor_Initialization(t *testing.T) {
 		t.Fatal("newSupervisor() returned nil")
 	}
 
-	if sup.termState != termState {
-		t.Error("supervisor.termState not set correctly")
+	if sup.term != termState {
+		t.Error("supervisor.term not set correctly")
 	}
 
 	if sup.prismList == nil {
@@ -2,14 +2,35 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"golang.org/x/sys/unix"
 )
 
+// RealTerminal is the terminal the foreground prism is shown on: the kitty
+// panel's PTY (terminalState), or a client attached over a unix socket when
+// prismctl runs headless (socketTerminal)
+type RealTerminal interface {
+	io.Reader // user input
+	io.Writer // output of the foreground prism, picker and idle screen
+
+	// Size returns the terminal's window size
+	Size() (*unix.Winsize, error)
+	// Reset puts the terminal's line discipline back in canonical mode, see
+	// resetTerminalState. Escape sequence modes are moved between prisms by
+	// supervisor.enterModes instead, see modeTransition.
+	Reset() error
+	// Restore puts the terminal back the way prismctl found it
+	Restore() error
+}
+
+// terminalState is the RealTerminal of a panel: prismctl's stdin and stdout
 type terminalState struct {
 	savedTermios *unix.Termios
 	fd           int
+	in           *os.File
+	out          *os.File
 }
 
 func newTerminalState() (*terminalState, error) {
@@ -23,17 +44,52 @@ func newTerminalState() (*terminalState, error) {
 	return &terminalState{
 		savedTermios: termios,
 		fd:           fd,
+		in:           os.Stdin,
+		out:          os.Stdout,
 	}, nil
 }
 
-// resetTerminalState resets the terminal to canonical mode and clears visual state
+func (ts *terminalState) Read(p []byte) (int, error) {
+	if ts.in == nil {
+		return 0, io.EOF
+	}
+	return ts.in.Read(p)
+}
+
+// Write discards output when there is no stdout, as in tests
+func (ts *terminalState) Write(p []byte) (int, error) {
+	if ts.out == nil {
+		return len(p), nil
+	}
+	return ts.out.Write(p)
+}
+
+func (ts *terminalState) Size() (*unix.Winsize, error) {
+	winsize, err := unix.IoctlGetWinsize(ts.fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get terminal size: %w", err)
+	}
+	return winsize, nil
+}
+
+func (ts *terminalState) Reset() error {
+	return ts.resetTerminalState()
+}
+
+func (ts *terminalState) Restore() error {
+	return ts.restoreTerminalState()
+}
+
+// resetTerminalState resets the terminal to canonical mode
 // This MUST be called after EVERY child exit (clean or crash) to prevent terminal corruption
+// Visual state is not touched here: modeTransition moves the terminal from
+// the modes one prism left behind to exactly those the next one expects.
 // - What does canonical mean? https://www.gnu.org/software/libc/manual/html_node/Canonical-or-Not.html
 // - TCGETS: termios control get settings
 // - TCSETS: termios control set settings (immediate)
 //   - See also TCSETSW and TCSETSF, which respectively wait to apply settings until output drained or input flushed 
 func (ts *terminalState) resetTerminalState() error {
-	// 1. Reset termios to canonical mode
+	// Reset termios to canonical mode
 	termios, err := unix.IoctlGetTermios(ts.fd, unix.TCGETS)
 	if err != nil {
 		return fmt.Errorf("failed to get current terminal attributes: %w", err)
@@ -48,19 +104,6 @@ func (ts *terminalState) resetTerminalState() error {
 		return fmt.Errorf("failed to set terminal attributes: %w", err)
 	}
 
-	// 2. Send visual reset sequences to clear terminal state
-	resetSeq := []byte{
-		0x1b, '[', '0', 'm',                     // SGR reset (colors, bold, etc.)
-		0x1b, '[', '?', '1', '0', '4', '9', 'l', // Exit alt screen
-		0x1b, '[', '?', '2', '5', 'h',           // Show cursor
-		0x1b, '[', '?', '1', '0', '0', '0', 'l', // Disable mouse
-		0x1b, '[', '?', '1', '0', '0', '6', 'l', // Disable SGR mouse
-	}
-
-	if _, err := unix.Write(ts.fd, resetSeq); err != nil {
-		return fmt.Errorf("failed to write reset sequences: %w", err)
-	}
-
 	return nil
 }
 
@@ -0,0 +1,223 @@
+// usage.go samples the CPU and memory used by each prism. A prism is
+// accounted with its whole process tree, so a widget that burns CPU in a
+// helper (a shell loop, a polling command) is charged for it. Samples are
+// taken every usageSampleInterval from /proc/<pid>/stat and
+// /proc/<pid>/status; CPU usage is the share of one core used since the
+// previous sample.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usageSampleInterval = 2 * time.Second
+
+// clockTicks is USER_HZ, the unit of utime and stime in /proc/<pid>/stat.
+// It is 100 on every Linux architecture Go supports.
+const clockTicks = 100
+
+// procRoot is where process information is read from
+var procRoot = "/proc"
+
+// prismUsage is the last usage sample of a prism's process tree
+type prismUsage struct {
+	cpuPercent float64
+	rssBytes   uint64
+	threads    int
+
+	ticks   uint64    // CPU time of the tree at the sample
+	sampled time.Time // zero until the first sample
+}
+
+// procStat is what usage sampling needs from /proc/<pid>/stat
+type procStat struct {
+	ppid    int
+	ticks   uint64 // utime + stime
+	threads int
+}
+
+// parseProcStat parses the contents of /proc/<pid>/stat
+func parseProcStat(data []byte) (procStat, error) {
+	// comm may contain spaces and parentheses; it ends at the last ')'
+	end := bytes.LastIndexByte(data, ')')
+	if end == -1 {
+		return procStat{}, fmt.Errorf("malformed stat")
+	}
+
+	// Fields after comm, starting with state (field 3)
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 18 {
+		return procStat{}, fmt.Errorf("malformed stat: %d fields", len(fields))
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed ppid: %w", err)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed utime: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stime: %w", err)
+	}
+	threads, err := strconv.Atoi(fields[17])
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed num_threads: %w", err)
+	}
+
+	return procStat{ppid: ppid, ticks: utime + stime, threads: threads}, nil
+}
+
+// readRSS returns VmRSS from /proc/<pid>/status. Kernel threads and zombies
+// have none.
+func readRSS(pid int) uint64 {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(line, "VmRSS:")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return 0
+		}
+		kb, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+
+	return 0
+}
+
+// processTable is a snapshot of every process
+type processTable struct {
+	stats    map[int]procStat
+	children map[int][]int
+}
+
+func readProcessTable() (*processTable, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &processTable{
+		stats:    make(map[int]procStat, len(entries)),
+		children: make(map[int][]int),
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		// Processes can exit between ReadDir and here
+		data, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		stat, err := parseProcStat(data)
+		if err != nil {
+			continue
+		}
+
+		table.stats[pid] = stat
+		table.children[stat.ppid] = append(table.children[stat.ppid], pid)
+	}
+
+	return table, nil
+}
+
+// tree returns the usage of pid and all of its descendants, with CPU usage
+// left for the caller to work out from ticks
+func (t *processTable) tree(pid int) prismUsage {
+	var usage prismUsage
+
+	pending := []int{pid}
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		stat, ok := t.stats[current]
+		if !ok {
+			continue
+		}
+
+		usage.ticks += stat.ticks
+		usage.threads += stat.threads
+		usage.rssBytes += readRSS(current)
+
+		pending = append(pending, t.children[current]...)
+	}
+
+	return usage
+}
+
+// sampleUsage takes a usage sample of every prism
+func (s *supervisor) sampleUsage() {
+	table, err := readProcessTable()
+	if err != nil {
+		log.Printf("Warning: failed to read process table: %v", err)
+		return
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := make(map[int]prismUsage, len(s.prismList))
+	for _, prism := range s.prismList {
+		sample := table.tree(prism.pid)
+		sample.sampled = now
+
+		if previous, ok := s.usage[prism.pid]; ok {
+			elapsed := now.Sub(previous.sampled).Seconds()
+			// The tree loses the CPU time of helpers that exited
+			if elapsed > 0 && sample.ticks > previous.ticks {
+				sample.cpuPercent = float64(sample.ticks-previous.ticks) / clockTicks / elapsed * 100
+			}
+		}
+
+		usage[prism.pid] = sample
+
+		if s.stateManager != nil {
+			s.stateManager.OnUsageSampled(prism.name, sample)
+		}
+	}
+
+	// Prisms that exited are dropped along with their samples
+	s.usage = usage
+}
+
+// sampleUsageEvery samples usage until shutdown
+func (s *supervisor) sampleUsageEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.sampleUsage()
+
+		select {
+		case <-ticker.C:
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// comm may itself contain spaces and parentheses
+	data := []byte("4242 (my (odd) prism) S 4200 4242 4242 0 -1 4194560 1021 0 0 0 37 12 0 0 20 0 3 0 123456 10485760 512 18446744073709551615")
+
+	stat, err := parseProcStat(data)
+	if err != nil {
+		t.Fatalf("parseProcStat() error: %v", err)
+	}
+	if stat.ppid != 4200 {
+		t.Errorf("ppid = %d, want 4200", stat.ppid)
+	}
+	if stat.ticks != 49 {
+		t.Errorf("ticks = %d, want 49", stat.ticks)
+	}
+	if stat.threads != 3 {
+		t.Errorf("threads = %d, want 3", stat.threads)
+	}
+
+	if _, err := parseProcStat([]byte("4242 (truncated) S 1")); err == nil {
+		t.Error("parseProcStat() accepted a truncated stat")
+	}
+}
+
+// writeFakeProc writes /proc/<pid>/stat and status under root for a
+// process with the given parent, CPU ticks, threads and RSS
+func writeFakeProc(t *testing.T, root string, pid, ppid, ticks, threads, rssKB int) {
+	t.Helper()
+
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stat := fmt.Sprintf("%d (sh) S %d %d %d 0 -1 4194560 0 0 0 0 %d 0 0 0 20 0 %d 0 1 0 0", pid, ppid, pid, pid, ticks, threads)
+	status := fmt.Sprintf("Name:\tsh\nVmRSS:\t%d kB\n", rssKB)
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "status"), []byte(status), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSupervisor_SampleUsageCountsProcessTree(t *testing.T) {
+	root := t.TempDir()
+	defer func(saved string) { procRoot = saved }(procRoot)
+	procRoot = root
+
+	sup := newSupervisor(&terminalState{fd: -1}, nil, nil)
+	sup.prismList = append(sup.prismList, prismInstance{name: "busy", pid: 100})
+
+	// The prism idles while its helper burns CPU; 300 is someone else's
+	writeFakeProc(t, root, 100, 1, 10, 1, 1024)
+	writeFakeProc(t, root, 101, 100, 5, 2, 2048)
+	writeFakeProc(t, root, 300, 1, 0, 1, 4096)
+	sup.sampleUsage()
+
+	// Two seconds later the helper has used 150 ticks, 75% of a CPU
+	sup.mu.Lock()
+	first := sup.usage[100]
+	first.sampled = first.sampled.Add(-2 * time.Second)
+	sup.usage[100] = first
+	sup.mu.Unlock()
+
+	writeFakeProc(t, root, 101, 100, 155, 2, 2048)
+	sup.sampleUsage()
+
+	sup.mu.Lock()
+	usage, ok := sup.usage[100]
+	sup.mu.Unlock()
+
+	if !ok {
+		t.Fatal("no usage sample for prism")
+	}
+	if usage.ticks != 165 || usage.threads != 3 {
+		t.Errorf("ticks, threads = %d, %d, want 165, 3 for the prism and its helper", usage.ticks, usage.threads)
+	}
+	if usage.rssBytes != 3072*1024 {
+		t.Errorf("rssBytes = %d, want %d", usage.rssBytes, 3072*1024)
+	}
+	// A little more than two seconds pass in between
+	if usage.cpuPercent > 75 || usage.cpuPercent < 70 {
+		t.Errorf("cpuPercent = %.1f, want about 75", usage.cpuPercent)
+	}
+
+	// A helper that exited takes its CPU time with it, which is not counted
+	// as negative usage
+	os.RemoveAll(filepath.Join(root, "101"))
+	sup.sampleUsage()
+	sup.mu.Lock()
+	usage = sup.usage[100]
+	sup.mu.Unlock()
+	if usage.cpuPercent != 0 {
+		t.Errorf("cpuPercent after the helper exited = %.1f, want 0", usage.cpuPercent)
+	}
+}
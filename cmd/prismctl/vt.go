@@ -0,0 +1,1256 @@
+// vt.go implements a headless VT screen model for prism output. Every child
+// PTY is parsed into a vtScreen so prismctl always knows what a prism last
+// drew, including prisms that are running in the background, and can repaint
+// it onto the real PTY the moment the prism returns to the foreground.
+//
+// The model covers what TUI prisms actually use: printable text (including
+// wide characters), cursor movement, erase/insert/delete, scroll regions, SGR
+// attributes, the alternate screen, the DEC private modes that affect input
+// and rendering, the cursor shape and the kitty keyboard protocol flags. OSC,
+// DCS, APC, PM and SOS strings are consumed and ignored, apart from the
+// window title.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+type vtColorKind uint8
+
+const (
+	colorDefault vtColorKind = iota
+	colorIndexed
+	colorRGB
+)
+
+type vtColor struct {
+	kind  vtColorKind
+	value uint32 // palette index or 0xRRGGBB
+}
+
+const (
+	attrBold uint16 = 1 << iota
+	attrDim
+	attrItalic
+	attrUnderline
+	attrBlink
+	attrReverse
+	attrHidden
+	attrStrike
+)
+
+type vtAttr struct {
+	fg    vtColor
+	bg    vtColor
+	flags uint16
+}
+
+type vtCell struct {
+	r    rune   // 0 marks the right half of a wide character
+	comb string // combining marks attached to r
+	attr vtAttr
+}
+
+// vtModes tracks the terminal modes a prism has switched away from their
+// defaults. The zero value is a freshly reset terminal.
+type vtModes struct {
+	altScreen      bool // ?1049 / ?1047 / ?47
+	cursorHidden   bool // ?25 reset
+	appCursorKeys  bool // ?1
+	appKeypad      bool // ESC =
+	autowrapOff    bool // ?7 reset
+	originMode     bool // ?6
+	insertMode     bool // SM 4
+	bracketedPaste bool // ?2004
+	focusEvents    bool // ?1004
+	mouseTracking  int  // 0, 1000, 1002 or 1003
+	mouseSGR       bool // ?1006
+	cursorShape    int  // DECSCUSR style, 0 = the terminal's default
+
+	// Kitty keyboard protocol flags; each screen has its own stack
+	keyboard    keyboardStack // main screen
+	altKeyboard keyboardStack // alternate screen
+}
+
+// activeKeyboard returns the keyboard flag stack of the screen in use
+func (m *vtModes) activeKeyboard() *keyboardStack {
+	if m.altScreen {
+		return &m.altKeyboard
+	}
+	return &m.keyboard
+}
+
+type vtCursor struct {
+	x, y   int
+	pen    vtAttr
+	origin bool
+}
+
+type vtParseState int
+
+const (
+	stateGround vtParseState = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateOSC
+	stateString
+	stateStringEscape
+)
+
+type vtScreen struct {
+	cols, rows int
+	main       [][]vtCell
+	alt        [][]vtCell
+
+	cursor      vtCursor
+	saved       vtCursor
+	wrapPending bool
+	top, bottom int // scroll region, inclusive
+	modes       vtModes
+	title       string
+
+	state   vtParseState
+	params  []byte
+	inter   []byte
+	oscBuf  []byte
+	utf8Buf []byte
+}
+
+func newVTScreen(cols, rows int) *vtScreen {
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	s := &vtScreen{
+		cols: cols,
+		rows: rows,
+	}
+	s.reset()
+	return s
+}
+
+// reset performs a full terminal reset (RIS)
+func (s *vtScreen) reset() {
+	s.main = newVTGrid(s.cols, s.rows)
+	s.alt = newVTGrid(s.cols, s.rows)
+	s.cursor = vtCursor{}
+	s.saved = vtCursor{}
+	s.wrapPending = false
+	s.top = 0
+	s.bottom = s.rows - 1
+	s.modes = vtModes{}
+	s.title = ""
+	s.state = stateGround
+}
+
+func newVTGrid(cols, rows int) [][]vtCell {
+	grid := make([][]vtCell, rows)
+	for y := range grid {
+		grid[y] = newVTLine(cols, vtAttr{})
+	}
+	return grid
+}
+
+func newVTLine(cols int, attr vtAttr) []vtCell {
+	line := make([]vtCell, cols)
+	for x := range line {
+		line[x] = blankCell(attr)
+	}
+	return line
+}
+
+// blankCell returns an erased cell. Erasures keep the background of the
+// current pen (background color erase) but drop every other attribute.
+func blankCell(pen vtAttr) vtCell {
+	return vtCell{r: ' ', attr: vtAttr{bg: pen.bg}}
+}
+
+func (s *vtScreen) grid() [][]vtCell {
+	if s.modes.altScreen {
+		return s.alt
+	}
+	return s.main
+}
+
+// resize changes the screen dimensions without reflowing content. When the
+// screen shrinks below the cursor, lines are dropped from the top so the
+// cursor row stays visible, matching xterm.
+func (s *vtScreen) resize(cols, rows int) {
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	if cols == s.cols && rows == s.rows {
+		return
+	}
+
+	drop := 0
+	if s.cursor.y >= rows {
+		drop = s.cursor.y - rows + 1
+	}
+
+	s.main = resizeVTGrid(s.main, cols, rows, drop)
+	s.alt = resizeVTGrid(s.alt, cols, rows, drop)
+	s.cols = cols
+	s.rows = rows
+	s.top = 0
+	s.bottom = rows - 1
+	s.cursor.y -= drop
+	s.wrapPending = false
+	s.clampCursor()
+}
+
+func resizeVTGrid(grid [][]vtCell, cols, rows, drop int) [][]vtCell {
+	if drop > 0 {
+		grid = grid[drop:]
+	}
+	resized := make([][]vtCell, rows)
+	for y := range resized {
+		line := newVTLine(cols, vtAttr{})
+		if y < len(grid) {
+			copy(line, grid[y])
+			// A wide character cut in half by the new width becomes a blank
+			if cols > 0 && line[cols-1].r != 0 && runewidth.RuneWidth(line[cols-1].r) == 2 {
+				line[cols-1] = blankCell(vtAttr{})
+			}
+		}
+		resized[y] = line
+	}
+	return resized
+}
+
+// Write feeds raw PTY output into the screen model. It never fails; the
+// signature satisfies io.Writer so the model can sit behind io.Copy/MultiWriter.
+func (s *vtScreen) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.feed(b)
+	}
+	return len(p), nil
+}
+
+func (s *vtScreen) feed(b byte) {
+	// C0 controls are executed in every state except inside strings
+	if b < 0x20 && s.state != stateOSC && s.state != stateString && s.state != stateStringEscape {
+		switch b {
+		case 0x1b:
+			s.utf8Buf = s.utf8Buf[:0]
+			s.state = stateEscape
+			s.inter = s.inter[:0]
+		case 0x18, 0x1a: // CAN, SUB abort sequences
+			s.state = stateGround
+		default:
+			s.execute(b)
+		}
+		return
+	}
+
+	switch s.state {
+	case stateGround:
+		s.ground(b)
+
+	case stateEscape, stateEscapeIntermediate:
+		switch {
+		case b >= 0x20 && b <= 0x2f:
+			s.inter = append(s.inter, b)
+			s.state = stateEscapeIntermediate
+		case s.state == stateEscape && b == '[':
+			s.params = s.params[:0]
+			s.inter = s.inter[:0]
+			s.state = stateCSI
+		case s.state == stateEscape && b == ']':
+			s.oscBuf = s.oscBuf[:0]
+			s.state = stateOSC
+		case s.state == stateEscape && (b == 'P' || b == 'X' || b == '^' || b == '_'):
+			s.state = stateString
+		default:
+			s.escDispatch(b)
+			s.state = stateGround
+		}
+
+	case stateCSI:
+		switch {
+		case b >= 0x30 && b <= 0x3f:
+			s.params = append(s.params, b)
+		case b >= 0x20 && b <= 0x2f:
+			s.inter = append(s.inter, b)
+		case b >= 0x40 && b <= 0x7e:
+			s.csiDispatch(b)
+			s.state = stateGround
+		default:
+			s.state = stateGround
+		}
+
+	case stateOSC:
+		switch b {
+		case 0x07:
+			s.oscDispatch()
+			s.state = stateGround
+		case 0x1b:
+			s.state = stateStringEscape
+		default:
+			if len(s.oscBuf) < 4096 {
+				s.oscBuf = append(s.oscBuf, b)
+			}
+		}
+
+	case stateString:
+		if b == 0x1b {
+			s.state = stateStringEscape
+		} else if b == 0x07 {
+			s.state = stateGround
+		}
+
+	case stateStringEscape:
+		// ESC \ (ST) terminates the string; anything else starts a new escape
+		s.oscDispatch()
+		if b == '\\' {
+			s.state = stateGround
+		} else {
+			s.state = stateEscape
+			s.inter = s.inter[:0]
+			s.feed(b)
+		}
+	}
+}
+
+func (s *vtScreen) ground(b byte) {
+	if b == 0x7f {
+		return
+	}
+	if b < utf8.RuneSelf && len(s.utf8Buf) == 0 {
+		s.print(rune(b))
+		return
+	}
+
+	s.utf8Buf = append(s.utf8Buf, b)
+	if !utf8.FullRune(s.utf8Buf) {
+		return
+	}
+	r, _ := utf8.DecodeRune(s.utf8Buf)
+	s.utf8Buf = s.utf8Buf[:0]
+	s.print(r)
+}
+
+func (s *vtScreen) execute(b byte) {
+	switch b {
+	case '\b':
+		s.wrapPending = false
+		if s.cursor.x > 0 {
+			s.cursor.x--
+		}
+	case '\t':
+		s.wrapPending = false
+		next := (s.cursor.x/8 + 1) * 8
+		if next >= s.cols {
+			next = s.cols - 1
+		}
+		s.cursor.x = next
+	case '\n', '\v', '\f':
+		s.lineFeed()
+	case '\r':
+		s.wrapPending = false
+		s.cursor.x = 0
+	}
+}
+
+func (s *vtScreen) print(r rune) {
+	width := runewidth.RuneWidth(r)
+	if width == 0 {
+		s.combine(r)
+		return
+	}
+
+	if s.wrapPending {
+		s.wrapPending = false
+		if !s.modes.autowrapOff {
+			s.cursor.x = 0
+			s.lineFeed()
+		}
+	}
+
+	if s.cursor.x+width > s.cols {
+		if width > s.cols {
+			return
+		}
+		if s.modes.autowrapOff {
+			s.cursor.x = s.cols - width
+		} else {
+			s.cursor.x = 0
+			s.lineFeed()
+		}
+	}
+
+	line := s.grid()[s.cursor.y]
+	if s.modes.insertMode {
+		copy(line[s.cursor.x+width:], line[s.cursor.x:])
+	}
+
+	s.clearWideAt(line, s.cursor.x)
+	if width == 2 {
+		s.clearWideAt(line, s.cursor.x+1)
+	}
+
+	line[s.cursor.x] = vtCell{r: r, attr: s.cursor.pen}
+	if width == 2 {
+		line[s.cursor.x+1] = vtCell{r: 0, attr: s.cursor.pen}
+	}
+
+	s.cursor.x += width
+	if s.cursor.x >= s.cols {
+		s.cursor.x = s.cols - 1
+		s.wrapPending = !s.modes.autowrapOff
+	}
+}
+
+// combine attaches a zero-width rune to the most recently printed cell
+func (s *vtScreen) combine(r rune) {
+	x := s.cursor.x
+	if !s.wrapPending {
+		x--
+	}
+	if x < 0 {
+		return
+	}
+	line := s.grid()[s.cursor.y]
+	if line[x].r == 0 && x > 0 {
+		x--
+	}
+	line[x].comb += string(r)
+}
+
+// clearWideAt blanks the other half of a wide character before cell x is
+// overwritten, so a half-character is never left behind.
+func (s *vtScreen) clearWideAt(line []vtCell, x int) {
+	if x < 0 || x >= len(line) {
+		return
+	}
+	if line[x].r == 0 && x > 0 {
+		line[x-1] = blankCell(line[x-1].attr)
+	}
+	if line[x].r != 0 && runewidth.RuneWidth(line[x].r) == 2 && x+1 < len(line) {
+		line[x+1] = blankCell(line[x+1].attr)
+	}
+}
+
+func (s *vtScreen) lineFeed() {
+	s.wrapPending = false
+	if s.cursor.y == s.bottom {
+		s.scrollUp(1)
+	} else if s.cursor.y < s.rows-1 {
+		s.cursor.y++
+	}
+}
+
+func (s *vtScreen) reverseIndex() {
+	s.wrapPending = false
+	if s.cursor.y == s.top {
+		s.scrollDown(1)
+	} else if s.cursor.y > 0 {
+		s.cursor.y--
+	}
+}
+
+func (s *vtScreen) scrollUp(n int) {
+	s.scrollRegionUp(s.top, s.bottom, n)
+}
+
+func (s *vtScreen) scrollDown(n int) {
+	s.scrollRegionDown(s.top, s.bottom, n)
+}
+
+func (s *vtScreen) scrollRegionUp(top, bottom, n int) {
+	grid := s.grid()
+	if n > bottom-top+1 {
+		n = bottom - top + 1
+	}
+	copy(grid[top:bottom+1], grid[top+n:bottom+1])
+	for y := bottom - n + 1; y <= bottom; y++ {
+		grid[y] = newVTLine(s.cols, s.cursor.pen)
+	}
+}
+
+func (s *vtScreen) scrollRegionDown(top, bottom, n int) {
+	grid := s.grid()
+	if n > bottom-top+1 {
+		n = bottom - top + 1
+	}
+	copy(grid[top+n:bottom+1], grid[top:bottom+1-n])
+	for y := top; y < top+n; y++ {
+		grid[y] = newVTLine(s.cols, s.cursor.pen)
+	}
+}
+
+func (s *vtScreen) escDispatch(b byte) {
+	if len(s.inter) > 0 {
+		// Charset designations (ESC ( B etc.) and DECALN are not modelled
+		if s.inter[0] == '#' && b == '8' {
+			for _, line := range s.grid() {
+				for x := range line {
+					line[x] = vtCell{r: 'E'}
+				}
+			}
+		}
+		return
+	}
+
+	switch b {
+	case '7':
+		s.saveCursor()
+	case '8':
+		s.restoreCursor()
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.cursor.x = 0
+		s.lineFeed()
+	case 'M':
+		s.reverseIndex()
+	case 'c':
+		s.reset()
+	case '=':
+		s.modes.appKeypad = true
+	case '>':
+		s.modes.appKeypad = false
+	}
+}
+
+func (s *vtScreen) saveCursor() {
+	s.saved = s.cursor
+	s.saved.origin = s.modes.originMode
+}
+
+func (s *vtScreen) restoreCursor() {
+	s.cursor = s.saved
+	s.modes.originMode = s.saved.origin
+	s.wrapPending = false
+	s.clampCursor()
+}
+
+func (s *vtScreen) oscDispatch() {
+	data := string(s.oscBuf)
+	s.oscBuf = s.oscBuf[:0]
+
+	cmd, arg, ok := strings.Cut(data, ";")
+	if !ok {
+		return
+	}
+	if cmd == "0" || cmd == "2" {
+		s.title = arg
+	}
+}
+
+// csiParams splits the parameter string into numeric parameters. Colon
+// separated sub-parameters are returned in subs, indexed like params.
+func (s *vtScreen) csiParams() (params []int, subs [][]int) {
+	raw := string(s.params)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, field := range strings.Split(raw, ";") {
+		parts := strings.Split(field, ":")
+		n, _ := strconv.Atoi(parts[0])
+		params = append(params, n)
+		var sub []int
+		for _, p := range parts[1:] {
+			v, _ := strconv.Atoi(p)
+			sub = append(sub, v)
+		}
+		subs = append(subs, sub)
+	}
+	return params, subs
+}
+
+func param(params []int, i, def int) int {
+	if i >= len(params) || params[i] == 0 {
+		return def
+	}
+	return params[i]
+}
+
+func (s *vtScreen) csiDispatch(final byte) {
+	private := byte(0)
+	if len(s.params) > 0 && s.params[0] >= '<' && s.params[0] <= '?' {
+		private = s.params[0]
+		s.params = s.params[1:]
+	}
+	params, subs := s.csiParams()
+
+	if len(s.inter) > 0 {
+		switch {
+		case s.inter[0] == '!' && final == 'p':
+			s.softReset()
+		case s.inter[0] == ' ' && final == 'q' && private == 0:
+			s.modes.cursorShape = param(params, 0, 0)
+		}
+		return
+	}
+
+	if final == 'u' && private != 0 {
+		s.keyboardProtocol(private, params)
+		return
+	}
+
+	if private == '?' {
+		switch final {
+		case 'h':
+			s.setPrivateModes(params, true)
+		case 'l':
+			s.setPrivateModes(params, false)
+		}
+		return
+	}
+	if private != 0 {
+		return
+	}
+
+	switch final {
+	case 'A':
+		s.moveCursor(s.cursor.x, s.cursor.y-param(params, 0, 1))
+	case 'B', 'e':
+		s.moveCursor(s.cursor.x, s.cursor.y+param(params, 0, 1))
+	case 'C', 'a':
+		s.moveCursor(s.cursor.x+param(params, 0, 1), s.cursor.y)
+	case 'D':
+		s.moveCursor(s.cursor.x-param(params, 0, 1), s.cursor.y)
+	case 'E':
+		s.moveCursor(0, s.cursor.y+param(params, 0, 1))
+	case 'F':
+		s.moveCursor(0, s.cursor.y-param(params, 0, 1))
+	case 'G', '`':
+		s.moveCursor(param(params, 0, 1)-1, s.cursor.y)
+	case 'H', 'f':
+		s.cursorPosition(param(params, 0, 1)-1, param(params, 1, 1)-1)
+	case 'd':
+		s.cursorPosition(param(params, 0, 1)-1, s.cursor.x)
+	case 'J':
+		s.eraseDisplay(param(params, 0, 0))
+	case 'K':
+		s.eraseLine(param(params, 0, 0))
+	case '@':
+		s.insertChars(param(params, 0, 1))
+	case 'P':
+		s.deleteChars(param(params, 0, 1))
+	case 'X':
+		s.eraseChars(param(params, 0, 1))
+	case 'L':
+		s.insertLines(param(params, 0, 1))
+	case 'M':
+		s.deleteLines(param(params, 0, 1))
+	case 'S':
+		s.scrollUp(param(params, 0, 1))
+	case 'T':
+		s.scrollDown(param(params, 0, 1))
+	case 'm':
+		s.sgr(params, subs)
+	case 'r':
+		s.setScrollRegion(param(params, 0, 1)-1, param(params, 1, s.rows)-1)
+	case 's':
+		s.saveCursor()
+	case 'u':
+		s.restoreCursor()
+	case 'h':
+		for _, p := range params {
+			if p == 4 {
+				s.modes.insertMode = true
+			}
+		}
+	case 'l':
+		for _, p := range params {
+			if p == 4 {
+				s.modes.insertMode = false
+			}
+		}
+	}
+}
+
+func (s *vtScreen) setPrivateModes(params []int, on bool) {
+	for _, p := range params {
+		switch p {
+		case 1:
+			s.modes.appCursorKeys = on
+		case 6:
+			s.modes.originMode = on
+			s.cursorPosition(0, 0)
+		case 7:
+			s.modes.autowrapOff = !on
+		case 25:
+			s.modes.cursorHidden = !on
+		case 47, 1047:
+			s.switchScreen(on, false)
+		case 1049:
+			s.switchScreen(on, true)
+		case 1000, 1002, 1003:
+			if on {
+				s.modes.mouseTracking = p
+			} else if s.modes.mouseTracking == p {
+				s.modes.mouseTracking = 0
+			}
+		case 1004:
+			s.modes.focusEvents = on
+		case 1006:
+			s.modes.mouseSGR = on
+		case 2004:
+			s.modes.bracketedPaste = on
+		}
+	}
+}
+
+// keyboardProtocol handles the kitty keyboard protocol's flag stack
+// sequences: CSI > flags u pushes, CSI < n u pops and CSI = flags ; mode u
+// changes the current flags. CSI ? u is a query and changes nothing.
+func (s *vtScreen) keyboardProtocol(private byte, params []int) {
+	stack := s.modes.activeKeyboard()
+	raw := func(i int) int {
+		if i < len(params) {
+			return params[i]
+		}
+		return 0
+	}
+
+	switch private {
+	case '>':
+		stack.push(raw(0))
+	case '<':
+		stack.pop(param(params, 0, 1))
+	case '=':
+		stack.set(raw(0), param(params, 1, 1))
+	}
+}
+
+func (s *vtScreen) switchScreen(alt, saveCursor bool) {
+	if alt == s.modes.altScreen {
+		return
+	}
+	if alt {
+		if saveCursor {
+			s.saveCursor()
+		}
+		s.modes.altScreen = true
+		s.alt = newVTGrid(s.cols, s.rows)
+	} else {
+		s.modes.altScreen = false
+		if saveCursor {
+			s.restoreCursor()
+		}
+	}
+	s.wrapPending = false
+}
+
+func (s *vtScreen) softReset() {
+	s.modes.cursorHidden = false
+	s.modes.insertMode = false
+	s.modes.originMode = false
+	s.modes.autowrapOff = false
+	s.modes.appCursorKeys = false
+	s.modes.appKeypad = false
+	s.cursor.pen = vtAttr{}
+	s.top = 0
+	s.bottom = s.rows - 1
+	s.saved = vtCursor{}
+}
+
+func (s *vtScreen) clampCursor() {
+	if s.cursor.x >= s.cols {
+		s.cursor.x = s.cols - 1
+	}
+	if s.cursor.x < 0 {
+		s.cursor.x = 0
+	}
+	if s.cursor.y >= s.rows {
+		s.cursor.y = s.rows - 1
+	}
+	if s.cursor.y < 0 {
+		s.cursor.y = 0
+	}
+}
+
+// moveCursor performs relative movement, which stops at the scroll region
+// margins when the cursor starts inside the region.
+func (s *vtScreen) moveCursor(x, y int) {
+	s.wrapPending = false
+	top, bottom := 0, s.rows-1
+	if s.cursor.y >= s.top && s.cursor.y <= s.bottom {
+		top, bottom = s.top, s.bottom
+	}
+	if y < top {
+		y = top
+	}
+	if y > bottom {
+		y = bottom
+	}
+	s.cursor.x = x
+	s.cursor.y = y
+	s.clampCursor()
+}
+
+// cursorPosition performs absolute positioning, honouring origin mode
+func (s *vtScreen) cursorPosition(row, col int) {
+	s.wrapPending = false
+	if s.modes.originMode {
+		row += s.top
+		if row > s.bottom {
+			row = s.bottom
+		}
+	}
+	s.cursor.x = col
+	s.cursor.y = row
+	s.clampCursor()
+}
+
+func (s *vtScreen) setScrollRegion(top, bottom int) {
+	if bottom >= s.rows {
+		bottom = s.rows - 1
+	}
+	if top < 0 || top >= bottom {
+		return
+	}
+	s.top = top
+	s.bottom = bottom
+	s.cursorPosition(0, 0)
+}
+
+func (s *vtScreen) eraseDisplay(mode int) {
+	grid := s.grid()
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		for y := s.cursor.y + 1; y < s.rows; y++ {
+			grid[y] = newVTLine(s.cols, s.cursor.pen)
+		}
+	case 1:
+		s.eraseLine(1)
+		for y := 0; y < s.cursor.y; y++ {
+			grid[y] = newVTLine(s.cols, s.cursor.pen)
+		}
+	case 2, 3:
+		for y := range grid {
+			grid[y] = newVTLine(s.cols, s.cursor.pen)
+		}
+	}
+	s.wrapPending = false
+}
+
+func (s *vtScreen) eraseLine(mode int) {
+	line := s.grid()[s.cursor.y]
+	from, to := 0, s.cols
+	switch mode {
+	case 0:
+		from = s.cursor.x
+	case 1:
+		to = s.cursor.x + 1
+	}
+	s.clearWideAt(line, from)
+	s.clearWideAt(line, to-1)
+	for x := from; x < to; x++ {
+		line[x] = blankCell(s.cursor.pen)
+	}
+	s.wrapPending = false
+}
+
+func (s *vtScreen) eraseChars(n int) {
+	line := s.grid()[s.cursor.y]
+	end := s.cursor.x + n
+	if end > s.cols {
+		end = s.cols
+	}
+	s.clearWideAt(line, s.cursor.x)
+	s.clearWideAt(line, end-1)
+	for x := s.cursor.x; x < end; x++ {
+		line[x] = blankCell(s.cursor.pen)
+	}
+	s.wrapPending = false
+}
+
+func (s *vtScreen) insertChars(n int) {
+	line := s.grid()[s.cursor.y]
+	if n > s.cols-s.cursor.x {
+		n = s.cols - s.cursor.x
+	}
+	s.clearWideAt(line, s.cursor.x)
+	copy(line[s.cursor.x+n:], line[s.cursor.x:])
+	for x := s.cursor.x; x < s.cursor.x+n; x++ {
+		line[x] = blankCell(s.cursor.pen)
+	}
+	s.wrapPending = false
+}
+
+func (s *vtScreen) deleteChars(n int) {
+	line := s.grid()[s.cursor.y]
+	if n > s.cols-s.cursor.x {
+		n = s.cols - s.cursor.x
+	}
+	s.clearWideAt(line, s.cursor.x)
+	s.clearWideAt(line, s.cursor.x+n-1)
+	copy(line[s.cursor.x:], line[s.cursor.x+n:])
+	for x := s.cols - n; x < s.cols; x++ {
+		line[x] = blankCell(s.cursor.pen)
+	}
+	s.wrapPending = false
+}
+
+func (s *vtScreen) insertLines(n int) {
+	if s.cursor.y < s.top || s.cursor.y > s.bottom {
+		return
+	}
+	s.scrollRegionDown(s.cursor.y, s.bottom, n)
+	s.cursor.x = 0
+	s.wrapPending = false
+}
+
+func (s *vtScreen) deleteLines(n int) {
+	if s.cursor.y < s.top || s.cursor.y > s.bottom {
+		return
+	}
+	s.scrollRegionUp(s.cursor.y, s.bottom, n)
+	s.cursor.x = 0
+	s.wrapPending = false
+}
+
+func (s *vtScreen) sgr(params []int, subs [][]int) {
+	if len(params) == 0 {
+		s.cursor.pen = vtAttr{}
+		return
+	}
+
+	pen := &s.cursor.pen
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			*pen = vtAttr{}
+		case p == 1:
+			pen.flags |= attrBold
+		case p == 2:
+			pen.flags |= attrDim
+		case p == 3:
+			pen.flags |= attrItalic
+		case p == 4:
+			if len(subs[i]) > 0 && subs[i][0] == 0 {
+				pen.flags &^= attrUnderline
+			} else {
+				pen.flags |= attrUnderline
+			}
+		case p == 5 || p == 6:
+			pen.flags |= attrBlink
+		case p == 7:
+			pen.flags |= attrReverse
+		case p == 8:
+			pen.flags |= attrHidden
+		case p == 9:
+			pen.flags |= attrStrike
+		case p == 21:
+			pen.flags |= attrUnderline
+		case p == 22:
+			pen.flags &^= attrBold | attrDim
+		case p == 23:
+			pen.flags &^= attrItalic
+		case p == 24:
+			pen.flags &^= attrUnderline
+		case p == 25:
+			pen.flags &^= attrBlink
+		case p == 27:
+			pen.flags &^= attrReverse
+		case p == 28:
+			pen.flags &^= attrHidden
+		case p == 29:
+			pen.flags &^= attrStrike
+		case p >= 30 && p <= 37:
+			pen.fg = vtColor{kind: colorIndexed, value: uint32(p - 30)}
+		case p == 38:
+			var c vtColor
+			c, i = extendedColor(params, subs, i)
+			pen.fg = c
+		case p == 39:
+			pen.fg = vtColor{}
+		case p >= 40 && p <= 47:
+			pen.bg = vtColor{kind: colorIndexed, value: uint32(p - 40)}
+		case p == 48:
+			var c vtColor
+			c, i = extendedColor(params, subs, i)
+			pen.bg = c
+		case p == 49:
+			pen.bg = vtColor{}
+		case p >= 90 && p <= 97:
+			pen.fg = vtColor{kind: colorIndexed, value: uint32(p - 90 + 8)}
+		case p >= 100 && p <= 107:
+			pen.bg = vtColor{kind: colorIndexed, value: uint32(p - 100 + 8)}
+		}
+	}
+}
+
+// extendedColor decodes 38/48 color selections in both the semicolon form
+// (38;5;n, 38;2;r;g;b) and the colon form (38:5:n, 38:2::r:g:b). It returns
+// the index of the last parameter consumed.
+func extendedColor(params []int, subs [][]int, i int) (vtColor, int) {
+	if sub := subs[i]; len(sub) > 0 {
+		switch sub[0] {
+		case 5:
+			if len(sub) >= 2 {
+				return vtColor{kind: colorIndexed, value: uint32(sub[1] & 0xff)}, i
+			}
+		case 2:
+			rgb := sub[1:]
+			if len(rgb) == 4 {
+				rgb = rgb[1:] // skip colorspace id
+			}
+			if len(rgb) >= 3 {
+				return rgbColor(rgb[0], rgb[1], rgb[2]), i
+			}
+		}
+		return vtColor{}, i
+	}
+
+	if i+1 >= len(params) {
+		return vtColor{}, i
+	}
+	switch params[i+1] {
+	case 5:
+		if i+2 < len(params) {
+			return vtColor{kind: colorIndexed, value: uint32(params[i+2] & 0xff)}, i + 2
+		}
+	case 2:
+		if i+4 < len(params) {
+			return rgbColor(params[i+2], params[i+3], params[i+4]), i + 4
+		}
+	}
+	return vtColor{}, len(params)
+}
+
+func rgbColor(r, g, b int) vtColor {
+	return vtColor{kind: colorRGB, value: uint32(r&0xff)<<16 | uint32(g&0xff)<<8 | uint32(b&0xff)}
+}
+
+// sgrSequence returns the escape sequence that selects attr from a reset pen
+func sgrSequence(attr vtAttr) string {
+	var b strings.Builder
+	b.WriteString("\x1b[0")
+	flags := []struct {
+		flag uint16
+		code string
+	}{
+		{attrBold, "1"}, {attrDim, "2"}, {attrItalic, "3"}, {attrUnderline, "4"},
+		{attrBlink, "5"}, {attrReverse, "7"}, {attrHidden, "8"}, {attrStrike, "9"},
+	}
+	for _, f := range flags {
+		if attr.flags&f.flag != 0 {
+			b.WriteString(";")
+			b.WriteString(f.code)
+		}
+	}
+	writeColor(&b, attr.fg, 30, 90, 38)
+	writeColor(&b, attr.bg, 40, 100, 48)
+	b.WriteString("m")
+	return b.String()
+}
+
+func writeColor(b *strings.Builder, c vtColor, base, brightBase, extended int) {
+	switch c.kind {
+	case colorIndexed:
+		switch {
+		case c.value < 8:
+			fmt.Fprintf(b, ";%d", base+int(c.value))
+		case c.value < 16:
+			fmt.Fprintf(b, ";%d", brightBase+int(c.value)-8)
+		default:
+			fmt.Fprintf(b, ";%d;5;%d", extended, c.value)
+		}
+	case colorRGB:
+		fmt.Fprintf(b, ";%d;2;%d;%d;%d", extended, c.value>>16&0xff, c.value>>8&0xff, c.value&0xff)
+	}
+}
+
+// render returns the byte sequence that repaints this screen onto a real
+// terminal of the same size: buffer selection, every cell with its
+// attributes, the scroll region, the DEC modes, the pen and the cursor.
+// Every mode is set explicitly, so the terminal may be in any state.
+func (s *vtScreen) render() []byte {
+	return s.repaint(nil)
+}
+
+// renderFrom is render for a terminal known to be in mode state from. Only
+// the modes that differ are changed, apart from those painting itself needs.
+func (s *vtScreen) renderFrom(from vtModes) []byte {
+	return s.repaint(&from)
+}
+
+func (s *vtScreen) repaint(from *vtModes) []byte {
+	var b bytes.Buffer
+
+	if from == nil {
+		// Hide the cursor while painting so it doesn't flicker across the panel
+		b.WriteString("\x1b[?25l")
+		if s.modes.altScreen {
+			b.WriteString("\x1b[?1049h")
+		} else {
+			b.WriteString("\x1b[?1049l")
+		}
+	} else {
+		b.WriteString(modeTransition(*from, s.modes))
+		b.WriteString("\x1b[?25l")
+	}
+	b.WriteString("\x1b[r\x1b[?6l\x1b[?7l\x1b[0m\x1b[H\x1b[2J")
+
+	s.renderLines(&b, 0, 0)
+
+	if s.top != 0 || s.bottom != s.rows-1 {
+		fmt.Fprintf(&b, "\x1b[%d;%dr", s.top+1, s.bottom+1)
+	}
+	if from == nil {
+		b.WriteString(s.modeSequence())
+	} else {
+		// Undo what painting changed
+		if s.modes.originMode {
+			b.WriteString("\x1b[?6h")
+		}
+		if !s.modes.autowrapOff {
+			b.WriteString("\x1b[?7h")
+		}
+	}
+	b.WriteString(sgrSequence(s.cursor.pen))
+
+	row := s.cursor.y
+	if s.modes.originMode {
+		row -= s.top
+	}
+	fmt.Fprintf(&b, "\x1b[%d;%dH", row+1, s.cursor.x+1)
+	if !s.modes.cursorHidden {
+		b.WriteString("\x1b[?25h")
+	}
+
+	return b.Bytes()
+}
+
+// renderLines writes every line of the visible buffer with its top-left
+// corner at (x0, y0) on the real terminal. Trailing blank cells are skipped
+// because the caller clears the area first.
+func (s *vtScreen) renderLines(b *bytes.Buffer, x0, y0 int) {
+	pen := vtAttr{}
+	for y, line := range s.grid() {
+		end := len(line)
+		for end > 0 && line[end-1].r == ' ' && line[end-1].comb == "" && line[end-1].attr == (vtAttr{}) {
+			end--
+		}
+		if end == 0 {
+			continue
+		}
+
+		fmt.Fprintf(b, "\x1b[%d;%dH", y0+y+1, x0+1)
+		for x := 0; x < end; x++ {
+			cell := line[x]
+			if cell.r == 0 {
+				continue
+			}
+			if cell.attr != pen {
+				b.WriteString(sgrSequence(cell.attr))
+				pen = cell.attr
+			}
+			b.WriteRune(cell.r)
+			b.WriteString(cell.comb)
+		}
+	}
+	if pen != (vtAttr{}) {
+		b.WriteString("\x1b[0m")
+	}
+}
+
+// renderPane writes every cell of the visible buffer, blanks included, with
+// its top-left corner at (x0, y0). Unlike renderLines it does not rely on the
+// area being cleared first, so it can repaint one pane of a split layout
+// without disturbing its neighbours.
+func (s *vtScreen) renderPane(b *bytes.Buffer, x0, y0 int) {
+	pen := vtAttr{}
+	b.WriteString("\x1b[0m")
+	for y, line := range s.grid() {
+		fmt.Fprintf(b, "\x1b[%d;%dH", y0+y+1, x0+1)
+		for _, cell := range line {
+			if cell.r == 0 {
+				continue
+			}
+			if cell.attr != pen {
+				b.WriteString(sgrSequence(cell.attr))
+				pen = cell.attr
+			}
+			b.WriteRune(cell.r)
+			b.WriteString(cell.comb)
+		}
+	}
+	if pen != (vtAttr{}) {
+		b.WriteString("\x1b[0m")
+	}
+}
+
+// modeSequence returns the sequences that put a reset terminal into this
+// screen's mode state. Every mode is emitted explicitly so the real PTY ends
+// up in exactly this state regardless of what the previous prism left behind.
+func (s *vtScreen) modeSequence() string {
+	var b strings.Builder
+	m := s.modes
+
+	decset := func(mode int, on bool) {
+		if on {
+			fmt.Fprintf(&b, "\x1b[?%dh", mode)
+		} else {
+			fmt.Fprintf(&b, "\x1b[?%dl", mode)
+		}
+	}
+
+	decset(1, m.appCursorKeys)
+	decset(6, m.originMode)
+	decset(7, !m.autowrapOff)
+	decset(1004, m.focusEvents)
+	decset(2004, m.bracketedPaste)
+	for _, mouse := range []int{1000, 1002, 1003} {
+		decset(mouse, m.mouseTracking == mouse)
+	}
+	decset(1006, m.mouseSGR)
+
+	if m.insertMode {
+		b.WriteString("\x1b[4h")
+	} else {
+		b.WriteString("\x1b[4l")
+	}
+	if m.appKeypad {
+		b.WriteString("\x1b=")
+	} else {
+		b.WriteString("\x1b>")
+	}
+	fmt.Fprintf(&b, "\x1b[%d q", m.cursorShape)
+
+	// Emptying the stack first keeps a terminal that already has these
+	// flags from getting them pushed twice
+	if keyboard := *m.activeKeyboard(); keyboard != (keyboardStack{}) {
+		fmt.Fprintf(&b, "\x1b[<%du", keyboardStackSize)
+		b.WriteString(keyboard.sequence())
+	}
+
+	return b.String()
+}
+
+// lineText returns the text content of visible line y with trailing spaces
+// trimmed. Used for diagnostics and tests.
+func (s *vtScreen) lineText(y int) string {
+	if y < 0 || y >= s.rows {
+		return ""
+	}
+	var b strings.Builder
+	for _, cell := range s.grid()[y] {
+		if cell.r == 0 {
+			continue
+		}
+		b.WriteRune(cell.r)
+		b.WriteString(cell.comb)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestVTScreen_PrintAndWrap(t *testing.T) {
+	s := newVTScreen(5, 3)
+	s.Write([]byte("hello world"))
+
+	if got := s.lineText(0); got != "hello" {
+		t.Errorf("line 0 = %q, want %q", got, "hello")
+	}
+	if got := s.lineText(1); got != " worl" {
+		t.Errorf("line 1 = %q, want %q", got, " worl")
+	}
+	if got := s.lineText(2); got != "d" {
+		t.Errorf("line 2 = %q, want %q", got, "d")
+	}
+	if s.cursor.x != 1 || s.cursor.y != 2 {
+		t.Errorf("cursor = (%d,%d), want (1,2)", s.cursor.x, s.cursor.y)
+	}
+}
+
+func TestVTScreen_ScrollsAtBottom(t *testing.T) {
+	s := newVTScreen(10, 2)
+	s.Write([]byte("one\r\ntwo\r\nthree"))
+
+	if got := s.lineText(0); got != "two" {
+		t.Errorf("line 0 = %q, want %q", got, "two")
+	}
+	if got := s.lineText(1); got != "three" {
+		t.Errorf("line 1 = %q, want %q", got, "three")
+	}
+}
+
+func TestVTScreen_CursorPositionAndErase(t *testing.T) {
+	s := newVTScreen(10, 3)
+	s.Write([]byte("aaaaaaaaaa\r\nbbbbbbbbbb\r\ncccccccccc"))
+	s.Write([]byte("\x1b[2;4H\x1b[K"))
+
+	if got := s.lineText(1); got != "bbb" {
+		t.Errorf("line 1 after EL = %q, want %q", got, "bbb")
+	}
+
+	s.Write([]byte("\x1b[2J"))
+	for y := 0; y < 3; y++ {
+		if got := s.lineText(y); got != "" {
+			t.Errorf("line %d after ED 2 = %q, want empty", y, got)
+		}
+	}
+}
+
+func TestVTScreen_SGRAttributes(t *testing.T) {
+	s := newVTScreen(10, 1)
+	s.Write([]byte("\x1b[1;31mA\x1b[38;2;1;2;3;48;5;200mB\x1b[0mC"))
+
+	line := s.grid()[0]
+
+	if line[0].attr.flags&attrBold == 0 {
+		t.Error("A should be bold")
+	}
+	if line[0].attr.fg != (vtColor{kind: colorIndexed, value: 1}) {
+		t.Errorf("A fg = %+v, want indexed 1", line[0].attr.fg)
+	}
+	if line[1].attr.fg != rgbColor(1, 2, 3) {
+		t.Errorf("B fg = %+v, want rgb(1,2,3)", line[1].attr.fg)
+	}
+	if line[1].attr.bg != (vtColor{kind: colorIndexed, value: 200}) {
+		t.Errorf("B bg = %+v, want indexed 200", line[1].attr.bg)
+	}
+	if line[2].attr != (vtAttr{}) {
+		t.Errorf("C attr = %+v, want default", line[2].attr)
+	}
+}
+
+func TestVTScreen_AltScreen(t *testing.T) {
+	s := newVTScreen(10, 2)
+	s.Write([]byte("primary"))
+	s.Write([]byte("\x1b[?1049h\x1b[Halt"))
+
+	if !s.modes.altScreen {
+		t.Fatal("altScreen should be set after ?1049h")
+	}
+	if got := s.lineText(0); got != "alt" {
+		t.Errorf("alt line 0 = %q, want %q", got, "alt")
+	}
+
+	s.Write([]byte("\x1b[?1049l"))
+	if s.modes.altScreen {
+		t.Fatal("altScreen should be reset after ?1049l")
+	}
+	if got := s.lineText(0); got != "primary" {
+		t.Errorf("primary line 0 = %q, want %q", got, "primary")
+	}
+	if s.cursor.x != 7 {
+		t.Errorf("cursor.x after restore = %d, want 7", s.cursor.x)
+	}
+}
+
+func TestVTScreen_PrivateModes(t *testing.T) {
+	s := newVTScreen(10, 2)
+	s.Write([]byte("\x1b[?25l\x1b[?2004h\x1b[?1002h\x1b[?1006h\x1b[?1h"))
+
+	want := vtModes{
+		cursorHidden:   true,
+		bracketedPaste: true,
+		mouseTracking:  1002,
+		mouseSGR:       true,
+		appCursorKeys:  true,
+	}
+	if s.modes != want {
+		t.Errorf("modes = %+v, want %+v", s.modes, want)
+	}
+}
+
+func TestVTScreen_WideCharacters(t *testing.T) {
+	s := newVTScreen(4, 2)
+	s.Write([]byte("a世界"))
+
+	if got := s.lineText(0); got != "a世" {
+		t.Errorf("line 0 = %q, want %q", got, "a世")
+	}
+	if got := s.lineText(1); got != "界" {
+		t.Errorf("line 1 = %q, want %q", got, "界")
+	}
+}
+
+func TestVTScreen_SequenceSplitAcrossWrites(t *testing.T) {
+	s := newVTScreen(10, 2)
+	s.Write([]byte("\x1b[2"))
+	s.Write([]byte(";3Hx\xe4"))
+	s.Write([]byte("\xb8\x96"))
+
+	if got := s.lineText(1); got != "  x世" {
+		t.Errorf("line 1 = %q, want %q", got, "  x世")
+	}
+}
+
+func TestVTScreen_ScrollRegion(t *testing.T) {
+	s := newVTScreen(5, 4)
+	s.Write([]byte("top\r\na\r\nb\r\nbot"))
+	s.Write([]byte("\x1b[2;3r\x1b[3;1H\nc"))
+
+	want := []string{"top", "b", "c", "bot"}
+	for y, w := range want {
+		if got := s.lineText(y); got != w {
+			t.Errorf("line %d = %q, want %q", y, got, w)
+		}
+	}
+}
+
+func TestVTScreen_OSCTitleIgnoredInOutput(t *testing.T) {
+	s := newVTScreen(10, 1)
+	s.Write([]byte("\x1b]0;shine-clock\x07ok\x1bP1$r\x1b\\!"))
+
+	if s.title != "shine-clock" {
+		t.Errorf("title = %q, want %q", s.title, "shine-clock")
+	}
+	if got := s.lineText(0); got != "ok!" {
+		t.Errorf("line 0 = %q, want %q", got, "ok!")
+	}
+}
+
+func TestVTScreen_Resize(t *testing.T) {
+	s := newVTScreen(10, 3)
+	s.Write([]byte("one\r\ntwo\r\nthree"))
+	s.resize(4, 2)
+
+	if s.cols != 4 || s.rows != 2 {
+		t.Fatalf("size = %dx%d, want 4x2", s.cols, s.rows)
+	}
+	if got := s.lineText(0); got != "two" {
+		t.Errorf("line 0 = %q, want %q", got, "two")
+	}
+	if got := s.lineText(1); got != "thre" {
+		t.Errorf("line 1 = %q, want %q", got, "thre")
+	}
+	if s.cursor.y != 1 {
+		t.Errorf("cursor.y = %d, want 1", s.cursor.y)
+	}
+}
+
+// Rendering a screen into a fresh screen must reproduce it exactly
+func TestVTScreen_RenderRoundTrip(t *testing.T) {
+	s := newVTScreen(12, 4)
+	s.Write([]byte("\x1b[?1049h\x1b[?2004h\x1b[2;5r\x1b[>1u\x1b[4 q"))
+	s.Write([]byte("\x1b[1;1H\x1b[1;32mgreen\x1b[0m plain"))
+	s.Write([]byte("\x1b[3;3H\x1b[7;48;2;10;20;30m世界\x1b[0m"))
+	s.Write([]byte("\x1b[4;12H\x1b[4mZ\x1b[?25l\x1b[3;7H\x1b[35m"))
+
+	replay := newVTScreen(12, 4)
+	replay.Write(s.render())
+
+	for y := 0; y < s.rows; y++ {
+		for x := 0; x < s.cols; x++ {
+			if got, want := replay.grid()[y][x], s.grid()[y][x]; got != want {
+				t.Errorf("cell (%d,%d) = %+v, want %+v", x, y, got, want)
+			}
+		}
+	}
+	if replay.cursor != s.cursor {
+		t.Errorf("cursor = %+v, want %+v", replay.cursor, s.cursor)
+	}
+	if replay.modes != s.modes {
+		t.Errorf("modes = %+v, want %+v", replay.modes, s.modes)
+	}
+	if replay.top != s.top || replay.bottom != s.bottom {
+		t.Errorf("scroll region = %d-%d, want %d-%d", replay.top, replay.bottom, s.top, s.bottom)
+	}
+}
@@ -10,17 +10,13 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/starbased-co/shine/pkg/prism"
 )
 
 func main() {
-	// Set window title using ANSI escape sequence
-	fmt.Print("\033]0;shine-bar\007")
-
-	// Note: Don't use tea.WithAltScreen() for thin status bars
+	// Note: Don't use prism.WithAltScreen() for thin status bars
 	// Alt screen mode is for full-screen TUIs, causes rendering issues in panels
-	p := tea.NewProgram(initialModel())
-
-	if _, err := p.Run(); err != nil {
+	if _, err := prism.Run(initialModel()); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -103,6 +99,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			refreshWorkspacesCmd(),
 		)
 
+	case prism.ResumeMsg:
+		m.currentTime = time.Now()
+		return m, refreshWorkspacesCmd()
+
 	case workspacesMsg:
 		m.workspaces = msg.workspaces
 		m.activeWorkspaceID = msg.activeID
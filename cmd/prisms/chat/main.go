@@ -12,24 +12,14 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/starbased-co/shine/pkg/prism"
 )
 
 const gap = "\n\n"
 
 func main() {
-	// Set window title using ANSI escape sequence
-	// Format: ESC ] 0 ; title BEL
-	// \033]0; is ESC ] 0 ;
-	// \007 is BEL (bell character)
-	fmt.Print("\033]0;shine-chat\007")
-
 	// Use alt screen mode to take over the full terminal
-	p := tea.NewProgram(
-		initialModel(),
-		tea.WithAltScreen(),
-	)
-
-	if _, err := p.Run(); err != nil {
+	if _, err := prism.Run(initialModel(), prism.WithAltScreen()); err != nil {
 		log.Fatal(err)
 	}
 }
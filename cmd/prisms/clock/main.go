@@ -1,26 +1,23 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/starbased-co/shine/pkg/prism"
 )
 
 func main() {
-	// Set window title using ANSI escape sequence
-	fmt.Print("\033]0;shine-clock\007")
+	settings, err := prism.LoadSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Use alt screen mode to take over the full terminal
 	// This prevents prismctl logs from interfering with the display
-	p := tea.NewProgram(
-		initialModel(),
-		tea.WithAltScreen(),
-	)
-
-	if _, err := p.Run(); err != nil {
+	if _, err := prism.Run(initialModel(settings.String("format", "15:04:05")), prism.WithAltScreen()); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -29,13 +26,15 @@ type tickMsg time.Time
 
 type model struct {
 	currentTime time.Time
+	format      string // time.Format layout
 	width       int
 	height      int
 }
 
-func initialModel() model {
+func initialModel(format string) model {
 	return model{
 		currentTime: time.Now(),
+		format:      format,
 		width:       20,
 		height:      5,
 	}
@@ -67,6 +66,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tickMsg:
 		m.currentTime = time.Time(msg)
 		return m, tickCmd()
+
+	case prism.ResumeMsg:
+		// Don't show the time we were suspended at until the next tick
+		m.currentTime = time.Now()
+		return m, nil
 	}
 
 	return m, nil
@@ -81,7 +85,7 @@ func (m model) View() string {
 		Width(m.width).
 		Height(m.height)
 
-	timeStr := m.currentTime.Format("15:04:05")
+	timeStr := m.currentTime.Format(m.format)
 
 	return timeStyle.Render(timeStr)
 }
@@ -11,19 +11,12 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/starbased-co/shine/pkg/prism"
 )
 
 func main() {
-	// Set window title using ANSI escape sequence
-	fmt.Print("\033]0;shine-sysinfo\007")
-
 	// Use alt screen mode to take over the full terminal
-	p := tea.NewProgram(
-		initialModel(),
-		tea.WithAltScreen(),
-	)
-
-	if _, err := p.Run(); err != nil {
+	if _, err := prism.Run(initialModel(), prism.WithAltScreen()); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -77,6 +70,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tickMsg:
 		m.info = getSysInfo()
 		return m, tickCmd()
+
+	case prism.ResumeMsg:
+		m.info = getSysInfo()
+		return m, nil
 	}
 
 	return m, nil
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/starbased-co/shine/pkg/attach"
+	"github.com/starbased-co/shine/pkg/config"
+	"github.com/starbased-co/shine/pkg/paths"
+)
+
+func cmdAttach(args []string) error {
+	fs := flag.NewFlagSet("attach", flag.ContinueOnError)
+	readOnly := fs.Bool("read-only", false, "watch only, without sending input")
+	detachKey := fs.String("detach-key", `C-\`, "key that detaches (none = disabled)")
+	ignoreSize := fs.Bool("ignore-size", false, "never resize the panel's prisms to this terminal")
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: shine attach <panel> [--read-only] [--detach-key KEY] [--ignore-size]")
+	}
+	panel := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	key, detachable, err := config.ParsePrefixKey(*detachKey)
+	if err != nil {
+		return fmt.Errorf("invalid detach key: %w", err)
+	}
+
+	socketPath := paths.PrismAttachSocket(panel)
+	if _, err := os.Stat(socketPath); err != nil {
+		return fmt.Errorf("panel %s is not running (no attach socket at %s)", panel, socketPath)
+	}
+
+	reason, err := attach.Attach(socketPath, os.Stdin, os.Stdout, attach.Options{
+		DetachKey:  key,
+		Detachable: detachable,
+		ReadOnly:   *readOnly,
+		IgnoreSize: *ignoreSize,
+	})
+	if errors.Is(err, attach.ErrClosed) {
+		fmt.Print("\r\n")
+		Warning(fmt.Sprintf("Panel %s closed the connection", panel))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to attach to %s: %w", panel, err)
+	}
+
+	fmt.Print("\r\n")
+	if reason == "" {
+		Info(fmt.Sprintf("Detached from %s", panel))
+	} else {
+		Info(fmt.Sprintf("Detached from %s: %s", panel, reason))
+	}
+
+	return nil
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/starbased-co/shine/pkg/paths"
 	"github.com/starbased-co/shine/pkg/rpc"
 	"github.com/starbased-co/shine/pkg/state"
+	"golang.org/x/sys/unix"
 )
 
 func connectShined() (*rpc.ShinedClient, error) {
@@ -37,6 +42,10 @@ func discoverPrismInstances() ([]string, error) {
 
 	instances := make([]string, 0, len(matches))
 	for _, socket := range matches {
+		// prism-*.attach.sock is the same instance's attach socket
+		if strings.HasSuffix(socket, ".attach.sock") {
+			continue
+		}
 		instances = append(instances, extractInstanceName(socket))
 	}
 
@@ -176,7 +185,121 @@ func cmdReload() error {
 	return nil
 }
 
-func displayStateFromMmap(instance string, s *state.PrismRuntimeState) {
+// cmdEvents prints the events shined pushes, such as config reloads and
+// rejected configs, until interrupted
+func cmdEvents() error {
+	if !isShinedRunning() {
+		return fmt.Errorf("shined is not running")
+	}
+
+	client, err := rpc.NewShinedClient(paths.ShinedSocket(), rpc.WithTimeout(3*time.Second), rpc.WithEventHandler(printEvent))
+	if err != nil {
+		return fmt.Errorf("failed to connect to shined: %w", err)
+	}
+	defer client.Close()
+
+	if _, err := client.SubscribeEvents(context.Background()); err != nil {
+		return fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+
+	Info("Watching shined events (Ctrl+C to stop)")
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, unix.SIGINT, unix.SIGTERM)
+
+	select {
+	case <-sigCh:
+		return nil
+	case <-client.Done():
+		return fmt.Errorf("shined closed the connection")
+	}
+}
+
+func printEvent(event *rpc.Event) {
+	msg := fmt.Sprintf("%s %s", time.UnixMilli(event.Time).Format("15:04:05"), event.Type)
+	if event.Message != "" {
+		msg += ": " + event.Message
+	}
+
+	if event.Type == "config/invalid" {
+		Error(msg)
+		return
+	}
+	Info(msg)
+}
+
+// cmdResetFailed clears the failed state of prisms that exceeded
+// max_restarts, so shined starts them and applies their restart policy again
+func cmdResetFailed(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: shine reset-failed <panel> [prism]")
+	}
+	panel, prism := args[0], ""
+	if len(args) == 2 {
+		prism = args[1]
+	}
+
+	if !isShinedRunning() {
+		return fmt.Errorf("shined is not running")
+	}
+
+	client, err := connectShined()
+	if err != nil {
+		return fmt.Errorf("failed to connect to shined: %w", err)
+	}
+	defer client.Close()
+
+	result, err := client.ResetFailed(context.Background(), panel, prism)
+	if err != nil {
+		return fmt.Errorf("reset-failed request failed: %w", err)
+	}
+
+	if len(result.Reset) == 0 {
+		Warning("No failed prisms to reset")
+		return nil
+	}
+	for _, name := range result.Reset {
+		Success(fmt.Sprintf("Reset %s", name))
+	}
+	return nil
+}
+
+// statusSorts are the orders shine status --sort accepts, each putting the
+// heaviest prisms first. Without --sort prisms keep the panel's order.
+var statusSorts = []string{"cpu", "mem"}
+
+// formatCPU formats a CPU usage sample, "-" when there is none
+func formatCPU(sampled bool, percent float64) string {
+	if !sampled {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", percent)
+}
+
+// formatMem formats resident memory, "-" when there is no sample
+func formatMem(sampled bool, bytes uint64) string {
+	if !sampled {
+		return "-"
+	}
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(bytes)/(1<<20))
+	default:
+		return fmt.Sprintf("%dK", bytes>>10)
+	}
+}
+
+// formatThreads formats a thread count, "-" when there is no sample
+func formatThreads(sampled bool, threads int) string {
+	if !sampled {
+		return "-"
+	}
+	return fmt.Sprintf("%d", threads)
+}
+
+func displayStateFromMmap(instance string, s *state.PrismRuntimeState, sortBy string) {
 	fmt.Println()
 	fmt.Printf("%s %s\n", styleBold.Render("Panel:"), instance)
 	fmt.Printf("%s %s\n", styleMuted.Render("Source:"), "mmap")
@@ -190,8 +313,19 @@ func displayStateFromMmap(instance string, s *state.PrismRuntimeState) {
 
 	fmt.Println(StatusBox(fgName, bgCount, len(activePrisms)))
 
+	switch sortBy {
+	case "cpu":
+		sort.SliceStable(activePrisms, func(i, j int) bool {
+			return activePrisms[i].CPUTenths > activePrisms[j].CPUTenths
+		})
+	case "mem":
+		sort.SliceStable(activePrisms, func(i, j int) bool {
+			return activePrisms[i].RSSKB > activePrisms[j].RSSKB
+		})
+	}
+
 	if len(activePrisms) > 0 {
-		table := NewTable("Prism", "PID", "State", "Uptime")
+		table := NewTable("Prism", "PID", "State", "Uptime", "CPU", "Mem", "Threads")
 		for _, prism := range activePrisms {
 			name := prism.GetName()
 			stateStr := "background"
@@ -202,14 +336,18 @@ func displayStateFromMmap(instance string, s *state.PrismRuntimeState) {
 			}
 			uptime := prism.Uptime()
 			uptimeStr := fmt.Sprintf("%v", uptime.Truncate(time.Second))
-			table.AddRow(name, fmt.Sprintf("%d", prism.PID), stateStr, uptimeStr)
+			sampled := prism.HasUsage()
+			table.AddRow(name, fmt.Sprintf("%d", prism.PID), stateStr, uptimeStr,
+				formatCPU(sampled, prism.CPUPercent()),
+				formatMem(sampled, prism.RSSBytes()),
+				formatThreads(sampled, int(prism.Threads)))
 		}
 		fmt.Println()
 		table.Print()
 	}
 }
 
-func displayStateFromRPC(instance string, prisms []rpc.PrismInfo) {
+func displayStateFromRPC(instance string, prisms []rpc.PrismInfo, sortBy string) {
 	fmt.Println()
 	fmt.Printf("%s %s\n", styleBold.Render("Panel:"), instance)
 	fmt.Printf("%s %s\n", styleMuted.Render("Source:"), "rpc")
@@ -226,8 +364,19 @@ func displayStateFromRPC(instance string, prisms []rpc.PrismInfo) {
 
 	fmt.Println(StatusBox(fgName, bgCount, len(prisms)))
 
+	switch sortBy {
+	case "cpu":
+		sort.SliceStable(prisms, func(i, j int) bool {
+			return prisms[i].CPUPercent > prisms[j].CPUPercent
+		})
+	case "mem":
+		sort.SliceStable(prisms, func(i, j int) bool {
+			return prisms[i].RSSBytes > prisms[j].RSSBytes
+		})
+	}
+
 	if len(prisms) > 0 {
-		table := NewTable("Prism", "PID", "State", "Uptime")
+		table := NewTable("Prism", "PID", "State", "Uptime", "CPU", "Mem", "Threads")
 		for _, prism := range prisms {
 			stateStr := prism.State
 			if prism.State == "fg" {
@@ -237,14 +386,26 @@ func displayStateFromRPC(instance string, prisms []rpc.PrismInfo) {
 			}
 			uptime := time.Duration(prism.UptimeMs) * time.Millisecond
 			uptimeStr := fmt.Sprintf("%v", uptime.Truncate(time.Second))
-			table.AddRow(prism.Name, fmt.Sprintf("%d", prism.PID), stateStr, uptimeStr)
+			table.AddRow(prism.Name, fmt.Sprintf("%d", prism.PID), stateStr, uptimeStr,
+				formatCPU(prism.Sampled, prism.CPUPercent),
+				formatMem(prism.Sampled, prism.RSSBytes),
+				formatThreads(prism.Sampled, prism.Threads))
 		}
 		fmt.Println()
 		table.Print()
 	}
 }
 
-func cmdStatus() error {
+func cmdStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	sortBy := fs.String("sort", "", "sort prisms by usage: cpu or mem")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *sortBy != "" && !slices.Contains(statusSorts, *sortBy) {
+		return fmt.Errorf("invalid sort %q (want one of: %s)", *sortBy, strings.Join(statusSorts, ", "))
+	}
+
 	ctx := context.Background()
 
 	// Try shined first for aggregated status
@@ -269,7 +430,8 @@ func cmdStatus() error {
 
 				// Query each panel for detailed status
 				for _, panel := range result.Panels {
-					displayPanelStatus(ctx, panel.Instance)
+					displayPanelStatus(ctx, panel.Instance, *sortBy)
+					printFailed(panel)
 				}
 				return nil
 			}
@@ -293,20 +455,22 @@ func cmdStatus() error {
 	Header(fmt.Sprintf("Shine Status (%d panel(s))", len(instances)))
 
 	for _, instance := range instances {
-		displayPanelStatus(ctx, instance)
+		displayPanelStatus(ctx, instance, *sortBy)
 	}
 
 	return nil
 }
 
-func displayPanelStatus(ctx context.Context, instance string) {
+func displayPanelStatus(ctx context.Context, instance string, sortBy string) {
 	// Try mmap first (instant, no connection needed)
 	reader, err := state.OpenPrismStateReader(paths.PrismState(instance))
 	if err == nil {
 		s, readErr := reader.Read()
 		reader.Close()
 		if readErr == nil {
-			displayStateFromMmap(instance, s)
+			displayStateFromMmap(instance, s, sortBy)
+			displayUnhealthy(ctx, instance)
+			displayRecentExits(ctx, instance)
 			return
 		}
 	}
@@ -330,7 +494,89 @@ func displayPanelStatus(ctx context.Context, instance string) {
 		return
 	}
 
-	displayStateFromRPC(instance, result.Prisms)
+	displayStateFromRPC(instance, result.Prisms, sortBy)
+	printUnhealthy(result.Prisms)
+	displayRecentExits(ctx, instance)
+}
+
+// displayUnhealthy warns about prisms failing their liveness probe. Probe
+// results are only kept by prismctl, so they are always queried over RPC.
+func displayUnhealthy(ctx context.Context, instance string) {
+	client, err := rpc.NewPrismClient(paths.PrismSocket(instance), rpc.WithTimeout(time.Second))
+	if err != nil {
+		return
+	}
+	result, err := client.List(ctx)
+	client.Close()
+	if err != nil {
+		return
+	}
+
+	printUnhealthy(result.Prisms)
+}
+
+func printUnhealthy(prisms []rpc.PrismInfo) {
+	for _, prism := range prisms {
+		if prism.Health == "unhealthy" {
+			Warning(fmt.Sprintf("%s is unhealthy: %s", prism.Name, prism.HealthReason))
+		}
+	}
+}
+
+// printFailed warns about prisms shined stopped restarting. Only shined
+// tracks restarts, so they are not shown without it.
+func printFailed(panel rpc.PanelInfo) {
+	for _, name := range panel.Failed {
+		Error(fmt.Sprintf("%s failed: exceeded max_restarts (shine reset-failed %s %s)", name, panel.Instance, name))
+	}
+}
+
+// statusRecentExits is how many recent exits shine status shows per panel
+const statusRecentExits = 5
+
+// displayRecentExits shows the prisms that exited most recently. Exit
+// history is only kept by prismctl, so it is always queried over RPC.
+func displayRecentExits(ctx context.Context, instance string) {
+	client, err := rpc.NewPrismClient(paths.PrismSocket(instance), rpc.WithTimeout(time.Second))
+	if err != nil {
+		return
+	}
+	result, err := client.History(ctx, "")
+	client.Close()
+	if err != nil || len(result.Exits) == 0 {
+		return
+	}
+
+	exits := result.Exits
+	if len(exits) > statusRecentExits {
+		exits = exits[:statusRecentExits]
+	}
+
+	fmt.Println()
+	fmt.Println(styleBold.Render("Recently exited:"))
+	table := NewTable("Prism", "PID", "Exit", "Runtime", "Exited")
+	for _, exit := range exits {
+		runtime := time.Duration(exit.RuntimeMs) * time.Millisecond
+		ago := time.Since(time.UnixMilli(exit.ExitedMs))
+		table.AddRow(exit.Name, fmt.Sprintf("%d", exit.PID), formatExit(exit),
+			fmt.Sprintf("%v", runtime.Truncate(time.Second)),
+			fmt.Sprintf("%v ago", ago.Truncate(time.Second)))
+	}
+	table.Print()
+}
+
+// formatExit describes how a prism exited
+func formatExit(exit rpc.ExitInfo) string {
+	switch {
+	case exit.Signal != "" && exit.CoreDumped:
+		return styleError.Render(exit.Signal + " (core dumped)")
+	case exit.Signal != "":
+		return styleError.Render(exit.Signal)
+	case exit.ExitCode != 0:
+		return styleError.Render(fmt.Sprintf("exit %d", exit.ExitCode))
+	default:
+		return styleMuted.Render("exit 0")
+	}
 }
 
 func cmdLogs(panelID string) error {
@@ -394,6 +640,113 @@ func cmdLogs(panelID string) error {
 	return nil
 }
 
+func cmdCapture(args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ContinueOnError)
+	lines := fs.Int("lines", 100, "number of lines to capture (0 = all)")
+	bytes := fs.Int("bytes", 0, "number of bytes to capture (overrides --lines)")
+	raw := fs.Bool("raw", false, "keep escape sequences")
+	screen := fs.Bool("screen", false, "capture the visible screen instead of the output history")
+
+	if len(args) < 2 {
+		return fmt.Errorf("usage: shine capture <panel> <prism> [--lines N] [--bytes N] [--raw] [--screen]")
+	}
+	panel, prism := args[0], args[1]
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	client, err := rpc.NewPrismClient(paths.PrismSocket(panel))
+	if err != nil {
+		return fmt.Errorf("failed to connect to panel %s: %w", panel, err)
+	}
+	defer client.Close()
+
+	result, err := client.Capture(context.Background(), &rpc.CaptureRequest{
+		Name:   prism,
+		Lines:  *lines,
+		Bytes:  *bytes,
+		Strip:  !*raw,
+		Screen: *screen,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to capture %s: %w", prism, err)
+	}
+
+	fmt.Print(result.Content)
+	if result.Content != "" && !strings.HasSuffix(result.Content, "\n") {
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func cmdSendKeys(args []string) error {
+	fs := flag.NewFlagSet("send-keys", flag.ContinueOnError)
+	literal := fs.Bool("l", false, "send keys as literal text, without key name lookup")
+
+	if len(args) < 2 {
+		return fmt.Errorf("usage: shine send-keys <panel> <prism> [-l] <key>...")
+	}
+	panel, prism := args[0], args[1]
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: shine send-keys <panel> <prism> [-l] <key>...")
+	}
+
+	client, err := rpc.NewPrismClient(paths.PrismSocket(panel))
+	if err != nil {
+		return fmt.Errorf("failed to connect to panel %s: %w", panel, err)
+	}
+	defer client.Close()
+
+	if _, err := client.SendKeys(context.Background(), &rpc.SendKeysRequest{
+		Name:    prism,
+		Keys:    fs.Args(),
+		Literal: *literal,
+	}); err != nil {
+		return fmt.Errorf("failed to send keys to %s: %w", prism, err)
+	}
+
+	return nil
+}
+
+// cmdVisibility reports a panel being hidden or shown to its prismctl, for
+// the keybinding that toggles the panel to call, so its prisms are told
+func cmdVisibility(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: shine visibility <panel> hidden|shown")
+	}
+	panel := args[0]
+
+	var hidden bool
+	switch args[1] {
+	case "hidden":
+		hidden = true
+	case "shown":
+		hidden = false
+	default:
+		return fmt.Errorf("usage: shine visibility <panel> hidden|shown")
+	}
+
+	client, err := rpc.NewPrismClient(paths.PrismSocket(panel))
+	if err != nil {
+		return fmt.Errorf("failed to connect to panel %s: %w", panel, err)
+	}
+	defer client.Close()
+
+	result, err := client.SetVisibility(context.Background(), hidden)
+	if err != nil {
+		return fmt.Errorf("failed to set visibility of %s: %w", panel, err)
+	}
+
+	if !result.Changed {
+		Info(fmt.Sprintf("Panel %s is already %s", panel, args[1]))
+	}
+	return nil
+}
+
 // TODO: remove/redo this way of getting instance name.
 func extractInstanceName(socketPath string) string {
 	base := filepath.Base(socketPath)
@@ -43,7 +43,7 @@ func main() {
 		err = cmdReload()
 
 	case "status":
-		err = cmdStatus()
+		err = cmdStatus(os.Args[2:])
 
 	case "logs":
 		panelID := ""
@@ -52,6 +52,27 @@ func main() {
 		}
 		err = cmdLogs(panelID)
 
+	case "capture":
+		err = cmdCapture(os.Args[2:])
+
+	case "send-keys":
+		err = cmdSendKeys(os.Args[2:])
+
+	case "replay":
+		err = cmdReplay(os.Args[2:])
+
+	case "attach":
+		err = cmdAttach(os.Args[2:])
+
+	case "reset-failed":
+		err = cmdResetFailed(os.Args[2:])
+
+	case "visibility":
+		err = cmdVisibility(os.Args[2:])
+
+	case "events":
+		err = cmdEvents()
+
 	default:
 		Error(fmt.Sprintf("Unknown command: %s", command))
 		fmt.Println()
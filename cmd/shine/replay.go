@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type castHeader struct {
+	Version int    `json:"version"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Title   string `json:"title"`
+}
+
+type castEvent struct {
+	Time float64
+	Kind string
+	Data string
+}
+
+func (e *castEvent) UnmarshalJSON(b []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("expected 3 fields, got %d", len(fields))
+	}
+	if err := json.Unmarshal(fields[0], &e.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(fields[1], &e.Kind); err != nil {
+		return err
+	}
+	return json.Unmarshal(fields[2], &e.Data)
+}
+
+func cmdReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speed := fs.Float64("speed", 1, "playback speed multiplier")
+	maxWait := fs.Duration("max-wait", 2*time.Second, "longest pause between events (0 = unlimited)")
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: shine replay <file> [--speed N] [--max-wait D]")
+	}
+	path := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *speed <= 0 {
+		return fmt.Errorf("speed must be positive")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		return fmt.Errorf("recording is empty: %s", path)
+	}
+
+	var header castHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("invalid asciicast header: %w", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	if cols, rows, err := terminalSize(); err == nil && (cols < header.Width || rows < header.Height) {
+		Warning(fmt.Sprintf("Terminal is %dx%d, recording is %dx%d", cols, rows, header.Width, header.Height))
+	}
+
+	last := 0.0
+	line := 1
+	for scanner.Scan() {
+		line++
+
+		var event castEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("invalid event on line %d: %w", line, err)
+		}
+
+		wait := time.Duration((event.Time - last) / *speed * float64(time.Second))
+		if *maxWait > 0 && wait > *maxWait {
+			wait = *maxWait
+		}
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+		last = event.Time
+
+		// Resize ("r") and other events can't be applied to the current terminal
+		if event.Kind == "o" {
+			os.Stdout.WriteString(event.Data)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	// Leave the terminal usable whatever state the recording ended in
+	fmt.Print("\x1b[0m\x1b[?25h\x1b[?1049l")
+	fmt.Println()
+
+	return nil
+}
+
+func terminalSize() (int, int, error) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
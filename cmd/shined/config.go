@@ -4,15 +4,14 @@ import (
 	"time"
 
 	"github.com/starbased-co/shine/pkg/config"
+	"github.com/starbased-co/shine/pkg/rpc"
 )
 
-// PrismEntry wraps config.PrismConfig with restart policies
+// PrismEntry wraps config.PrismConfig for shined. Restart policies come from
+// the config: restart, restart_delay and max_restarts per prism, overridable
+// per app.
 type PrismEntry struct {
 	*config.PrismConfig
-
-	Restart      string `toml:"restart"`       // always | on-failure | unless-stopped | no
-	RestartDelay string `toml:"restart_delay"` // Duration string (e.g., "5s")
-	MaxRestarts  int    `toml:"max_restarts"`  // Max restarts per hour (0 = unlimited)
 }
 
 type RestartPolicy int
@@ -24,8 +23,18 @@ const (
 	RestartAlways
 )
 
-func (pe *PrismEntry) GetRestartPolicy() RestartPolicy {
-	switch pe.Restart {
+// defaultRestartDelay is the first restart delay when none is configured
+const defaultRestartDelay = time.Second
+
+// restartSpec is the restart policy of one app
+type restartSpec struct {
+	policy      RestartPolicy
+	delay       time.Duration // before the first restart, doubled per quick restart
+	maxRestarts int           // within an hour before the app fails, 0 = unlimited
+}
+
+func parseRestartPolicy(policy string) RestartPolicy {
+	switch policy {
 	case "always":
 		return RestartAlways
 	case "on-failure":
@@ -39,17 +48,48 @@ func (pe *PrismEntry) GetRestartPolicy() RestartPolicy {
 	}
 }
 
-func (pe *PrismEntry) GetRestartDelay() time.Duration {
-	if pe.RestartDelay == "" {
-		return 1 * time.Second // Default
+func parseRestartDelay(delay string) time.Duration {
+	if delay == "" {
+		return defaultRestartDelay
 	}
-	d, err := time.ParseDuration(pe.RestartDelay)
-	if err != nil {
-		return 1 * time.Second // Fallback
+	d, err := time.ParseDuration(delay)
+	if err != nil || d <= 0 {
+		return defaultRestartDelay // Fallback
 	}
 	return d
 }
 
+// GetRestartPolicy returns the prism's own policy, which applies to the panel
+func (pe *PrismEntry) GetRestartPolicy() RestartPolicy {
+	return parseRestartPolicy(pe.Restart)
+}
+
+func (pe *PrismEntry) GetRestartDelay() time.Duration {
+	return parseRestartDelay(pe.RestartDelay)
+}
+
+// restartSpec returns the restart policy of the app an instance runs.
+// Instances beyond the first are named "<app>:N" by prismctl.
+func (pe *PrismEntry) restartSpec(instance string) restartSpec {
+	apps := pe.GetApps()
+
+	app, ok := apps[instance]
+	if !ok {
+		if name, _, isCopy := rpc.SplitInstanceID(instance); isCopy {
+			app, ok = apps[name]
+		}
+	}
+	if !ok || app == nil {
+		app = &config.AppConfig{}
+	}
+
+	return restartSpec{
+		policy:      parseRestartPolicy(pe.AppRestart(app)),
+		delay:       parseRestartDelay(pe.AppRestartDelay(app)),
+		maxRestarts: pe.AppMaxRestarts(app),
+	}
+}
+
 func (pe *PrismEntry) ValidateRestartPolicy() error {
 	if err := config.ValidateRestartPolicy(pe.Restart); err != nil {
 		return err
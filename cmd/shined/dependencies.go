@@ -0,0 +1,173 @@
+// dependencies.go starts panels in dependency order. A prism's panel starts
+// after the panels of the prisms it names in after and requires, and those
+// it requires must be healthy, with their socket up and their apps started,
+// or it is not started at all. When a required panel fails at runtime its
+// dependents are stopped, and they are started again once it has been
+// restarted. They stay stopped if it is not restarted or leaves the config.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/starbased-co/shine/pkg/config"
+)
+
+// sortEntries orders entries so that every entry comes after its
+// dependencies
+func sortEntries(entries []*PrismEntry) ([]*PrismEntry, error) {
+	byName := make(map[string]*PrismEntry, len(entries))
+	prisms := make([]*config.PrismConfig, 0, len(entries))
+	for _, entry := range entries {
+		byName[entry.Name] = entry
+		prisms = append(prisms, entry.PrismConfig)
+	}
+
+	sorted, err := config.SortPrisms(prisms)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]*PrismEntry, 0, len(sorted))
+	for _, pc := range sorted {
+		ordered = append(ordered, byName[pc.Name])
+	}
+	return ordered, nil
+}
+
+// SpawnPanels spawns entries in dependency order. Each panel is spawned once
+// its dependencies are up; a panel whose required panels are not healthy is
+// skipped. pm.onSpawned, if set, is called with every healthy new panel. The
+// error joins those of the panels that failed.
+func (pm *PanelManager) SpawnPanels(entries []*PrismEntry) error {
+	ordered, err := sortEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]bool)
+	var errs []error
+
+	for _, entry := range ordered {
+		if dep := pm.unmetRequirement(entry, failed); dep != "" {
+			failed[entry.Name] = true
+			errs = append(errs, fmt.Errorf("not spawning panel for %s: required panel %s is not healthy", entry.Name, dep))
+			continue
+		}
+
+		log.Printf("Spawning panel for prism: %s (instance: %s, binary: %s)",
+			entry.Name, entry.Name, entry.ResolvedPath)
+
+		panel, err := pm.SpawnPanel(entry, entry.Name)
+		if err == nil && !pm.CheckHealth(panel) {
+			err = fmt.Errorf("panel is not responding")
+		}
+		if err != nil {
+			failed[entry.Name] = true
+			errs = append(errs, fmt.Errorf("failed to spawn panel for %s: %w", entry.Name, err))
+			continue
+		}
+
+		log.Printf("Panel spawned successfully: %s (socket: %s)", panel.Instance, panel.SocketPath)
+
+		if pm.onSpawned != nil {
+			pm.onSpawned(panel)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// unmetRequirement returns the first panel entry requires that failed to
+// spawn, or that is neither being spawned nor running and healthy
+func (pm *PanelManager) unmetRequirement(entry *PrismEntry, failed map[string]bool) string {
+	for _, dep := range entry.Requires {
+		if failed[dep] {
+			return dep
+		}
+
+		panel, ok := pm.GetPanel(dep)
+		if !ok || !pm.CheckHealth(panel) {
+			return dep
+		}
+	}
+	return ""
+}
+
+// stopDependentsLocked stops the running panels that require instanceName,
+// directly or through one another, and holds on to their configs so that
+// restartDependents can bring them back. pm.mu must be held.
+func (pm *PanelManager) stopDependentsLocked(instanceName string) {
+	queue := []string{instanceName}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for instance, panel := range pm.panels {
+			if !slices.Contains(panel.Config.Requires, name) {
+				continue
+			}
+
+			log.Printf("Stopping panel %s: required panel %s failed", instance, name)
+			pm.killPanelLocked(panel)
+
+			if pm.heldDependents == nil {
+				pm.heldDependents = make(map[string][]*PrismEntry)
+			}
+			pm.heldDependents[instanceName] = append(pm.heldDependents[instanceName], panel.Config)
+			queue = append(queue, instance)
+		}
+	}
+}
+
+// restartDependents starts the panels stopped when instanceName failed
+func (pm *PanelManager) restartDependents(instanceName string) {
+	pm.mu.Lock()
+	entries := pm.heldDependents[instanceName]
+	delete(pm.heldDependents, instanceName)
+	pm.mu.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	log.Printf("Restarting panels that require %s", instanceName)
+	if err := pm.SpawnPanels(entries); err != nil {
+		log.Printf("Failed to restart panels that require %s: %v", instanceName, err)
+	}
+}
+
+// dropDependentsLocked forgets the panels stopped when instanceName failed,
+// as it will not be restarted. pm.mu must be held.
+func (pm *PanelManager) dropDependentsLocked(instanceName string) {
+	if held := pm.heldDependents[instanceName]; len(held) > 0 {
+		log.Printf("Not restarting %d panel(s) that require %s", len(held), instanceName)
+	}
+	delete(pm.heldDependents, instanceName)
+}
+
+// updateHeldDependents replaces the configs of held panels with those of
+// a reloaded config, dropping panels it no longer has and those held for
+// a panel it no longer has
+func (pm *PanelManager) updateHeldDependents(entries map[string]*PrismEntry) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for instance, held := range pm.heldDependents {
+		if _, ok := entries[instance]; !ok {
+			pm.dropDependentsLocked(instance)
+			continue
+		}
+
+		updated := held[:0]
+		for _, entry := range held {
+			if next, ok := entries[entry.Name]; ok {
+				updated = append(updated, next)
+			}
+		}
+		pm.heldDependents[instance] = updated
+	}
+}
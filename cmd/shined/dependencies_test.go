@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/creachadair/jrpc2/handler"
+	"github.com/starbased-co/shine/pkg/config"
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+func TestSortEntries(t *testing.T) {
+	entries := []*PrismEntry{
+		testEntry(func(pc *config.PrismConfig) { pc.Name = "chat"; pc.After = []string{"bar"} }),
+		testEntry(func(pc *config.PrismConfig) { pc.Name = "bar" }),
+	}
+
+	sorted, err := sortEntries(entries)
+	if err != nil {
+		t.Fatalf("sortEntries() error: %v", err)
+	}
+	if sorted[0].Name != "bar" || sorted[1].Name != "chat" {
+		t.Errorf("sortEntries() = %s, %s, want bar, chat", sorted[0].Name, sorted[1].Name)
+	}
+
+	entries[1].After = []string{"chat"}
+	if _, err := sortEntries(entries); err == nil {
+		t.Error("sortEntries() should reject a cycle")
+	}
+}
+
+// TestDependents tests that a panel's requirements must be healthy, and
+// that its dependents are stopped when it fails
+func TestDependents(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	srv := rpc.NewServer(sockPath, handler.Map{
+		"service/health": handler.New(func(ctx context.Context) (*rpc.HealthResult, error) {
+			return &rpc.HealthResult{Healthy: true}, nil
+		}),
+	}, nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	newPanel := func(name string, requires ...string) *Panel {
+		entry := testEntry(func(pc *config.PrismConfig) {
+			pc.Name = name
+			pc.Requires = requires
+		})
+		return &Panel{Name: name, Instance: name, SocketPath: sockPath, RPCClient: client, Config: entry}
+	}
+
+	pm := &PanelManager{panels: map[string]*Panel{
+		"bar":   newPanel("bar"),
+		"chat":  newPanel("chat", "bar"),
+		"clock": newPanel("clock", "chat"),
+		"audio": newPanel("audio"),
+	}}
+
+	mail := testEntry(func(pc *config.PrismConfig) { pc.Requires = []string{"bar"} })
+	if dep := pm.unmetRequirement(mail, map[string]bool{}); dep != "" {
+		t.Errorf("unmetRequirement() = %q, want bar to be healthy", dep)
+	}
+	if dep := pm.unmetRequirement(mail, map[string]bool{"bar": true}); dep != "bar" {
+		t.Errorf("unmetRequirement() = %q, want bar to have failed", dep)
+	}
+
+	pm.mu.Lock()
+	delete(pm.panels, "bar")
+	pm.stopDependentsLocked("bar")
+	pm.mu.Unlock()
+
+	if dep := pm.unmetRequirement(mail, map[string]bool{}); dep != "bar" {
+		t.Errorf("unmetRequirement() = %q, want bar not running", dep)
+	}
+
+	if _, ok := pm.GetPanel("audio"); !ok || len(pm.ListPanels()) != 1 {
+		t.Errorf("panels = %d, want only audio left running", len(pm.ListPanels()))
+	}
+
+	held := pm.heldDependents["bar"]
+	if len(held) != 2 || held[0].Name != "chat" || held[1].Name != "clock" {
+		t.Errorf("held dependents = %d, want chat and clock", len(held))
+	}
+
+	pm.updateHeldDependents(map[string]*PrismEntry{"bar": testEntry(nil), "chat": testEntry(nil)})
+	if held := pm.heldDependents["bar"]; len(held) != 1 || held[0].Name != "chat" {
+		t.Errorf("held dependents = %d, want clock dropped from the config", len(held))
+	}
+
+	pm.updateHeldDependents(map[string]*PrismEntry{"chat": testEntry(nil)})
+	if _, ok := pm.heldDependents["bar"]; ok {
+		t.Error("held dependents kept after bar was dropped from the config")
+	}
+}
+
+// TestDependents_NotRestarted tests that the panels stopped along with a
+// panel that is not restarted are not held for it
+func TestDependents_NotRestarted(t *testing.T) {
+	bar := &Panel{Name: "bar", Instance: "bar", Config: testEntry(func(pc *config.PrismConfig) {
+		pc.Name = "bar"
+		pc.Restart = "no"
+	})}
+	chat := &Panel{Name: "chat", Instance: "chat", Config: testEntry(func(pc *config.PrismConfig) {
+		pc.Requires = []string{"bar"}
+	})}
+
+	pm := &PanelManager{panels: map[string]*Panel{"bar": bar, "chat": chat}}
+	pm.handlePanelCrash(bar)
+
+	if n := len(pm.ListPanels()); n != 0 {
+		t.Errorf("panels = %d, want chat stopped along with bar", n)
+	}
+	if _, ok := pm.heldDependents["bar"]; ok {
+		t.Error("chat held for bar, which is not restarted")
+	}
+}
@@ -0,0 +1,76 @@
+// events.go is shined's event stream. A client calls events/subscribe and
+// is then pushed an "event" notification for everything published, such as
+// a reloaded config or one that was rejected, until it disconnects.
+
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+const (
+	eventConfigReloaded = "config/reloaded"
+	eventConfigInvalid  = "config/invalid"
+)
+
+// eventBus pushes events to subscribed connections. A nil *eventBus drops
+// them.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[*jrpc2.Server]bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[*jrpc2.Server]bool)}
+}
+
+func (b *eventBus) subscribe(srv *jrpc2.Server) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[srv] = true
+}
+
+// publish pushes an event to every subscriber, dropping those that have
+// disconnected
+func (b *eventBus) publish(eventType, message string) {
+	if b == nil {
+		return
+	}
+
+	event := &rpc.Event{
+		Type:    eventType,
+		Time:    time.Now().UnixMilli(),
+		Message: message,
+	}
+
+	b.mu.Lock()
+	subscribers := make([]*jrpc2.Server, 0, len(b.subscribers))
+	for srv := range b.subscribers {
+		subscribers = append(subscribers, srv)
+	}
+	b.mu.Unlock()
+
+	for _, srv := range subscribers {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := srv.Notify(ctx, "event", event)
+		cancel()
+
+		if err != nil {
+			log.Printf("Dropping event subscriber: %v", err)
+			b.mu.Lock()
+			delete(b.subscribers, srv)
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (h *Handlers) handleEventsSubscribe(ctx context.Context) (*rpc.EventsSubscribeResult, error) {
+	h.events.subscribe(jrpc2.ServerFromContext(ctx))
+	return &rpc.EventsSubscribeResult{Subscribed: true}, nil
+}
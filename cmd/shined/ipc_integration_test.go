@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/handler"
 	"github.com/starbased-co/shine/pkg/rpc"
 )
@@ -208,6 +209,71 @@ func TestShinedIPC_ConfigReload(t *testing.T) {
 	}
 }
 
+// TestShinedIPC_Events tests that published events reach subscribed clients
+// only, and that disconnected subscribers are dropped
+func TestShinedIPC_Events(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "shine.sock")
+
+	events := newEventBus()
+	h := &Handlers{events: events}
+	srv := rpc.NewServer(sockPath, handler.Map{
+		"events/subscribe": rpc.HandlerFunc(h.handleEventsSubscribe),
+	}, &jrpc2.ServerOptions{AllowPush: true})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	received := make(chan *rpc.Event, 10)
+	subscriber, err := rpc.NewShinedClient(sockPath, rpc.WithEventHandler(func(e *rpc.Event) { received <- e }))
+	if err != nil {
+		t.Fatalf("NewShinedClient() error: %v", err)
+	}
+	defer subscriber.Close()
+
+	bystander, err := rpc.NewShinedClient(sockPath, rpc.WithEventHandler(func(e *rpc.Event) {
+		t.Errorf("unsubscribed client got %+v", e)
+	}))
+	if err != nil {
+		t.Fatalf("NewShinedClient() error: %v", err)
+	}
+	defer bystander.Close()
+
+	ctx := context.Background()
+	if result, err := subscriber.SubscribeEvents(ctx); err != nil || !result.Subscribed {
+		t.Fatalf("SubscribeEvents() = %+v, %v", result, err)
+	}
+
+	events.publish(eventConfigInvalid, "prism \"clock\": invalid restart policy")
+
+	select {
+	case e := <-received:
+		if e.Type != eventConfigInvalid || e.Message == "" || e.Time == 0 {
+			t.Errorf("received %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event was not pushed to the subscriber")
+	}
+
+	subscriber.Close()
+	<-subscriber.Done()
+
+	waitFor := time.Now().Add(time.Second)
+	for {
+		events.publish(eventConfigReloaded, "")
+		events.mu.Lock()
+		remaining := len(events.subscribers)
+		events.mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(waitFor) {
+			t.Fatal("disconnected subscriber was not dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 // TestShinedIPC_ErrorHandling tests error handling for invalid requests
 func TestShinedIPC_ErrorHandling(t *testing.T) {
 	tmpDir := t.TempDir()
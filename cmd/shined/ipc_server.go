@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/handler"
 	"github.com/starbased-co/shine/pkg/paths"
 	"github.com/starbased-co/shine/pkg/rpc"
@@ -12,7 +13,7 @@ import (
 
 var rpcServer *rpc.Server
 
-func startRPCServer(pm *PanelManager, stateMgr *StateManager, cfgPath string) error {
+func startRPCServer(pm *PanelManager, stateMgr *StateManager, events *eventBus, cfgPath string, watcher *configWatcher) error {
 	runtimeDir := paths.RuntimeDir()
 	if err := os.MkdirAll(runtimeDir, 0755); err != nil {
 		return err
@@ -21,22 +22,28 @@ func startRPCServer(pm *PanelManager, stateMgr *StateManager, cfgPath string) er
 	h := &Handlers{
 		pm:      pm,
 		state:   stateMgr,
+		events:  events,
 		cfgPath: cfgPath,
+		watcher: watcher,
 	}
 
 	mux := handler.Map{
 		"panel/list":      rpc.HandlerFunc(h.handlePanelList),
 		"panel/spawn":     rpc.Handler(h.handlePanelSpawn),
 		"panel/kill":      rpc.Handler(h.handlePanelKill),
+		"panel/reset-failed": rpc.Handler(h.handlePanelResetFailed),
 		"service/status":  rpc.HandlerFunc(h.handleServiceStatus),
 		"config/reload":   rpc.HandlerFunc(h.handleConfigReload),
+		"events/subscribe": rpc.HandlerFunc(h.handleEventsSubscribe),
 		"prism/started":   rpc.Handler(h.handlePrismStarted),
 		"prism/stopped":   rpc.Handler(h.handlePrismStopped),
 		"prism/crashed":   rpc.Handler(h.handlePrismCrashed),
+		"prism/health":    rpc.Handler(h.handlePrismHealth),
 		"foreground/changed": rpc.Handler(h.handleForegroundChanged),
 	}
 
-	rpcServer = rpc.NewServer(paths.ShinedSocket(), mux, nil)
+	// Events are pushed to subscribed clients
+	rpcServer = rpc.NewServer(paths.ShinedSocket(), mux, &jrpc2.ServerOptions{AllowPush: true})
 	if err := rpcServer.Start(); err != nil {
 		return err
 	}
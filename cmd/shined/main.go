@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
@@ -72,13 +73,7 @@ func main() {
 			continue
 		}
 
-		entry := &PrismEntry{
-			PrismConfig: pc,
-			// Restart policies default to "no"
-			Restart:      "no",
-			RestartDelay: "1s",
-			MaxRestarts:  0,
-		}
+		entry := &PrismEntry{PrismConfig: pc}
 
 		if err := entry.ValidateRestartPolicy(); err != nil {
 			log.Fatalf("Invalid restart policy for prism %q: %v", name, err)
@@ -100,13 +95,26 @@ func main() {
 		log.Fatalf("Failed to create panel manager: %v", err)
 	}
 
-	if err := startRPCServer(pm, stateMgr, cfgPath); err != nil {
+	pm.onSpawned = func(panel *Panel) {
+		stateMgr.OnPanelSpawned(panel.Instance, panel.Name, panel.PID, true)
+	}
+
+	events := newEventBus()
+
+	// Changed configs are applied from the main loop, one at a time with
+	// SIGHUP reloads
+	configCh := make(chan *config.Config, 1)
+	watcher := newConfigWatcher(cfgPath, configCh, events)
+	defer watcher.Close()
+
+	if err := startRPCServer(pm, stateMgr, events, cfgPath, watcher); err != nil {
 		log.Fatalf("Failed to start RPC server: %v", err)
 	}
 	defer stopRPCServer()
 
-	if err := spawnConfiguredPanels(pm, prismEntries, stateMgr); err != nil {
-		log.Fatalf("Failed to spawn panels: %v", err)
+	// A panel that fails does not stop the others, only those that require it
+	if err := pm.SpawnPanels(prismEntries); err != nil {
+		log.Printf("Failed to spawn panels: %v", err)
 	}
 
 	sigCh := make(chan os.Signal, 1)
@@ -115,6 +123,8 @@ func main() {
 	healthTicker := time.NewTicker(30 * time.Second)
 	defer healthTicker.Stop()
 
+	watcher.update(pkgCfg)
+
 	log.Println("shined is running (Ctrl+C to stop)")
 
 	for {
@@ -123,7 +133,7 @@ func main() {
 			switch sig {
 			case syscall.SIGHUP:
 				log.Println("Received SIGHUP - reloading configuration")
-				if err := reloadConfig(pm, cfgPath); err != nil {
+				if err := reloadConfig(pm, cfgPath, events, watcher); err != nil {
 					log.Printf("Failed to reload config: %v", err)
 				}
 
@@ -136,6 +146,11 @@ func main() {
 				return
 			}
 
+		case cfg := <-configCh:
+			log.Println("Configuration changed - applying it")
+			applyConfig(pm, cfg, watcher)
+			events.publish(eventConfigReloaded, "")
+
 		case <-healthTicker.C:
 			pm.MonitorPanels()
 		}
@@ -160,52 +175,114 @@ func setupLogging() *os.File {
 	return logFile
 }
 
-func spawnConfiguredPanels(pm *PanelManager, entries []*PrismEntry, stateMgr *StateManager) error {
-	for _, entry := range entries {
-		instanceName := entry.Name
+// configWatcher watches the config while core.watch is set, and sends each
+// changed config that validates to ch. Configs that fail are reported as
+// events and never applied.
+type configWatcher struct {
+	path   string
+	ch     chan<- *config.Config
+	events *eventBus
+
+	mu      sync.Mutex
+	watcher *config.Watcher
+	stop    chan struct{} // closed when watcher is stopped
+}
 
-		log.Printf("Spawning panel for prism: %s (instance: %s, binary: %s)",
-			entry.Name, instanceName, entry.ResolvedPath)
+func newConfigWatcher(configPath string, ch chan<- *config.Config, events *eventBus) *configWatcher {
+	return &configWatcher{path: configPath, ch: ch, events: events}
+}
 
-		panel, err := pm.SpawnPanel(entry, instanceName)
-		if err != nil {
-			return fmt.Errorf("failed to spawn panel for %s: %w", entry.Name, err)
-		}
+// update starts or stops watching as core.watch of cfg says
+func (w *configWatcher) update(cfg *config.Config) {
+	watch := cfg.Core != nil && cfg.Core.Watch
 
-		healthy := pm.CheckHealth(panel)
-		stateMgr.OnPanelSpawned(panel.Instance, panel.Name, panel.PID, healthy)
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-		log.Printf("Panel spawned successfully: %s (socket: %s)",
-			panel.Instance, panel.SocketPath)
+	if watch == (w.watcher != nil) {
+		return
+	}
+	if !watch {
+		w.stopLocked()
+		log.Printf("Stopped watching configuration")
+		return
 	}
 
-	return nil
+	// A config that was changed as the watcher stops is dropped rather
+	// than left blocking a send nobody reads
+	stop := make(chan struct{})
+	watcher, err := config.NewWatcher(w.path,
+		func(cfg *config.Config) {
+			select {
+			case w.ch <- cfg:
+			case <-stop:
+			}
+		},
+		config.WithErrorHandler(func(err error) {
+			log.Printf("Ignoring changed config: %v", err)
+			w.events.publish(eventConfigInvalid, err.Error())
+		}),
+	)
+	if err != nil {
+		log.Printf("Failed to watch config: %v", err)
+		return
+	}
+
+	watcher.Start()
+	w.watcher = watcher
+	w.stop = stop
+	log.Printf("Watching configuration for changes")
 }
 
-func reloadConfig(pm *PanelManager, configPath string) error {
+// Close stops watching
+func (w *configWatcher) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLocked()
+}
+
+// stopLocked stops the watcher if one is running. w.mu must be held.
+func (w *configWatcher) stopLocked() {
+	if w.watcher == nil {
+		return
+	}
+	close(w.stop)
+	w.watcher.Stop()
+	w.watcher = nil
+	w.stop = nil
+}
+
+func reloadConfig(pm *PanelManager, configPath string, events *eventBus, watcher *configWatcher) error {
 	log.Println("Reloading configuration...")
 
 	pkgCfg, err := config.Load(configPath)
 	if err != nil {
+		events.publish(eventConfigInvalid, err.Error())
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	if err := pkgCfg.Validate(); err != nil {
+		events.publish(eventConfigInvalid, err.Error())
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	applyConfig(pm, pkgCfg, watcher)
+	events.publish(eventConfigReloaded, "")
+	return nil
+}
+
+// applyConfig brings the running panels, and whether the config is watched,
+// in line with a validated config
+func applyConfig(pm *PanelManager, pkgCfg *config.Config, watcher *configWatcher) {
+	watcher.update(pkgCfg)
+
 	newEntries := make([]*PrismEntry, 0)
 	for name, pc := range pkgCfg.Prisms {
 		if !pc.Enabled || pc.ResolvedPath == "" {
 			continue
 		}
 
-		entry := &PrismEntry{
-			PrismConfig:  pc,
-			Restart:      "no",
-			RestartDelay: "1s",
-			MaxRestarts:  0,
-		}
+		entry := &PrismEntry{PrismConfig: pc}
 
 		if err := entry.ValidateRestartPolicy(); err != nil {
 			log.Printf("Invalid restart policy for prism %q: %v", name, err)
@@ -239,22 +316,26 @@ func reloadConfig(pm *PanelManager, configPath string) error {
 	}
 
   // spawn  = {x ∈ new : x ∉ current}
+	pm.updateHeldDependents(newPrisms)
+	added := make([]*PrismEntry, 0)
 	for name, entry := range newPrisms {
 		if _, exists := currentPrisms[name]; !exists {
-			instanceName := entry.Name
-
-			log.Printf("Adding new panel for prism: %s (instance: %s)", name, instanceName)
+			log.Printf("Adding new panel for prism: %s (instance: %s)", name, entry.Name)
+			added = append(added, entry)
+		}
+	}
+	if err := pm.SpawnPanels(added); err != nil {
+		log.Printf("Failed to spawn panels: %v", err)
+	}
 
-			panel, err := pm.SpawnPanel(entry, instanceName)
-			if err != nil {
-				log.Printf("Failed to spawn panel for %s: %v", name, err)
-				continue
+  // update = {x ∈ new : x ∈ current}
+	for name, entry := range newPrisms {
+		if panel, exists := currentPrisms[name]; exists {
+			if err := pm.ReconfigurePanel(panel.Instance, entry); err != nil {
+				log.Printf("Failed to reconfigure panel %s: %v", panel.Instance, err)
 			}
-
-			log.Printf("New panel spawned: %s", panel.Instance)
 		}
 	}
 
 	log.Println("Configuration reloaded successfully")
-	return nil
 }
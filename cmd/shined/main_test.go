@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/config"
+)
+
+// TestConfigWatcher tests that the config is watched only while core.watch
+// is set, and that stopping the watcher drops a changed config nobody reads
+func TestConfigWatcher(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "shine.toml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+	}
+	write("[core]\nwatch = true\n")
+
+	// Not read while the main loop applies a config
+	configCh := make(chan *config.Config)
+	w := newConfigWatcher(configPath, configCh, newEventBus())
+	defer w.Close()
+
+	w.update(&config.Config{Core: &config.CoreConfig{Watch: true}})
+	if w.watcher == nil {
+		t.Fatal("config not watched with core.watch set")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	write("[core]\nwatch = true\ntheme = \"dark\"\n")
+
+	select {
+	case cfg := <-configCh:
+		if cfg.Core.Theme != "dark" {
+			t.Errorf("changed config theme = %q, want dark", cfg.Core.Theme)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("changed config not sent")
+	}
+
+	// Changed again, and left waiting for the main loop
+	write("[core]\ntheme = \"light\"\n")
+	time.Sleep(config.DefaultWatchDebounce + 500*time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		w.update(&config.Config{Core: &config.CoreConfig{}})
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher still stopping with core.watch unset")
+	}
+	if w.watcher != nil {
+		t.Error("config still watched with core.watch unset")
+	}
+
+	select {
+	case cfg := <-configCh:
+		t.Errorf("config with theme %q sent after the watcher stopped", cfg.Core.Theme)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -16,6 +16,8 @@ func (h *Handlers) handlePrismStarted(ctx context.Context, n *rpc.PrismStartedNo
 		h.state.OnPanelPrismStarted(n.Panel, n.Name, n.PID)
 	}
 
+	h.pm.MarkPrismStarted(n.Panel, n.Name)
+
 	return &NotificationAck{}, nil
 }
 
@@ -26,7 +28,8 @@ func (h *Handlers) handlePrismStopped(ctx context.Context, n *rpc.PrismStoppedNo
 		h.state.OnPanelPrismStopped(n.Panel, n.Name, n.ExitCode)
 	}
 
-	h.pm.MarkPrismStopped(n.Panel, n.Name, n.ExitCode)
+	h.setPrismHealth(n.Panel, n.Name, true, "")
+	h.pm.MarkPrismStopped(n.Panel, n.Name, n.ExitCode, n.Requested)
 
 	return &NotificationAck{}, nil
 }
@@ -38,11 +41,33 @@ func (h *Handlers) handlePrismCrashed(ctx context.Context, n *rpc.PrismCrashedNo
 		h.state.OnPanelPrismCrashed(n.Panel, n.Name, n.ExitCode, n.Signal)
 	}
 
+	h.setPrismHealth(n.Panel, n.Name, true, "")
 	h.pm.TriggerRestartPolicy(n.Panel, n.Name, n.ExitCode)
 
 	return &NotificationAck{}, nil
 }
 
+func (h *Handlers) handlePrismHealth(ctx context.Context, n *rpc.PrismHealthNotification) (*NotificationAck, error) {
+	if n.Healthy {
+		log.Printf("[%s] prism healthy: %s", n.Panel, n.Name)
+	} else {
+		log.Printf("[%s] prism UNHEALTHY: %s (%s)", n.Panel, n.Name, n.Reason)
+	}
+
+	h.setPrismHealth(n.Panel, n.Name, n.Healthy, n.Reason)
+
+	return &NotificationAck{}, nil
+}
+
+// setPrismHealth records a prism's liveness and updates the panel's health
+// in the shared state when it changed
+func (h *Handlers) setPrismHealth(panel, name string, healthy bool, reason string) {
+	changed, panelHealthy := h.pm.SetPrismHealth(panel, name, healthy, reason)
+	if changed && h.state != nil {
+		h.state.OnPanelHealthChanged(panel, panelHealthy)
+	}
+}
+
 func (h *Handlers) handleForegroundChanged(ctx context.Context, n *rpc.ForegroundChangedNotification) (*NotificationAck, error) {
 	log.Printf("[%s] foreground changed: %s → %s", n.Panel, n.From, n.To)
 
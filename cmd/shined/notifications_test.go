@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/creachadair/jrpc2/handler"
+	"github.com/starbased-co/shine/pkg/config"
 	"github.com/starbased-co/shine/pkg/rpc"
 )
 
@@ -367,3 +368,146 @@ func TestNotificationHandlers_ErrorHandling(t *testing.T) {
 		t.Error("invalid notification should return error")
 	}
 }
+
+// TestNotificationHandlers_PrismHealth tests that liveness results are tracked per panel
+func TestNotificationHandlers_PrismHealth(t *testing.T) {
+	panel := &Panel{Name: "chat", Instance: "chat"}
+	pm := &PanelManager{
+		panels:       map[string]*Panel{"chat": panel},
+		restartState: make(map[string]map[string]*PrismRestartState),
+	}
+	h := &Handlers{pm: pm}
+
+	ctx := context.Background()
+
+	h.handlePrismHealth(ctx, &rpc.PrismHealthNotification{Panel: "chat", Name: "irc", Reason: "no output for 1m0s"})
+	h.handlePrismHealth(ctx, &rpc.PrismHealthNotification{Panel: "chat", Name: "feed", Reason: "probe command failed"})
+
+	unhealthy := pm.UnhealthyPrisms(panel)
+	if len(unhealthy) != 2 || unhealthy[0] != "feed" || unhealthy[1] != "irc" {
+		t.Fatalf("UnhealthyPrisms() = %v, want [feed irc]", unhealthy)
+	}
+
+	h.handlePrismHealth(ctx, &rpc.PrismHealthNotification{Panel: "chat", Name: "irc", Healthy: true})
+
+	// An exit clears the prism's liveness result
+	h.handlePrismStopped(ctx, &rpc.PrismStoppedNotification{Panel: "chat", Name: "feed"})
+
+	if unhealthy := pm.UnhealthyPrisms(panel); len(unhealthy) != 0 {
+		t.Errorf("UnhealthyPrisms() = %v, want none", unhealthy)
+	}
+
+	if changed, _ := pm.SetPrismHealth("gone", "irc", false, "timeout"); changed {
+		t.Error("SetPrismHealth() should ignore unknown panels")
+	}
+}
+
+// TestNotificationHandlers_RestartPolicy tests restarts, the failed state
+// and resetting it against a fake prismctl
+func TestNotificationHandlers_RestartPolicy(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	ups := make(chan string, 10)
+	srv := rpc.NewServer(sockPath, handler.Map{
+		"prism/up": handler.New(func(ctx context.Context, req *rpc.UpRequest) (*rpc.UpResult, error) {
+			ups <- req.Name
+			return &rpc.UpResult{ID: req.Name}, nil
+		}),
+	}, nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	entry := &PrismEntry{PrismConfig: &config.PrismConfig{
+		Name:         "chat",
+		Restart:      "on-failure",
+		RestartDelay: "1ms",
+		MaxRestarts:  2,
+		Apps: map[string]*config.AppConfig{
+			"irc":  {Enabled: true},
+			"feed": {Enabled: true, Restart: "unless-stopped"},
+		},
+	}}
+	panel := &Panel{Name: "chat", Instance: "chat", SocketPath: sockPath, Config: entry}
+	pm := &PanelManager{
+		panels:       map[string]*Panel{"chat": panel},
+		restartState: make(map[string]map[string]*PrismRestartState),
+	}
+	h := &Handlers{pm: pm}
+	ctx := context.Background()
+
+	expectUp := func(want string) {
+		t.Helper()
+		select {
+		case name := <-ups:
+			if name != want {
+				t.Errorf("restarted %q, want %q", name, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not restarted", want)
+		}
+	}
+	expectNoUp := func() {
+		t.Helper()
+		select {
+		case name := <-ups:
+			t.Errorf("unexpected restart of %q", name)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// on-failure: a crash restarts, a clean exit does not
+	h.handlePrismCrashed(ctx, &rpc.PrismCrashedNotification{Panel: "chat", Name: "irc", ExitCode: 1})
+	expectUp("irc")
+	h.handlePrismStopped(ctx, &rpc.PrismStoppedNotification{Panel: "chat", Name: "irc"})
+	expectNoUp()
+
+	// unless-stopped, overridden per app: a clean exit restarts a second
+	// instance, a requested stop does not
+	h.handlePrismStopped(ctx, &rpc.PrismStoppedNotification{Panel: "chat", Name: "feed:2"})
+	expectUp("feed:2")
+	h.handlePrismStopped(ctx, &rpc.PrismStoppedNotification{Panel: "chat", Name: "feed", ExitCode: 143, Requested: true})
+	expectNoUp()
+
+	// The third crash within the hour exceeds max_restarts
+	h.handlePrismCrashed(ctx, &rpc.PrismCrashedNotification{Panel: "chat", Name: "irc", ExitCode: 1})
+	expectUp("irc")
+	h.handlePrismCrashed(ctx, &rpc.PrismCrashedNotification{Panel: "chat", Name: "irc", ExitCode: 1})
+	expectNoUp()
+
+	if failed := pm.FailedPrisms(panel); len(failed) != 1 || failed[0] != "irc" {
+		t.Fatalf("FailedPrisms() = %v, want [irc]", failed)
+	}
+
+	result, err := h.handlePanelResetFailed(ctx, &rpc.PanelResetFailedRequest{Instance: "chat"})
+	if err != nil {
+		t.Fatalf("handlePanelResetFailed() error: %v", err)
+	}
+	if len(result.Reset) != 1 || result.Reset[0] != "irc" {
+		t.Errorf("Reset = %v, want [irc]", result.Reset)
+	}
+	expectUp("irc")
+
+	if failed := pm.FailedPrisms(panel); len(failed) != 0 {
+		t.Errorf("FailedPrisms() after reset = %v, want none", failed)
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	base := time.Second
+
+	for attempt, want := range []time.Duration{base, 2 * base, 4 * base, 8 * base} {
+		got := restartBackoff(base, attempt)
+		if got < want || got >= want+want/4+1 {
+			t.Errorf("restartBackoff(%v, %d) = %v, want %v plus up to a quarter", base, attempt, got, want)
+		}
+	}
+
+	if got := restartBackoff(base, 100); got < restartBackoffMax || got > restartBackoffMax+restartBackoffMax/4 {
+		t.Errorf("restartBackoff() = %v, want it capped at %v plus jitter", got, restartBackoffMax)
+	}
+	if got := restartBackoff(0, 3); got != 0 {
+		t.Errorf("restartBackoff(0, 3) = %v, want 0", got)
+	}
+}
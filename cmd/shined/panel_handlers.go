@@ -13,7 +13,9 @@ import (
 type Handlers struct {
 	pm       *PanelManager
 	state    *StateManager
+	events   *eventBus
 	cfgPath  string
+	watcher  *configWatcher
 }
 
 func (h *Handlers) handlePanelList(ctx context.Context) (*rpc.PanelListResult, error) {
@@ -24,13 +26,17 @@ func (h *Handlers) handlePanelList(ctx context.Context) (*rpc.PanelListResult, e
 	}
 
 	for i, panel := range panels {
-		healthy := h.pm.CheckHealth(panel)
+		unhealthy := h.pm.UnhealthyPrisms(panel)
+		healthy := h.pm.CheckHealth(panel) && len(unhealthy) == 0
 		result.Panels[i] = rpc.PanelInfo{
 			Instance: panel.Instance,
 			Name:     panel.Name,
 			PID:      panel.PID,
 			Socket:   panel.SocketPath,
 			Healthy:  healthy,
+
+			Unhealthy: unhealthy,
+			Failed:    h.pm.FailedPrisms(panel),
 		}
 	}
 
@@ -111,6 +117,30 @@ func (h *Handlers) handlePanelKill(ctx context.Context, req *rpc.PanelKillReques
 	return &rpc.PanelKillResult{Killed: true}, nil
 }
 
+// handlePanelResetFailed clears prisms that exceeded max_restarts and starts
+// them again
+func (h *Handlers) handlePanelResetFailed(ctx context.Context, req *rpc.PanelResetFailedRequest) (*rpc.PanelResetFailedResult, error) {
+	if req.Instance == "" {
+		return nil, rpc.ErrInvalidParams("instance name required")
+	}
+
+	if _, ok := h.pm.GetPanel(req.Instance); !ok {
+		return nil, rpc.ErrPanelNotFound(req.Instance)
+	}
+
+	log.Printf("panel/reset-failed: %s %s", req.Instance, req.Name)
+
+	reset, err := h.pm.ResetFailed(req.Instance, req.Name)
+	if err != nil {
+		return nil, rpc.ErrOperationFailed("reset-failed", err)
+	}
+	if reset == nil {
+		reset = []string{}
+	}
+
+	return &rpc.PanelResetFailedResult{Reset: reset}, nil
+}
+
 func (h *Handlers) handleServiceStatus(ctx context.Context) (*rpc.ServiceStatusResult, error) {
 	panels := h.pm.ListPanels()
 
@@ -121,13 +151,17 @@ func (h *Handlers) handleServiceStatus(ctx context.Context) (*rpc.ServiceStatusR
 	}
 
 	for i, panel := range panels {
-		healthy := h.pm.CheckHealth(panel)
+		unhealthy := h.pm.UnhealthyPrisms(panel)
+		healthy := h.pm.CheckHealth(panel) && len(unhealthy) == 0
 		result.Panels[i] = rpc.PanelInfo{
 			Instance: panel.Instance,
 			Name:     panel.Name,
 			PID:      panel.PID,
 			Socket:   panel.SocketPath,
 			Healthy:  healthy,
+
+			Unhealthy: unhealthy,
+			Failed:    h.pm.FailedPrisms(panel),
 		}
 	}
 
@@ -137,7 +171,7 @@ func (h *Handlers) handleServiceStatus(ctx context.Context) (*rpc.ServiceStatusR
 func (h *Handlers) handleConfigReload(ctx context.Context) (*rpc.ConfigReloadResult, error) {
 	log.Println("config/reload via RPC")
 
-	err := reloadConfig(h.pm, h.cfgPath)
+	err := reloadConfig(h.pm, h.cfgPath, h.events, h.watcher)
 	if err != nil {
 		return &rpc.ConfigReloadResult{
 			Reloaded: false,
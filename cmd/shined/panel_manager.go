@@ -5,13 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/starbased-co/shine/pkg/config"
 	"github.com/starbased-co/shine/pkg/paths"
 	"github.com/starbased-co/shine/pkg/rpc"
 )
@@ -26,12 +29,15 @@ type Panel struct {
 	Config     *PrismEntry
 	CrashCount int
 	LastCrash  time.Time
+	Unhealthy  map[string]string // prism → liveness probe failure, guarded by PanelManager.mu
 }
 
 type PrismRestartState struct {
 	RestartCount      int
 	RestartTimestamps []time.Time
 	ExplicitlyStopped bool
+	Backoff           int  // restarts in quick succession, each doubles the delay
+	Failed            bool // exceeded max_restarts, cleared by ResetFailed
 }
 
 type PanelManager struct {
@@ -40,6 +46,8 @@ type PanelManager struct {
 	logDir   string
 	prismctlBin string
 	restartState map[string]map[string]*PrismRestartState
+	heldDependents map[string][]*PrismEntry // failed panel → dependents stopped with it
+	onSpawned func(*Panel) // called by SpawnPanels with every healthy new panel
 }
 
 func getPIDFromWindowID(windowID string) (int, error) {
@@ -106,6 +114,7 @@ func NewPanelManager() (*PanelManager, error) {
 		logDir:       logDir,
 		prismctlBin:  prismctlBin,
 		restartState: make(map[string]map[string]*PrismRestartState),
+		heldDependents: make(map[string][]*PrismEntry),
 	}, nil
 }
 
@@ -178,10 +187,14 @@ func (pm *PanelManager) SpawnPanel(config *PrismEntry, instanceName string) (*Pa
 	return panel, nil
 }
 
-func (pm *PanelManager) configureApps(panel *Panel, config *PrismEntry) error {
+// configureRequest builds the prism/configure request for a prism's config,
+// which also serves prism/reconfigure
+func configureRequest(config *PrismEntry) *rpc.ConfigureRequest {
 	apps := make([]rpc.AppInfo, 0)
 
-	for name, appCfg := range config.GetApps() {
+	appCfgs := config.GetApps()
+	for _, name := range config.SortedAppNames() {
+		appCfg := appCfgs[name]
 		if appCfg == nil || !appCfg.Enabled || appCfg.ResolvedPath == "" {
 			continue
 		}
@@ -189,17 +202,46 @@ func (pm *PanelManager) configureApps(panel *Panel, config *PrismEntry) error {
 			Name:    name,
 			Path:    appCfg.ResolvedPath,
 			Enabled: appCfg.Enabled,
+			Args:    appCfg.Args,
+			Env:     config.AppEnv(appCfg),
+			Cwd:     config.AppCwd(appCfg),
+			Size:    appCfg.Size,
+			Weight:  appCfg.Weight,
+
+			StopSignal:  config.AppStopSignal(appCfg),
+			StopTimeout: config.AppStopTimeout(appCfg),
+			Liveness:    livenessInfo(config.AppLiveness(appCfg)),
+
+			Background:      config.AppBackground(appCfg),
+			LifecycleSignal: config.AppLifecycleSignal(appCfg),
+
+			Theme:    config.Theme,
+			Settings: config.AppSettings(appCfg),
 		})
 	}
 
-	if len(apps) == 0 {
+	return &rpc.ConfigureRequest{
+		Apps:   apps,
+		Layout: config.Layout,
+		Prefix: config.Prefix,
+		Record: config.Record,
+
+		KeepAlive:       config.KeepAlive,
+		Placeholder:     config.Placeholder,
+		PlaceholderText: config.PlaceholderText,
+	}
+}
+
+func (pm *PanelManager) configureApps(panel *Panel, config *PrismEntry) error {
+	req := configureRequest(config)
+	if len(req.Apps) == 0 {
 		return fmt.Errorf("no enabled apps with resolved paths")
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	result, err := panel.RPCClient.Configure(ctx, apps)
+	result, err := panel.RPCClient.Configure(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -221,14 +263,19 @@ func (pm *PanelManager) KillPanel(instanceName string) error {
 		return fmt.Errorf("panel %s not found", instanceName)
 	}
 
+	pm.killPanelLocked(panel)
+	return nil
+}
+
+// killPanelLocked closes a panel's window and forgets it. pm.mu must be held.
+func (pm *PanelManager) killPanelLocked(panel *Panel) {
 	cmd := exec.Command("kitten", "@", "close-window", "--match", fmt.Sprintf("id:%s", panel.WindowID))
 	if err := cmd.Run(); err != nil {
 		log.Printf("Warning: failed to close window %s: %v", panel.WindowID, err)
 	}
 
-	delete(pm.panels, instanceName)
-	log.Printf("Killed panel %s (window ID: %s)", instanceName, panel.WindowID)
-	return nil
+	delete(pm.panels, panel.Instance)
+	log.Printf("Killed panel %s (window ID: %s)", panel.Instance, panel.WindowID)
 }
 
 func (pm *PanelManager) GetPanel(instanceName string) (*Panel, bool) {
@@ -258,6 +305,63 @@ func (pm *PanelManager) CheckHealth(panel *Panel) bool {
 	return err == nil
 }
 
+// SetPrismHealth records the result of a prism's liveness probe. It reports
+// whether the prism's health changed, and whether the panel's prisms are
+// all healthy now.
+func (pm *PanelManager) SetPrismHealth(panelInstance, prismName string, healthy bool, reason string) (changed, panelHealthy bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	panel, ok := pm.panels[panelInstance]
+	if !ok {
+		return false, false
+	}
+
+	_, wasUnhealthy := panel.Unhealthy[prismName]
+	if healthy {
+		delete(panel.Unhealthy, prismName)
+	} else {
+		if panel.Unhealthy == nil {
+			panel.Unhealthy = make(map[string]string)
+		}
+		panel.Unhealthy[prismName] = reason
+	}
+
+	return wasUnhealthy == healthy, len(panel.Unhealthy) == 0
+}
+
+// UnhealthyPrisms returns the sorted names of the panel's prisms that are
+// failing their liveness probe
+func (pm *PanelManager) UnhealthyPrisms(panel *Panel) []string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if len(panel.Unhealthy) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(panel.Unhealthy))
+	for name := range panel.Unhealthy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func livenessInfo(cfg *config.LivenessConfig) *rpc.LivenessInfo {
+	if cfg == nil {
+		return nil
+	}
+	return &rpc.LivenessInfo{
+		Probe:    cfg.Probe,
+		Command:  cfg.Command,
+		Interval: cfg.Interval,
+		Timeout:  cfg.Timeout,
+		Failures: cfg.Failures,
+		Action:   cfg.Action,
+	}
+}
+
 func (pm *PanelManager) MonitorPanels() {
 	panels := pm.ListPanels()
 
@@ -273,7 +377,12 @@ func (pm *PanelManager) handlePanelCrash(panel *Panel) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	if pm.panels[panel.Instance] != panel {
+		// Already replaced, or stopped along with a panel it requires
+		return
+	}
 	delete(pm.panels, panel.Instance)
+	pm.stopDependentsLocked(panel.Instance)
 
 	now := time.Now()
 	if now.Sub(panel.LastCrash) > time.Hour {
@@ -303,27 +412,34 @@ func (pm *PanelManager) handlePanelCrash(panel *Panel) {
 		shouldRestart = false
 	}
 
-	if shouldRestart {
-		delay := panel.Config.GetRestartDelay()
-		log.Printf("Restarting panel %s after %v delay", panel.Instance, delay)
+	if !shouldRestart {
+		pm.dropDependentsLocked(panel.Instance)
+		return
+	}
 
-		go func() {
-			time.Sleep(delay)
-			pm.mu.Lock()
-			defer pm.mu.Unlock()
-
-			newPanel, err := pm.spawnPanelUnlocked(panel.Config, panel.Instance)
-			if err != nil {
-				log.Printf("Failed to restart panel %s: %v", panel.Instance, err)
-				return
-			}
+	delay := panel.Config.GetRestartDelay()
+	log.Printf("Restarting panel %s after %v delay", panel.Instance, delay)
 
+	go func() {
+		time.Sleep(delay)
+		pm.mu.Lock()
+		newPanel, err := pm.spawnPanelUnlocked(panel.Config, panel.Instance)
+		if err == nil {
 			newPanel.CrashCount = panel.CrashCount
 			newPanel.LastCrash = panel.LastCrash
+		} else {
+			pm.dropDependentsLocked(panel.Instance)
+		}
+		pm.mu.Unlock()
 
-			log.Printf("Successfully restarted panel %s", panel.Instance)
-		}()
-	}
+		if err != nil {
+			log.Printf("Failed to restart panel %s: %v", panel.Instance, err)
+			return
+		}
+
+		log.Printf("Successfully restarted panel %s", panel.Instance)
+		pm.restartDependents(panel.Instance)
+	}()
 }
 
 func (pm *PanelManager) spawnPanelUnlocked(config *PrismEntry, instanceName string) (*Panel, error) {
@@ -427,16 +543,32 @@ func pruneRestartTimestamps(timestamps []time.Time) []time.Time {
 	return pruned
 }
 
-func (pm *PanelManager) MarkPrismStopped(panelInstance, prismName string, exitCode int) {
+// MarkPrismStarted forgets that a prism was stopped on request, so its
+// restart policy applies again
+func (pm *PanelManager) MarkPrismStarted(panelInstance, prismName string) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	state := pm.getRestartState(panelInstance, prismName)
+	if state := pm.restartState[panelInstance][prismName]; state != nil {
+		state.ExplicitlyStopped = false
+	}
+}
 
-	if exitCode == 0 {
+// MarkPrismStopped records a prism that exited cleanly or was stopped on
+// request. A requested stop is never undone by a restart policy; a clean exit
+// restarts the prism under "always" and "unless-stopped".
+func (pm *PanelManager) MarkPrismStopped(panelInstance, prismName string, exitCode int, requested bool) {
+	pm.mu.Lock()
+	state := pm.getRestartState(panelInstance, prismName)
+	if requested {
 		state.ExplicitlyStopped = true
 		log.Printf("[%s] Prism %s marked as explicitly stopped", panelInstance, prismName)
 	}
+	pm.mu.Unlock()
+
+	if !requested {
+		pm.TriggerRestartPolicy(panelInstance, prismName, exitCode)
+	}
 }
 
 func (pm *PanelManager) TriggerRestartPolicy(panelInstance, prismName string, exitCode int) {
@@ -444,30 +576,36 @@ func (pm *PanelManager) TriggerRestartPolicy(panelInstance, prismName string, ex
 	defer pm.mu.Unlock()
 
 	panel, ok := pm.panels[panelInstance]
-	if !ok {
+	if !ok || panel.Config == nil {
 		log.Printf("Panel %s not found, cannot restart prism %s", panelInstance, prismName)
 		return
 	}
 
 	state := pm.getRestartState(panelInstance, prismName)
+	if state.Failed {
+		log.Printf("[%s] Not restarting prism %s: it failed and needs shine reset-failed", panelInstance, prismName)
+		return
+	}
+
 	state.RestartTimestamps = pruneRestartTimestamps(state.RestartTimestamps)
 	state.RestartCount = len(state.RestartTimestamps)
 
-	policy := panel.Config.GetRestartPolicy()
-	maxRestarts := panel.Config.MaxRestarts
-	restartDelay := panel.Config.GetRestartDelay()
+	spec := panel.Config.restartSpec(prismName)
 
 	shouldRestart := false
 	reason := ""
 
-	switch policy {
+	switch spec.policy {
 	case RestartNo:
 		reason = "policy is 'no'"
 		shouldRestart = false
 
 	case RestartAlways:
 		reason = "policy is 'always'"
-		shouldRestart = true
+		shouldRestart = !state.ExplicitlyStopped
+		if state.ExplicitlyStopped {
+			reason = "policy is 'always' but prism was explicitly stopped"
+		}
 
 	case RestartOnFailure:
 		if exitCode != 0 {
@@ -493,20 +631,54 @@ func (pm *PanelManager) TriggerRestartPolicy(panelInstance, prismName string, ex
 		return
 	}
 
-	if maxRestarts > 0 && state.RestartCount >= maxRestarts {
-		log.Printf("[%s] Prism %s exceeded max_restarts (%d/%d), not restarting",
-			panelInstance, prismName, state.RestartCount, maxRestarts)
+	if spec.maxRestarts > 0 && state.RestartCount >= spec.maxRestarts {
+		state.Failed = true
+		log.Printf("[%s] Prism %s exceeded max_restarts (%d/%d), marking it failed",
+			panelInstance, prismName, state.RestartCount, spec.maxRestarts)
 		return
 	}
 
+	// An app that ran for a while since its last restart backs off from
+	// restart_delay again
+	if n := len(state.RestartTimestamps); n == 0 || time.Since(state.RestartTimestamps[n-1]) > restartBackoffReset {
+		state.Backoff = 0
+	}
+	delay := restartBackoff(spec.delay, state.Backoff)
+	state.Backoff++
+
 	log.Printf("[%s] Will restart prism %s: %s (restart count: %d, delay: %v)",
-		panelInstance, prismName, reason, state.RestartCount, restartDelay)
+		panelInstance, prismName, reason, state.RestartCount, delay)
 
 	state.RestartTimestamps = append(state.RestartTimestamps, time.Now())
 	state.RestartCount = len(state.RestartTimestamps)
 	state.ExplicitlyStopped = false
 
-	go pm.restartPrismAsync(panel, prismName, restartDelay, state.RestartCount)
+	go pm.restartPrismAsync(panel, prismName, delay, state.RestartCount)
+}
+
+const (
+	// restartBackoffMax caps the delay between restarts
+	restartBackoffMax = 5 * time.Minute
+	// restartBackoffReset is how long after its last restart an app has to
+	// run for the delay to start from restart_delay again
+	restartBackoffReset = 10 * time.Minute
+)
+
+// restartBackoff returns the delay before restart attempt (counting from
+// 0): base doubled per attempt up to restartBackoffMax, plus up to a quarter
+// of that again as jitter, so apps that crash together do not restart in
+// lockstep
+func restartBackoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < restartBackoffMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, restartBackoffMax)
+
+	if jitter := delay / 4; jitter > 0 {
+		delay += rand.N(jitter)
+	}
+	return delay
 }
 
 func (pm *PanelManager) restartPrismAsync(panel *Panel, prismName string, delay time.Duration, restartCount int) {
@@ -514,10 +686,19 @@ func (pm *PanelManager) restartPrismAsync(panel *Panel, prismName string, delay
 
 	log.Printf("[%s] Attempting restart #%d of prism %s", panel.Instance, restartCount, prismName)
 
+	if err := startPrism(panel, prismName); err != nil {
+		log.Printf("[%s] Failed to restart prism %s: %v", panel.Instance, prismName, err)
+		return
+	}
+
+	log.Printf("[%s] Successfully restarted prism %s", panel.Instance, prismName)
+}
+
+// startPrism starts a prism of panel over a connection of its own
+func startPrism(panel *Panel, prismName string) error {
 	client, err := rpc.NewPrismClient(panel.SocketPath)
 	if err != nil {
-		log.Printf("[%s] Failed to create RPC client for restart: %v", panel.Instance, err)
-		return
+		return fmt.Errorf("failed to create RPC client: %w", err)
 	}
 	defer client.Close()
 
@@ -525,10 +706,52 @@ func (pm *PanelManager) restartPrismAsync(panel *Panel, prismName string, delay
 	defer cancel()
 
 	_, err = client.Up(ctx, prismName)
-	if err != nil {
-		log.Printf("[%s] Failed to restart prism %s: %v", panel.Instance, prismName, err)
-		return
+	return err
+}
+
+// FailedPrisms returns the sorted names of the panel's prisms that exceeded
+// max_restarts
+func (pm *PanelManager) FailedPrisms(panel *Panel) []string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var names []string
+	for name, state := range pm.restartState[panel.Instance] {
+		if state.Failed {
+			names = append(names, name)
+		}
 	}
+	sort.Strings(names)
+	return names
+}
 
-	log.Printf("[%s] Successfully restarted prism %s", panel.Instance, prismName)
+// ResetFailed clears the failed state and restart history of the panel's
+// prism, or of all its failed prisms when prismName is empty, and starts
+// them again. It returns the prisms it reset.
+func (pm *PanelManager) ResetFailed(panelInstance, prismName string) ([]string, error) {
+	pm.mu.Lock()
+	panel, ok := pm.panels[panelInstance]
+	if !ok {
+		pm.mu.Unlock()
+		return nil, fmt.Errorf("panel %s not found", panelInstance)
+	}
+
+	var reset []string
+	for name, state := range pm.restartState[panelInstance] {
+		if state.Failed && (prismName == "" || name == prismName) {
+			delete(pm.restartState[panelInstance], name)
+			reset = append(reset, name)
+		}
+	}
+	pm.mu.Unlock()
+
+	sort.Strings(reset)
+	for _, name := range reset {
+		log.Printf("[%s] Reset failed prism %s, starting it again", panelInstance, name)
+		if err := startPrism(panel, name); err != nil {
+			return reset, fmt.Errorf("failed to start %s: %w", name, err)
+		}
+	}
+
+	return reset, nil
 }
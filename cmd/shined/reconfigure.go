@@ -0,0 +1,180 @@
+// reconfigure.go applies a reloaded config to panels that are already
+// running. Each panel's old and new config are compared: geometry and focus
+// policy are applied to the panel's kitty window in place, and changes to
+// the apps go to prismctl as a declarative prism/reconfigure, which leaves
+// unchanged apps running. A panel is respawned only when that cannot work:
+// when it moves to another output, when its layout changes, or when kitty
+// fails to resize it.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"reflect"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/paths"
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+// panelDiff is what changed between two configs of a panel
+type panelDiff struct {
+	respawn  bool // output or layout, needs a new panel
+	geometry bool // size, position or focus policy
+	apps     bool // apps, their settings or the panel's prefix, idle and recording settings
+}
+
+func (d panelDiff) changed() bool {
+	return d.respawn || d.geometry || d.apps
+}
+
+func diffPanel(current, next *PrismEntry) panelDiff {
+	currentPanel, nextPanel := current.ToPanelConfig(), next.ToPanelConfig()
+	currentReq, nextReq := configureRequest(current), configureRequest(next)
+
+	return panelDiff{
+		respawn:  currentPanel.OutputName != nextPanel.OutputName || !sameLayout(currentReq, nextReq),
+		geometry: *currentPanel != *nextPanel,
+		apps:     !reflect.DeepEqual(currentReq, nextReq),
+	}
+}
+
+// sameLayout reports whether two configure requests have the same layout.
+// Split layouts have a pane per app, so their apps must match as well.
+func sameLayout(a, b *rpc.ConfigureRequest) bool {
+	layout := func(l string) string {
+		if l == "" {
+			return "single"
+		}
+		return l
+	}
+
+	if layout(a.Layout) != layout(b.Layout) {
+		return false
+	}
+	if layout(a.Layout) == "single" {
+		return true
+	}
+
+	if len(a.Apps) != len(b.Apps) {
+		return false
+	}
+	for i := range a.Apps {
+		if a.Apps[i].Name != b.Apps[i].Name || a.Apps[i].Size != b.Apps[i].Size || a.Apps[i].Weight != b.Apps[i].Weight {
+			return false
+		}
+	}
+	return true
+}
+
+// ReconfigurePanel applies entry to a running panel, changing only what
+// differs from the panel's current config
+func (pm *PanelManager) ReconfigurePanel(instanceName string, entry *PrismEntry) error {
+	pm.mu.Lock()
+	panel, ok := pm.panels[instanceName]
+	pm.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("panel %s not found", instanceName)
+	}
+
+	diff := diffPanel(panel.Config, entry)
+	if !diff.changed() {
+		pm.mu.Lock()
+		panel.Config = entry // restart policies may still differ
+		pm.mu.Unlock()
+		return nil
+	}
+
+	if diff.respawn {
+		log.Printf("Respawning panel %s: its output or layout changed", instanceName)
+		return pm.respawnPanel(instanceName, entry)
+	}
+
+	if diff.geometry {
+		if err := resizePanel(panel, entry); err != nil {
+			log.Printf("Failed to resize panel %s, respawning it: %v", instanceName, err)
+			return pm.respawnPanel(instanceName, entry)
+		}
+		log.Printf("Resized panel %s", instanceName)
+	}
+
+	if diff.apps {
+		if err := pm.reconfigureApps(panel, entry); err != nil {
+			return fmt.Errorf("failed to reconfigure apps: %w", err)
+		}
+	}
+
+	pm.mu.Lock()
+	panel.Config = entry
+	pm.mu.Unlock()
+
+	return nil
+}
+
+// resizePanel applies entry's geometry and focus policy to the panel's
+// kitty window
+func resizePanel(panel *Panel, entry *PrismEntry) error {
+	cmd := exec.Command("kitten", entry.ToPanelConfig().ToResizeArgs(panel.WindowID)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// reconfigureApps sends the panel its new app set. Removed apps lose their
+// restart state, so they no longer show up as failed.
+func (pm *PanelManager) reconfigureApps(panel *Panel, entry *PrismEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := panel.RPCClient.Reconfigure(ctx, configureRequest(entry))
+	if err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	for _, name := range result.Stopped {
+		delete(pm.restartState[panel.Instance], name)
+	}
+	pm.mu.Unlock()
+
+	log.Printf("Reconfigured panel %s: started %v, stopped %v, updated %v",
+		panel.Instance, result.Started, result.Stopped, result.Updated)
+
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("failed to start apps: %v", result.Failed)
+	}
+	return nil
+}
+
+// respawnPanel replaces a panel with a new one for entry
+func (pm *PanelManager) respawnPanel(instanceName string, entry *PrismEntry) error {
+	if err := pm.KillPanel(instanceName); err != nil {
+		return err
+	}
+
+	// The old prismctl removes its socket on the way out; a new panel is
+	// only up once the new one has created it
+	socketPath := paths.PrismSocket(instanceName)
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(socketPath); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if _, err := pm.SpawnPanel(entry, instanceName); err != nil {
+		// Nothing restarts the panel, nor the panels that require it
+		pm.mu.Lock()
+		pm.stopDependentsLocked(instanceName)
+		pm.dropDependentsLocked(instanceName)
+		pm.mu.Unlock()
+		return err
+	}
+	return nil
+}
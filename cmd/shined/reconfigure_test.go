@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/creachadair/jrpc2/handler"
+	"github.com/starbased-co/shine/pkg/config"
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+func testEntry(edit func(pc *config.PrismConfig)) *PrismEntry {
+	pc := &config.PrismConfig{
+		Name:   "chat",
+		Origin: "top-center",
+		Height: 2,
+		Apps: map[string]*config.AppConfig{
+			"irc":  {Enabled: true, ResolvedPath: "/usr/bin/irc"},
+			"feed": {Enabled: true, ResolvedPath: "/usr/bin/feed"},
+		},
+	}
+	if edit != nil {
+		edit(pc)
+	}
+	return &PrismEntry{PrismConfig: pc}
+}
+
+func TestDiffPanel(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(pc *config.PrismConfig)
+		want panelDiff
+	}{
+		{"unchanged", nil, panelDiff{}},
+		{"restart policy only", func(pc *config.PrismConfig) { pc.Restart = "always" }, panelDiff{}},
+		{"height", func(pc *config.PrismConfig) { pc.Height = 3 }, panelDiff{geometry: true}},
+		{"focus policy", func(pc *config.PrismConfig) { pc.FocusPolicy = "on-demand" }, panelDiff{geometry: true}},
+		{"output", func(pc *config.PrismConfig) { pc.OutputName = "DP-2" }, panelDiff{respawn: true, geometry: true}},
+		{"binary path", func(pc *config.PrismConfig) { pc.Apps["irc"].ResolvedPath = "/opt/irc" }, panelDiff{apps: true}},
+		{"added app", func(pc *config.PrismConfig) {
+			pc.Apps["mail"] = &config.AppConfig{Enabled: true, ResolvedPath: "/usr/bin/mail"}
+		}, panelDiff{apps: true}},
+		{"prefix", func(pc *config.PrismConfig) { pc.Prefix = "C-a" }, panelDiff{apps: true}},
+		{"layout", func(pc *config.PrismConfig) { pc.Layout = "hsplit" }, panelDiff{respawn: true, apps: true}},
+	}
+
+	current := testEntry(nil)
+	for _, tt := range tests {
+		if got := diffPanel(current, testEntry(tt.edit)); got != tt.want {
+			t.Errorf("%s: diffPanel() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// In a split layout every app has a pane
+	split := testEntry(func(pc *config.PrismConfig) { pc.Layout = "vsplit" })
+	resized := testEntry(func(pc *config.PrismConfig) {
+		pc.Layout = "vsplit"
+		pc.Apps["irc"].Size = 10
+	})
+	if d := diffPanel(split, resized); !d.respawn {
+		t.Errorf("diffPanel() = %+v, want a respawn for a resized pane", d)
+	}
+}
+
+// TestReconfigurePanel_Apps tests that app changes reach prismctl without
+// touching the panel's window
+func TestReconfigurePanel_Apps(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "prism.sock")
+
+	reqs := make(chan *rpc.ConfigureRequest, 1)
+	srv := rpc.NewServer(sockPath, handler.Map{
+		"prism/reconfigure": handler.New(func(ctx context.Context, req *rpc.ConfigureRequest) (*rpc.ReconfigureResult, error) {
+			reqs <- req
+			return &rpc.ReconfigureResult{Started: []string{"mail"}, Stopped: []string{"feed"}}, nil
+		}),
+	}, nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	client, err := rpc.NewPrismClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewPrismClient() error: %v", err)
+	}
+	defer client.Close()
+
+	current := testEntry(nil)
+	panel := &Panel{Name: "chat", Instance: "chat", SocketPath: sockPath, RPCClient: client, Config: current}
+	pm := &PanelManager{
+		panels: map[string]*Panel{"chat": panel},
+		restartState: map[string]map[string]*PrismRestartState{
+			"chat": {"feed": {Failed: true}},
+		},
+	}
+
+	next := testEntry(func(pc *config.PrismConfig) {
+		delete(pc.Apps, "feed")
+		pc.Apps["mail"] = &config.AppConfig{Enabled: true, ResolvedPath: "/usr/bin/mail"}
+	})
+	if err := pm.ReconfigurePanel("chat", next); err != nil {
+		t.Fatalf("ReconfigurePanel() error: %v", err)
+	}
+
+	req := <-reqs
+	if len(req.Apps) != 2 || req.Apps[0].Name != "irc" || req.Apps[1].Name != "mail" {
+		t.Errorf("prism/reconfigure apps = %+v, want irc and mail", req.Apps)
+	}
+	if panel.Config != next {
+		t.Error("the panel should have its new config")
+	}
+	if failed := pm.FailedPrisms(panel); len(failed) != 0 {
+		t.Errorf("FailedPrisms() = %v, want the removed app forgotten", failed)
+	}
+}
@@ -0,0 +1,89 @@
+// Package attach is the protocol spoken on a prismctl attach socket. A client
+// terminal connects, introduces itself with a Hello, and from then on sends
+// its input and size changes while prismctl sends the panel's output back.
+//
+// Every message is a frame: a one-byte type, a big-endian uint32 payload
+// length and the payload.
+package attach
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Frame types
+const (
+	MsgHello  byte = 'H' // client → prismctl: Hello as JSON, first frame only
+	MsgInput  byte = 'I' // client → prismctl: raw input bytes
+	MsgResize byte = 'R' // client → prismctl: Size as JSON
+	MsgOutput byte = 'O' // prismctl → client: raw output bytes
+	MsgDetach byte = 'D' // prismctl → client: the client was detached, payload is the reason
+)
+
+// maxPayload bounds a single frame so a bad peer cannot make us allocate
+// arbitrary amounts of memory
+const maxPayload = 1 << 20
+
+// Size is a terminal's window size in cells and pixels
+type Size struct {
+	Cols   uint16 `json:"cols"`
+	Rows   uint16 `json:"rows"`
+	Xpixel uint16 `json:"xpixel,omitempty"`
+	Ypixel uint16 `json:"ypixel,omitempty"`
+}
+
+// Hello is the first frame a client sends
+type Hello struct {
+	Size       Size `json:"size"`
+	ReadOnly   bool `json:"read_only,omitempty"`   // input is ignored
+	IgnoreSize bool `json:"ignore_size,omitempty"` // never sizes the panel, like tmux attach -f ignore-size
+}
+
+// WriteFrame writes one frame with a single Write call
+func WriteFrame(w io.Writer, typ byte, payload []byte) error {
+	if len(payload) > maxPayload {
+		return fmt.Errorf("frame payload too large: %d bytes", len(payload))
+	}
+
+	buf := make([]byte, 5+len(payload))
+	buf[0] = typ
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(payload)))
+	copy(buf[5:], payload)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// WriteJSON writes one frame with v encoded as JSON
+func WriteJSON(w io.Writer, typ byte, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return WriteFrame(w, typ, payload)
+}
+
+// ReadFrame reads one frame
+func ReadFrame(r io.Reader) (typ byte, payload []byte, err error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	n := binary.BigEndian.Uint32(header[1:5])
+	if n > maxPayload {
+		return 0, nil, fmt.Errorf("frame payload too large: %d bytes", n)
+	}
+
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
@@ -0,0 +1,74 @@
+package attach
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteFrame(&buf, MsgOutput, []byte("hello")); err != nil {
+		t.Fatalf("WriteFrame() error: %v", err)
+	}
+	if err := WriteJSON(&buf, MsgHello, Hello{Size: Size{Cols: 80, Rows: 24}}); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+	if err := WriteFrame(&buf, MsgDetach, nil); err != nil {
+		t.Fatalf("WriteFrame() error: %v", err)
+	}
+
+	typ, payload, err := ReadFrame(&buf)
+	if err != nil || typ != MsgOutput || string(payload) != "hello" {
+		t.Errorf("ReadFrame() = %q, %q, %v, want output frame", typ, payload, err)
+	}
+
+	typ, payload, err = ReadFrame(&buf)
+	if err != nil || typ != MsgHello {
+		t.Fatalf("ReadFrame() = %q, %v, want hello frame", typ, err)
+	}
+	var hello Hello
+	if err := json.Unmarshal(payload, &hello); err != nil || hello.Size.Cols != 80 || hello.Size.Rows != 24 {
+		t.Errorf("hello = %+v, %v, want 80x24", hello, err)
+	}
+
+	typ, payload, err = ReadFrame(&buf)
+	if err != nil || typ != MsgDetach || len(payload) != 0 {
+		t.Errorf("ReadFrame() = %q, %q, %v, want empty detach frame", typ, payload, err)
+	}
+
+	if _, _, err := ReadFrame(&buf); err != io.EOF {
+		t.Errorf("ReadFrame() at end = %v, want EOF", err)
+	}
+}
+
+func TestReadFrame_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	WriteFrame(&buf, MsgInput, []byte("abcdef"))
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-2])
+
+	if _, _, err := ReadFrame(truncated); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadFrame() of truncated frame = %v, want ErrUnexpectedEOF", err)
+	}
+
+	huge := []byte{MsgInput, 0xff, 0xff, 0xff, 0xff}
+	if _, _, err := ReadFrame(bytes.NewReader(huge)); err == nil {
+		t.Error("ReadFrame() should reject oversized frames")
+	}
+}
+
+func TestDecodeSize(t *testing.T) {
+	size, err := DecodeSize([]byte(`{"cols":120,"rows":40,"xpixel":960}`))
+	if err != nil {
+		t.Fatalf("DecodeSize() error: %v", err)
+	}
+	if size != (Size{Cols: 120, Rows: 40, Xpixel: 960}) {
+		t.Errorf("DecodeSize() = %+v", size)
+	}
+
+	if _, err := DecodeSize([]byte("nope")); err == nil {
+		t.Error("DecodeSize() should reject invalid JSON")
+	}
+}
@@ -0,0 +1,182 @@
+package attach
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultDetachKey is Ctrl-\, as in dtach
+const DefaultDetachKey byte = 0x1c
+
+// ErrClosed is returned by Attach when prismctl hung up without detaching
+// the client, e.g. because it exited
+var ErrClosed = errors.New("prismctl closed the connection")
+
+// Options configure an attached client
+type Options struct {
+	DetachKey  byte // detaches the client when typed
+	Detachable bool // false disables the detach key
+	ReadOnly   bool // only watch: input other than the detach key is not sent
+	IgnoreSize bool // leave the panel's size to the other terminals
+}
+
+// resetSequence undoes modes the prism may have left on when the client
+// detaches: attributes, alternate screen, hidden cursor and mouse reporting
+const resetSequence = "\x1b[0m\x1b[?1049l\x1b[?25h\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l"
+
+// Attach connects the terminal in/out to the attach socket at path and
+// relays input, output and size changes until the detach key is typed or
+// prismctl detaches the client. in is put in raw mode for the duration, and
+// the terminal is reset when the client detaches. The returned reason is
+// empty when the user detached.
+func Attach(path string, in, out *os.File, opts Options) (reason string, err error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", path, err)
+	}
+	defer conn.Close()
+
+	fd := int(in.Fd())
+
+	size, err := TerminalSize(fd)
+	if err != nil {
+		return "", err
+	}
+	hello := Hello{Size: size, ReadOnly: opts.ReadOnly, IgnoreSize: opts.IgnoreSize}
+	if err := WriteJSON(conn, MsgHello, hello); err != nil {
+		return "", fmt.Errorf("failed to send hello: %w", err)
+	}
+
+	saved, err := makeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer unix.IoctlSetTermios(fd, unix.TCSETS, saved)
+	defer out.WriteString(resetSequence)
+
+	type result struct {
+		reason string
+		err    error
+	}
+	done := make(chan result, 2)
+
+	// Terminal → prismctl
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				data := buf[:n]
+				detach := false
+				if opts.Detachable {
+					if i := bytes.IndexByte(data, opts.DetachKey); i >= 0 {
+						data, detach = data[:i], true
+					}
+				}
+				if len(data) > 0 && !opts.ReadOnly {
+					if werr := WriteFrame(conn, MsgInput, data); werr != nil {
+						done <- result{err: werr}
+						return
+					}
+				}
+				if detach {
+					done <- result{}
+					return
+				}
+			}
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+		}
+	}()
+
+	// prismctl → terminal
+	go func() {
+		for {
+			typ, payload, err := ReadFrame(conn)
+			if err != nil {
+				done <- result{err: ErrClosed}
+				return
+			}
+			switch typ {
+			case MsgOutput:
+				if _, err := out.Write(payload); err != nil {
+					done <- result{err: err}
+					return
+				}
+			case MsgDetach:
+				done <- result{reason: string(payload)}
+				return
+			}
+		}
+	}()
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, unix.SIGWINCH)
+	defer signal.Stop(winch)
+
+	for {
+		select {
+		case r := <-done:
+			return r.reason, r.err
+		case <-winch:
+			size, err := TerminalSize(fd)
+			if err != nil {
+				continue
+			}
+			if err := WriteJSON(conn, MsgResize, size); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// TerminalSize returns the window size of the terminal on fd
+func TerminalSize(fd int) (Size, error) {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return Size{}, fmt.Errorf("failed to get terminal size: %w", err)
+	}
+	return Size{Cols: ws.Col, Rows: ws.Row, Xpixel: ws.Xpixel, Ypixel: ws.Ypixel}, nil
+}
+
+// DecodeSize decodes the payload of a MsgResize frame
+func DecodeSize(payload []byte) (Size, error) {
+	var size Size
+	if err := json.Unmarshal(payload, &size); err != nil {
+		return size, fmt.Errorf("invalid size: %w", err)
+	}
+	return size, nil
+}
+
+// makeRaw puts the terminal on fd in raw mode, like cfmakeraw(3), and returns
+// the previous attributes
+func makeRaw(fd int) (*unix.Termios, error) {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get terminal attributes: %w", err)
+	}
+	saved := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, termios); err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %w", err)
+	}
+
+	return &saved, nil
+}
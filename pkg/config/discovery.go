@@ -193,6 +193,79 @@ func MergePrismConfigs(prismSource, userConfig *PrismConfig) *PrismConfig {
 		merged.Path = prismSource.Path
 	}
 
+	merged.Args = prismSource.Args
+	if len(userConfig.Args) > 0 {
+		merged.Args = userConfig.Args
+	}
+
+	merged.Env = prismSource.Env
+	if len(userConfig.Env) > 0 {
+		merged.Env = userConfig.Env
+	}
+
+	merged.Cwd = prismSource.Cwd
+	if userConfig.Cwd != "" {
+		merged.Cwd = userConfig.Cwd
+	}
+
+	merged.StopSignal = prismSource.StopSignal
+	if userConfig.StopSignal != "" {
+		merged.StopSignal = userConfig.StopSignal
+	}
+
+	merged.StopTimeout = prismSource.StopTimeout
+	if userConfig.StopTimeout != "" {
+		merged.StopTimeout = userConfig.StopTimeout
+	}
+
+	merged.Liveness = prismSource.Liveness
+	if userConfig.Liveness != nil {
+		merged.Liveness = userConfig.Liveness
+	}
+
+	merged.Restart = prismSource.Restart
+	if userConfig.Restart != "" {
+		merged.Restart = userConfig.Restart
+	}
+
+	merged.RestartDelay = prismSource.RestartDelay
+	if userConfig.RestartDelay != "" {
+		merged.RestartDelay = userConfig.RestartDelay
+	}
+
+	merged.MaxRestarts = prismSource.MaxRestarts
+	if userConfig.MaxRestarts != 0 {
+		merged.MaxRestarts = userConfig.MaxRestarts
+	}
+
+	merged.After = prismSource.After
+	if len(userConfig.After) > 0 {
+		merged.After = userConfig.After
+	}
+
+	merged.Requires = prismSource.Requires
+	if len(userConfig.Requires) > 0 {
+		merged.Requires = userConfig.Requires
+	}
+
+	merged.Background = prismSource.Background
+	if userConfig.Background != "" {
+		merged.Background = userConfig.Background
+	}
+
+	merged.LifecycleSignal = prismSource.LifecycleSignal
+	if userConfig.LifecycleSignal != "" {
+		merged.LifecycleSignal = userConfig.LifecycleSignal
+	}
+
+	merged.Theme = prismSource.Theme
+	if userConfig.Theme != "" {
+		merged.Theme = userConfig.Theme
+	}
+
+	// The prism source ships defaults, the user overrides single settings
+	merged.Settings = mergeSettings(prismSource.Settings, userConfig.Settings)
+
 	if len(userConfig.Apps) > 0 {
 		merged.Apps = userConfig.Apps
 	} else {
@@ -226,6 +299,36 @@ func MergePrismConfigs(prismSource, userConfig *PrismConfig) *PrismConfig {
 		merged.HideOnFocusLoss = userConfig.HideOnFocusLoss
 	}
 
+	merged.Layout = prismSource.Layout
+	if userConfig.Layout != "" {
+		merged.Layout = userConfig.Layout
+	}
+
+	merged.Record = prismSource.Record
+	if userConfig.Record {
+		merged.Record = userConfig.Record
+	}
+
+	merged.KeepAlive = prismSource.KeepAlive
+	if userConfig.KeepAlive {
+		merged.KeepAlive = userConfig.KeepAlive
+	}
+
+	merged.Placeholder = prismSource.Placeholder
+	if userConfig.Placeholder != "" {
+		merged.Placeholder = userConfig.Placeholder
+	}
+
+	merged.PlaceholderText = prismSource.PlaceholderText
+	if userConfig.PlaceholderText != "" {
+		merged.PlaceholderText = userConfig.PlaceholderText
+	}
+
+	merged.Prefix = prismSource.Prefix
+	if userConfig.Prefix != "" {
+		merged.Prefix = userConfig.Prefix
+	}
+
 	merged.FocusPolicy = prismSource.FocusPolicy
 	if userConfig.FocusPolicy != "" {
 		merged.FocusPolicy = userConfig.FocusPolicy
@@ -37,6 +37,14 @@ func Load(path string) (*Config, error) {
 		}
 	}
 
+	if cfg.Core != nil && cfg.Core.Theme != "" {
+		for _, pc := range cfg.Prisms {
+			if pc.Theme == "" {
+				pc.Theme = cfg.Core.Theme
+			}
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 )
 
@@ -232,6 +233,383 @@ func TestPrismConfig_ToPanelConfig(t *testing.T) {
 	}
 }
 
+func TestPrismConfig_SplitLayout(t *testing.T) {
+	prismCfg := &PrismConfig{
+		Name:   "bar",
+		Layout: "hsplit",
+		Apps: map[string]*AppConfig{
+			"spotify":    {Path: "shine-spotify", Enabled: true, Order: 2},
+			"clock":      {Path: "shine-clock", Enabled: true, Order: 1, Size: 10},
+			"workspaces": {Path: "shine-workspaces", Enabled: true, Order: 1, Weight: 3},
+		},
+	}
+
+	if err := prismCfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	names := prismCfg.SortedAppNames()
+	want := []string{"clock", "workspaces", "spotify"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected app order %v, got %v", want, names)
+	}
+
+	prismCfg.Layout = "grid"
+	if err := prismCfg.Validate(); err == nil {
+		t.Error("Expected error for unknown layout")
+	}
+
+	single := &PrismConfig{Name: "clock", Path: "shine-clock", Layout: "vsplit"}
+	if err := single.Validate(); err == nil {
+		t.Error("Expected error for split layout without apps")
+	}
+}
+
+func TestPrismConfig_AppProcessDefaults(t *testing.T) {
+	prismCfg := &PrismConfig{
+		Name: "weather",
+		Env:  map[string]string{"UNITS": "metric", "CITY": "Paris"},
+		Cwd:  "~/weather",
+		Apps: map[string]*AppConfig{
+			"berlin": {Enabled: true, Env: map[string]string{"CITY": "Berlin"}},
+			"tokyo":  {Enabled: true, Cwd: "/tmp"},
+		},
+	}
+
+	env := prismCfg.AppEnv(prismCfg.Apps["berlin"])
+	if env["CITY"] != "Berlin" || env["UNITS"] != "metric" {
+		t.Errorf("Expected app env to override prism env, got %v", env)
+	}
+
+	if cwd := prismCfg.AppCwd(prismCfg.Apps["berlin"]); cwd != "~/weather" {
+		t.Errorf("Expected prism cwd to be inherited, got %q", cwd)
+	}
+	if cwd := prismCfg.AppCwd(prismCfg.Apps["tokyo"]); cwd != "/tmp" {
+		t.Errorf("Expected app cwd to win, got %q", cwd)
+	}
+
+	single := &PrismConfig{Name: "clock", Path: "shine-clock", Args: []string{"--24h"}}
+	app := single.GetApps()["clock"]
+	if len(app.Args) != 1 || app.Args[0] != "--24h" {
+		t.Errorf("Expected single-app args to carry over, got %v", app.Args)
+	}
+
+	bad := &PrismConfig{Name: "bad", Env: map[string]string{"A=B": "c"}}
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected error for invalid env variable name")
+	}
+}
+
+func TestParsePrefixKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    byte
+		ok      bool
+		wantErr bool
+	}{
+		{"", 0, false, false},
+		{"none", 0, false, false},
+		{"C-a", 0x01, true, false},
+		{"c-B", 0x02, true, false},
+		{"C-space", 0x00, true, false},
+		{"C-]", 0x1d, true, false},
+		{"C-1", 0, false, true},
+		{"M-a", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		got, ok, err := ParsePrefixKey(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePrefixKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParsePrefixKey(%q) = %#x, %v, want %#x, %v", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseStopSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{"", syscall.SIGTERM, false},
+		{"SIGINT", syscall.SIGINT, false},
+		{"hup", syscall.SIGHUP, false},
+		{"SIGUSR1", syscall.SIGUSR1, false},
+		{"SIGSTOP", 0, true},
+		{"SIGBOGUS", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStopSignal(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStopSignal(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStopSignal(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if sig, err := ParseSignal("winch"); err != nil || sig != syscall.SIGWINCH {
+		t.Errorf("ParseSignal(winch) = %v, %v, want SIGWINCH", sig, err)
+	}
+	if _, err := ParseSignal(""); err == nil {
+		t.Error("ParseSignal() should reject an empty name")
+	}
+
+	prismCfg := &PrismConfig{
+		Name:        "chat",
+		StopSignal:  "SIGHUP",
+		StopTimeout: "2s",
+		Apps: map[string]*AppConfig{
+			"irc": {Enabled: true, StopTimeout: "30s"},
+		},
+	}
+	app := prismCfg.Apps["irc"]
+	if sig := prismCfg.AppStopSignal(app); sig != "SIGHUP" {
+		t.Errorf("Expected prism stop_signal to be inherited, got %q", sig)
+	}
+	if timeout := prismCfg.AppStopTimeout(app); timeout != "30s" {
+		t.Errorf("Expected app stop_timeout to win, got %q", timeout)
+	}
+
+	bad := &PrismConfig{Name: "bad", StopTimeout: "soon"}
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected error for invalid stop_timeout")
+	}
+}
+
+func TestLoad_Liveness(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "shine.toml")
+
+	configContent := `[prisms.chat]
+name = "chat"
+enabled = true
+
+[prisms.chat.liveness]
+probe = "output"
+timeout = "1m"
+
+[prisms.chat.apps.irc]
+enabled = true
+
+[prisms.chat.apps.irc.liveness]
+probe = "exec"
+command = ["pgrep", "-x", "weechat"]
+action = "restart"
+
+[prisms.chat.apps.log]
+enabled = true
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	chat := cfg.Prisms["chat"]
+	irc := chat.AppLiveness(chat.Apps["irc"])
+	if irc == nil || irc.Probe != "exec" || irc.Action != "restart" || len(irc.Command) != 3 {
+		t.Errorf("Expected the app's exec probe, got %+v", irc)
+	}
+	if probe := chat.AppLiveness(chat.Apps["log"]); probe == nil || probe.Probe != "output" || probe.Timeout != "1m" {
+		t.Errorf("Expected the prism's output probe to be inherited, got %+v", probe)
+	}
+
+	tests := []struct {
+		probe   LivenessConfig
+		wantErr bool
+	}{
+		{LivenessConfig{Probe: "heartbeat", Interval: "30s", Failures: 2, Action: "notify"}, false},
+		{LivenessConfig{Probe: "heartbeat", Timeout: "500ms"}, true},
+		{LivenessConfig{Probe: "dsr"}, true},
+		{LivenessConfig{Probe: "exec", Command: []string{"true"}}, false},
+		{LivenessConfig{Probe: "exec"}, true},
+		{LivenessConfig{Probe: "output", Command: []string{"true"}}, true},
+		{LivenessConfig{Probe: "ping"}, true},
+		{LivenessConfig{Probe: "output", Timeout: "0s"}, true},
+		{LivenessConfig{Probe: "output", Interval: "often"}, true},
+		{LivenessConfig{Probe: "output", Failures: -1}, true},
+		{LivenessConfig{Probe: "output", Action: "reboot"}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.probe.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.probe, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoad_ThemeAndSettings(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "shine.toml")
+
+	configContent := `[core]
+theme = "nord"
+
+[prisms.clock]
+name = "clock"
+enabled = true
+path = "shine-clock"
+
+[prisms.clock.settings]
+format = "15:04"
+seconds = false
+
+[prisms.weather]
+name = "weather"
+enabled = true
+theme = "gruvbox"
+
+[prisms.weather.settings]
+units = "metric"
+refresh = "10m"
+
+[prisms.weather.apps.berlin]
+enabled = true
+
+[prisms.weather.apps.berlin.settings]
+city = "Berlin"
+refresh = "5m"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	clock := cfg.Prisms["clock"]
+	if clock.Theme != "nord" {
+		t.Errorf("Expected the core theme nord, got %q", clock.Theme)
+	}
+	settings := clock.AppSettings(clock.GetApps()["clock"])
+	if settings["format"] != "15:04" || settings["seconds"] != false {
+		t.Errorf("Expected the prism's settings, got %v", settings)
+	}
+
+	weather := cfg.Prisms["weather"]
+	if weather.Theme != "gruvbox" {
+		t.Errorf("Expected the prism's own theme gruvbox, got %q", weather.Theme)
+	}
+	settings = weather.AppSettings(weather.Apps["berlin"])
+	if settings["city"] != "Berlin" || settings["units"] != "metric" || settings["refresh"] != "5m" {
+		t.Errorf("Expected the app's settings over the prism's, got %v", settings)
+	}
+	if weather.Settings["refresh"] != "10m" {
+		t.Errorf("Overlaying app settings changed the prism's: %v", weather.Settings)
+	}
+}
+
+func TestPrismConfig_Lifecycle(t *testing.T) {
+	prismCfg := &PrismConfig{
+		Name:            "chat",
+		Background:      "run",
+		LifecycleSignal: "SIGUSR1",
+		Apps: map[string]*AppConfig{
+			"irc":  {Enabled: true},
+			"mail": {Enabled: true, Background: "suspend", LifecycleSignal: "SIGUSR2"},
+		},
+	}
+	if err := prismCfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	irc, mail := prismCfg.Apps["irc"], prismCfg.Apps["mail"]
+	if got := prismCfg.AppBackground(irc); got != "run" {
+		t.Errorf("Expected prism background to be inherited, got %q", got)
+	}
+	if got := prismCfg.AppLifecycleSignal(mail); got != "SIGUSR2" {
+		t.Errorf("Expected app lifecycle_signal to win, got %q", got)
+	}
+
+	for _, bad := range []*AppConfig{
+		{Background: "freeze"},
+		{LifecycleSignal: "SIGSTOP"},
+		{LifecycleSignal: "SIGKILL"},
+		{LifecycleSignal: "SIGBOGUS"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+
+	if sig, err := ParseLifecycleSignal(""); err != nil || sig != 0 {
+		t.Errorf("ParseLifecycleSignal(\"\") = %v, %v, want no signal", sig, err)
+	}
+}
+
+func TestPrismConfig_Restart(t *testing.T) {
+	prismCfg := &PrismConfig{
+		Name:         "chat",
+		Restart:      "on-failure",
+		RestartDelay: "2s",
+		MaxRestarts:  5,
+		Apps: map[string]*AppConfig{
+			"irc": {Enabled: true, Restart: "always", MaxRestarts: 10},
+		},
+	}
+	if err := prismCfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	irc := prismCfg.Apps["irc"]
+	if got := prismCfg.AppRestart(irc); got != "always" {
+		t.Errorf("Expected app restart to win, got %q", got)
+	}
+	if got := prismCfg.AppRestartDelay(irc); got != "2s" {
+		t.Errorf("Expected prism restart_delay to be inherited, got %q", got)
+	}
+	if got := prismCfg.AppMaxRestarts(irc); got != 10 {
+		t.Errorf("Expected app max_restarts to win, got %d", got)
+	}
+
+	for _, bad := range []*AppConfig{
+		{Restart: "sometimes"},
+		{RestartDelay: "soon"},
+		{RestartDelay: "-1s"},
+		{RestartDelay: "0s"},
+		{MaxRestarts: -1},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+}
+
+func TestValidatePlaceholder(t *testing.T) {
+	tests := []struct {
+		placeholder string
+		text        string
+		wantErr     bool
+	}{
+		{"", "", false},
+		{"blank", "", false},
+		{"apps", "", false},
+		{"text", "{{.Panel}} is idle", false},
+		{"text", "{{.Panel", true},
+		{"fancy", "", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidatePlaceholder(tt.placeholder, tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePlaceholder(%q, %q) error = %v, wantErr %v", tt.placeholder, tt.text, err, tt.wantErr)
+		}
+	}
+}
+
 func TestNewDefaultConfig_HasPrisms(t *testing.T) {
 	cfg := NewDefaultConfig()
 
@@ -0,0 +1,93 @@
+// order.go resolves the start order of prisms from their after and requires
+// lists, which form a directed acyclic graph: each prism comes after the
+// prisms it names.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dependencies returns the prisms pc starts after: those it names in After
+// and in Requires
+func (pc *PrismConfig) Dependencies() []string {
+	deps := make([]string, 0, len(pc.After)+len(pc.Requires))
+	deps = append(deps, pc.After...)
+	deps = append(deps, pc.Requires...)
+	return deps
+}
+
+// SortPrisms orders prisms so that each comes after the prisms in its
+// Dependencies, and otherwise by name. Dependencies on prisms that are not
+// in prisms are ignored. A cycle is an error.
+func SortPrisms(prisms []*PrismConfig) ([]*PrismConfig, error) {
+	byName := make(map[string]*PrismConfig, len(prisms))
+	names := make([]string, 0, len(prisms))
+	for _, pc := range prisms {
+		byName[pc.Name] = pc
+		names = append(names, pc.Name)
+	}
+	sort.Strings(names)
+
+	placed := make(map[string]bool, len(prisms))
+	ready := func(name string) bool {
+		for _, dep := range byName[name].Dependencies() {
+			if _, ok := byName[dep]; ok && !placed[dep] {
+				return false
+			}
+		}
+		return true
+	}
+
+	sorted := make([]*PrismConfig, 0, len(prisms))
+	for len(sorted) < len(names) {
+		next := ""
+		for _, name := range names {
+			if !placed[name] && ready(name) {
+				next = name
+				break
+			}
+		}
+
+		if next == "" {
+			return nil, fmt.Errorf("dependency cycle: %s", findCycle(byName, names, placed))
+		}
+
+		placed[next] = true
+		sorted = append(sorted, byName[next])
+	}
+
+	return sorted, nil
+}
+
+// findCycle follows unplaced dependencies from the first unplaced prism
+// until a prism repeats. Every unplaced prism waits on another, so it does.
+func findCycle(byName map[string]*PrismConfig, names []string, placed map[string]bool) string {
+	var path []string
+	seen := make(map[string]int)
+
+	name := ""
+	for _, n := range names {
+		if !placed[n] {
+			name = n
+			break
+		}
+	}
+
+	for {
+		if i, ok := seen[name]; ok {
+			return strings.Join(append(path[i:], name), " -> ")
+		}
+		seen[name] = len(path)
+		path = append(path, name)
+
+		for _, dep := range byName[name].Dependencies() {
+			if _, ok := byName[dep]; ok && !placed[dep] {
+				name = dep
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortPrisms(t *testing.T) {
+	prisms := []*PrismConfig{
+		{Name: "chat", Requires: []string{"bar"}},
+		{Name: "bar"},
+		{Name: "clock", After: []string{"chat", "notifications"}},
+		{Name: "audio"},
+	}
+
+	sorted, err := SortPrisms(prisms)
+	if err != nil {
+		t.Fatalf("SortPrisms() error: %v", err)
+	}
+
+	var names []string
+	for _, pc := range sorted {
+		names = append(names, pc.Name)
+	}
+	if got, want := strings.Join(names, " "), "audio bar chat clock"; got != want {
+		t.Errorf("SortPrisms() = %s, want %s", got, want)
+	}
+
+	prisms[1].After = []string{"clock"}
+	_, err = SortPrisms(prisms)
+	if err == nil || !strings.Contains(err.Error(), "bar -> clock -> chat -> bar") {
+		t.Errorf("SortPrisms() error = %v, want the cycle", err)
+	}
+}
+
+func TestConfig_ValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		prisms  map[string]*PrismConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			prisms: map[string]*PrismConfig{
+				"bar":  {Name: "bar"},
+				"chat": {Name: "chat", After: []string{"clock"}, Requires: []string{"bar"}},
+			},
+		},
+		{
+			name: "unknown requirement",
+			prisms: map[string]*PrismConfig{
+				"chat": {Name: "chat", Requires: []string{"bar"}},
+			},
+			wantErr: `requires unknown prism "bar"`,
+		},
+		{
+			name: "self",
+			prisms: map[string]*PrismConfig{
+				"bar": {Name: "bar", After: []string{"bar"}},
+			},
+			wantErr: "dependency cycle: bar -> bar",
+		},
+	}
+
+	for _, tt := range tests {
+		err := (&Config{Prisms: tt.prisms}).Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: Validate() error: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: Validate() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
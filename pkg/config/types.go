@@ -1,6 +1,10 @@
 package config
 
-import "github.com/starbased-co/shine/pkg/panel"
+import (
+	"sort"
+
+	"github.com/starbased-co/shine/pkg/panel"
+)
 
 type AppConfig struct {
 	// Path specifies the binary name or path
@@ -10,10 +14,79 @@ type AppConfig struct {
 	// Enabled controls whether this app should be launched
 	Enabled bool `toml:"enabled"`
 
+	// === Process ===
+	// Args are passed to the binary; Env is added to the environment it
+	// inherits; Cwd is its working directory. All support ~ and $VAR.
+	Args []string          `toml:"args,omitempty"`
+	Env  map[string]string `toml:"env,omitempty"`
+	Cwd  string            `toml:"cwd,omitempty"`
+
+	// === Split Layout ===
+	// Only used when the prism's layout is "hsplit" or "vsplit".
+	// Size fixes the pane size in cells; Weight shares the remaining cells
+	// between panes without a fixed size (default 1). Order sorts panes
+	// left-to-right or top-to-bottom, ties broken by app name.
+	Size   int `toml:"size,omitempty"`
+	Weight int `toml:"weight,omitempty"`
+	Order  int `toml:"order,omitempty"`
+
+	// === Stopping ===
+	// StopSignal is sent to stop the app (default "SIGTERM"). StopTimeout is
+	// how long it gets to exit before it is sent SIGKILL (default "5s").
+	StopSignal  string `toml:"stop_signal,omitempty"`
+	StopTimeout string `toml:"stop_timeout,omitempty"`
+
+	// === Liveness ===
+	// Liveness probes the app while it runs, to catch it hung rather than
+	// exited (default: the prism's Liveness, if any)
+	Liveness *LivenessConfig `toml:"liveness,omitempty"`
+
+	// === Lifecycle ===
+	// Background is what happens to the app while another app has the
+	// panel: "suspend" (default) stops it with SIGSTOP, "run" leaves it
+	// running and only tells it that it is hidden. LifecycleSignal, if set,
+	// is sent to the app whenever it gains or loses the foreground or focus
+	// or the panel is hidden or shown.
+	Background      string `toml:"background,omitempty"`
+	LifecycleSignal string `toml:"lifecycle_signal,omitempty"`
+
+	// === Restart ===
+	// Restart is when shined starts the app again after it exits: "no",
+	// "on-failure", "unless-stopped" or "always". RestartDelay is the first
+	// delay, doubled for each restart in quick succession. After MaxRestarts
+	// restarts within an hour the app is marked failed. All default to the
+	// prism's; MaxRestarts 0 inherits it.
+	Restart      string `toml:"restart,omitempty"`
+	RestartDelay string `toml:"restart_delay,omitempty"`
+	MaxRestarts  int    `toml:"max_restarts,omitempty"`
+
+	// === Settings ===
+	// Settings are app-specific values passed to the app as JSON in
+	// SHINE_SETTINGS, overlaying the prism's Settings key by key
+	Settings map[string]interface{} `toml:"settings,omitempty"`
+
 	// ResolvedPath is set during discovery (not from TOML)
 	ResolvedPath string `toml:"-"`
 }
 
+// LivenessConfig configures a liveness probe, run every Interval while the
+// app is running (not while suspended in the background):
+// "output": the app wrote output within the last Timeout
+// "heartbeat": the app called child/heartbeat on the child socket within
+// the last Timeout, as prisms built with prism.Run do from their event loop
+// "exec": Command exits 0 within Timeout
+// After Failures consecutive failures the app is marked unhealthy, and
+// Action decides what else happens: "unhealthy" (default) only marks it,
+// "notify" also sends a desktop notification, "restart" restarts it.
+type LivenessConfig struct {
+	Probe    string   `toml:"probe"`
+	Command  []string `toml:"command,omitempty"`
+	Interval string   `toml:"interval,omitempty"` // default "10s"
+	Timeout  string   `toml:"timeout,omitempty"`  // default "5s"
+	Failures int      `toml:"failures,omitempty"` // default 3
+	Action   string   `toml:"action,omitempty"`
+}
+
 type Config struct {
 	Core   *CoreConfig             `toml:"core"`
 	Prisms map[string]*PrismConfig `toml:"prisms"`
@@ -24,6 +97,14 @@ type CoreConfig struct {
 	// Can be a single string or array of strings
 	// Example: "~/.local/share/shine/bin" or ["~/.local/share/shine/bin", "~/.config/shine/bin"]
 	Path interface{} `toml:"path"`
+
+	// Theme names the theme prisms should use, passed to them in
+	// SHINE_THEME; a prism's own Theme wins
+	Theme string `toml:"theme,omitempty"`
+
+	// Watch reloads the config automatically when shine.toml or a prism
+	// config changes, as SIGHUP does
+	Watch bool `toml:"watch,omitempty"`
 }
 
 func (cc *CoreConfig) GetPaths() []string {
@@ -64,6 +145,46 @@ type PrismConfig struct {
 	// Can be a simple name (e.g., "shine-weather") or a path (e.g., "/usr/bin/shine-weather")
 	Path string `toml:"path,omitempty"`
 
+	// === Process ===
+	// Args applies to single-app mode only. Env and Cwd are also defaults
+	// for every app in multi-app mode; app Env entries win on conflict.
+	Args []string          `toml:"args,omitempty"`
+	Env  map[string]string `toml:"env,omitempty"`
+	Cwd  string            `toml:"cwd,omitempty"`
+
+	// StopSignal and StopTimeout apply to single-app mode and are defaults
+	// for every app in multi-app mode (see AppConfig)
+	StopSignal  string `toml:"stop_signal,omitempty"`
+	StopTimeout string `toml:"stop_timeout,omitempty"`
+
+	// Liveness applies to single-app mode and is the default for every app
+	// in multi-app mode (see AppConfig)
+	Liveness *LivenessConfig `toml:"liveness,omitempty"`
+
+	// Background and LifecycleSignal apply to single-app mode and are
+	// defaults for every app in multi-app mode (see AppConfig)
+	Background      string `toml:"background,omitempty"`
+	LifecycleSignal string `toml:"lifecycle_signal,omitempty"`
+
+	// Restart policy of single-app mode and default for every app in
+	// multi-app mode (see AppConfig). MaxRestarts 0 means unlimited.
+	Restart      string `toml:"restart,omitempty"`
+	RestartDelay string `toml:"restart_delay,omitempty"`
+	MaxRestarts  int    `toml:"max_restarts,omitempty"`
+
+	// After names prisms whose panels start before this one, if they are
+	// configured. Requires names prisms that must also come up healthy
+	// first: this panel is not started without them and is stopped when one
+	// of them fails.
+	After    []string `toml:"after,omitempty"`
+	Requires []string `toml:"requires,omitempty"`
+
+	// Theme overrides the core theme for this prism's apps. Settings are
+	// passed to its apps (see AppConfig); in multi-app mode they are
+	// defaults every app's Settings overlay.
+	Theme    string                 `toml:"theme,omitempty"`
+	Settings map[string]interface{} `toml:"settings,omitempty"`
+
 	// Apps defines multiple apps for this prism (multi-app mode)
 	// When set, this prism can manage multiple TUI applications
 	// The key is the app name, value is the app configuration
@@ -79,6 +200,30 @@ type PrismConfig struct {
 	Width    interface{} `toml:"width,omitempty"`    // int or string (with "px" or "%")
 	Height   interface{} `toml:"height,omitempty"`   // int or string (with "px" or "%")
 
+	// Layout controls how a multi-app prism uses its panel
+	// "single" (default): one foreground app fills the panel, the rest are suspended
+	// "hsplit": all apps run side by side, left to right
+	// "vsplit": all apps run stacked, top to bottom
+	Layout string `toml:"layout,omitempty"`
+
+	// Prefix is a tmux-style key such as "C-a" that prismctl intercepts to
+	// switch between apps; empty or "none" disables it
+	Prefix string `toml:"prefix,omitempty"`
+
+	// Record writes each app's output to an asciicast v2 file under
+	// the data directory (see paths.RecordingDir)
+	Record bool `toml:"record,omitempty"`
+
+	// KeepAlive keeps the panel open after its last app exits. While no app
+	// is in the foreground the panel shows a placeholder:
+	// "text" (default): PlaceholderText, a text/template with .Panel,
+	// .Background and .Apps, or a default message when empty
+	// "blank": an empty panel
+	// "apps": the configured apps, which can be started with prism/up
+	KeepAlive       bool   `toml:"keep_alive,omitempty"`
+	Placeholder     string `toml:"placeholder,omitempty"`
+	PlaceholderText string `toml:"placeholder_text,omitempty"`
+
 	// === Behavior ===
 	HideOnFocusLoss bool   `toml:"hide_on_focus_loss,omitempty"`
 	FocusPolicy     string `toml:"focus_policy,omitempty"`
@@ -113,13 +258,163 @@ func (pc *PrismConfig) GetApps() map[string]*AppConfig {
 			name: {
 				Path:         pc.Path,
 				Enabled:      true,
+				Args:         pc.Args,
+				Env:          pc.Env,
+				Cwd:          pc.Cwd,
+				StopSignal:   pc.StopSignal,
+				StopTimeout:  pc.StopTimeout,
+				Liveness:     pc.Liveness,
+				Settings:     pc.Settings,
 				ResolvedPath: pc.ResolvedPath,
+
+				Background:      pc.Background,
+				LifecycleSignal: pc.LifecycleSignal,
+
+				Restart:      pc.Restart,
+				RestartDelay: pc.RestartDelay,
+				MaxRestarts:  pc.MaxRestarts,
 			},
 		}
 	}
 	return nil
 }
 
+// AppEnv returns the environment for app: the prism's Env overlaid with the app's
+func (pc *PrismConfig) AppEnv(app *AppConfig) map[string]string {
+	if len(pc.Env) == 0 && len(app.Env) == 0 {
+		return nil
+	}
+
+	env := make(map[string]string, len(pc.Env)+len(app.Env))
+	for k, v := range pc.Env {
+		env[k] = v
+	}
+	for k, v := range app.Env {
+		env[k] = v
+	}
+	return env
+}
+
+// AppCwd returns the working directory for app, defaulting to the prism's Cwd
+func (pc *PrismConfig) AppCwd(app *AppConfig) string {
+	if app.Cwd != "" {
+		return app.Cwd
+	}
+	return pc.Cwd
+}
+
+// AppStopSignal returns the stop signal for app, defaulting to the prism's
+func (pc *PrismConfig) AppStopSignal(app *AppConfig) string {
+	if app.StopSignal != "" {
+		return app.StopSignal
+	}
+	return pc.StopSignal
+}
+
+// AppStopTimeout returns the stop timeout for app, defaulting to the prism's
+func (pc *PrismConfig) AppStopTimeout(app *AppConfig) string {
+	if app.StopTimeout != "" {
+		return app.StopTimeout
+	}
+	return pc.StopTimeout
+}
+
+// AppLiveness returns the liveness probe for app, defaulting to the prism's
+func (pc *PrismConfig) AppLiveness(app *AppConfig) *LivenessConfig {
+	if app.Liveness != nil {
+		return app.Liveness
+	}
+	return pc.Liveness
+}
+
+// AppRestart returns the restart policy for app, defaulting to the prism's
+func (pc *PrismConfig) AppRestart(app *AppConfig) string {
+	if app.Restart != "" {
+		return app.Restart
+	}
+	return pc.Restart
+}
+
+// AppRestartDelay returns the first restart delay for app, defaulting to the
+// prism's
+func (pc *PrismConfig) AppRestartDelay(app *AppConfig) string {
+	if app.RestartDelay != "" {
+		return app.RestartDelay
+	}
+	return pc.RestartDelay
+}
+
+// AppMaxRestarts returns how often app may restart within an hour before it
+// is marked failed, defaulting to the prism's
+func (pc *PrismConfig) AppMaxRestarts(app *AppConfig) int {
+	if app.MaxRestarts != 0 {
+		return app.MaxRestarts
+	}
+	return pc.MaxRestarts
+}
+
+// AppBackground returns what happens to app in the background, defaulting
+// to the prism's setting
+func (pc *PrismConfig) AppBackground(app *AppConfig) string {
+	if app.Background != "" {
+		return app.Background
+	}
+	return pc.Background
+}
+
+// AppLifecycleSignal returns the lifecycle signal for app, defaulting to the
+// prism's
+func (pc *PrismConfig) AppLifecycleSignal(app *AppConfig) string {
+	if app.LifecycleSignal != "" {
+		return app.LifecycleSignal
+	}
+	return pc.LifecycleSignal
+}
+
+// AppSettings returns the settings for app: the prism's Settings overlaid
+// with the app's
+func (pc *PrismConfig) AppSettings(app *AppConfig) map[string]interface{} {
+	return mergeSettings(pc.Settings, app.Settings)
+}
+
+// mergeSettings overlays over on base key by key, nil when both are empty
+func mergeSettings(base, over map[string]interface{}) map[string]interface{} {
+	if len(base) == 0 && len(over) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]interface{}, len(base)+len(over))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range over {
+		merged[k] = v
+	}
+	return merged
+}
+
+// IsSplitLayout reports whether all apps share the panel in split panes
+func (pc *PrismConfig) IsSplitLayout() bool {
+	return pc.Layout == "hsplit" || pc.Layout == "vsplit"
+}
+
+// SortedAppNames returns app names in layout order (Order, then name)
+func (pc *PrismConfig) SortedAppNames() []string {
+	apps := pc.GetApps()
+	names := make([]string, 0, len(apps))
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := apps[names[i]].Order, apps[names[j]].Order
+		if oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
 func (pc *PrismConfig) ToPanelConfig() *panel.Config {
 	cfg := panel.NewConfig()
 
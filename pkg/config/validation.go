@@ -2,9 +2,15 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"syscall"
+	"text/template"
 	"time"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/starbased-co/shine/pkg/panel"
+	"github.com/starbased-co/shine/pkg/rpc"
 )
 
 func (c *Config) Validate() error {
@@ -22,6 +28,20 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("prism %q: %w", name, err)
 		}
 	}
+
+	prisms := make([]*PrismConfig, 0, len(c.Prisms))
+	for name, prism := range c.Prisms {
+		for _, dep := range prism.Requires {
+			if !seen[dep] {
+				return fmt.Errorf("prism %q: requires unknown prism %q", name, dep)
+			}
+		}
+		prisms = append(prisms, prism)
+	}
+
+	if _, err := SortPrisms(prisms); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -63,10 +83,259 @@ func (pc *PrismConfig) Validate() error {
 		_ = panel.ParseFocusPolicy(pc.FocusPolicy)
 	}
 
+	if err := ValidateLayout(pc.Layout); err != nil {
+		return err
+	}
+	if err := ValidateEnv(pc.Env); err != nil {
+		return err
+	}
+
+	if _, _, err := ParsePrefixKey(pc.Prefix); err != nil {
+		return err
+	}
+
+	if _, err := ParseStopSignal(pc.StopSignal); err != nil {
+		return err
+	}
+	if err := ValidateStopTimeout(pc.StopTimeout); err != nil {
+		return err
+	}
+	if err := pc.Liveness.Validate(); err != nil {
+		return err
+	}
+	if err := ValidateBackground(pc.Background); err != nil {
+		return err
+	}
+	if err := validateRestart(pc.Restart, pc.RestartDelay, pc.MaxRestarts); err != nil {
+		return err
+	}
+	if _, err := ParseLifecycleSignal(pc.LifecycleSignal); err != nil {
+		return err
+	}
+
+	if err := ValidatePlaceholder(pc.Placeholder, pc.PlaceholderText); err != nil {
+		return err
+	}
+
+	if pc.IsSplitLayout() && !pc.IsMultiApp() {
+		return fmt.Errorf("layout %q requires apps to be configured", pc.Layout)
+	}
+
 	return nil
 }
 
 func (ac *AppConfig) Validate() error {
+	if ac.Size < 0 {
+		return fmt.Errorf("invalid size %d: must not be negative", ac.Size)
+	}
+	if ac.Weight < 0 {
+		return fmt.Errorf("invalid weight %d: must not be negative", ac.Weight)
+	}
+	if err := ValidateEnv(ac.Env); err != nil {
+		return err
+	}
+	if _, err := ParseStopSignal(ac.StopSignal); err != nil {
+		return err
+	}
+	if err := ValidateStopTimeout(ac.StopTimeout); err != nil {
+		return err
+	}
+	if err := ac.Liveness.Validate(); err != nil {
+		return err
+	}
+	if err := ValidateBackground(ac.Background); err != nil {
+		return err
+	}
+	if err := validateRestart(ac.Restart, ac.RestartDelay, ac.MaxRestarts); err != nil {
+		return err
+	}
+	if _, err := ParseLifecycleSignal(ac.LifecycleSignal); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate checks a liveness probe; a nil probe is valid
+func (lc *LivenessConfig) Validate() error {
+	if lc == nil {
+		return nil
+	}
+
+	switch lc.Probe {
+	case "output", "heartbeat":
+		if len(lc.Command) > 0 {
+			return fmt.Errorf("liveness: command is only used by the exec probe")
+		}
+	case "exec":
+		if len(lc.Command) == 0 || lc.Command[0] == "" {
+			return fmt.Errorf("liveness: exec probe requires a command")
+		}
+	default:
+		return fmt.Errorf("liveness: invalid probe %q: expected output, heartbeat or exec", lc.Probe)
+	}
+
+	if err := validateProbeDuration("interval", lc.Interval); err != nil {
+		return err
+	}
+	if err := validateProbeDuration("timeout", lc.Timeout); err != nil {
+		return err
+	}
+	if lc.Probe == "heartbeat" && lc.Timeout != "" {
+		if d, _ := time.ParseDuration(lc.Timeout); d < minHeartbeatTimeout {
+			return fmt.Errorf("liveness: invalid timeout %q: the heartbeat probe needs at least %s", lc.Timeout, minHeartbeatTimeout)
+		}
+	}
+
+	if lc.Failures < 0 {
+		return fmt.Errorf("liveness: invalid failures %d: must not be negative", lc.Failures)
+	}
+
+	switch lc.Action {
+	case "", "unhealthy", "notify", "restart":
+	default:
+		return fmt.Errorf("liveness: invalid action %q: expected unhealthy, notify or restart", lc.Action)
+	}
+
+	return nil
+}
+
+// minHeartbeatTimeout leaves room for a late heartbeat
+const minHeartbeatTimeout = 2 * rpc.HeartbeatInterval
+
+func validateProbeDuration(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("liveness: invalid %s %q: %w", field, value, err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("liveness: invalid %s %q: must be positive", field, value)
+	}
+	return nil
+}
+
+func ValidateEnv(env map[string]string) error {
+	for name := range env {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("invalid env variable name %q", name)
+		}
+	}
+	return nil
+}
+
+func ValidateLayout(layout string) error {
+	switch layout {
+	case "", "single", "hsplit", "vsplit":
+		return nil
+	default:
+		return fmt.Errorf("invalid layout %q", layout)
+	}
+}
+
+// ParsePrefixKey parses a prefix key like "C-a" into the byte a terminal
+// sends for it. ok is false when no prefix key is configured.
+func ParsePrefixKey(key string) (b byte, ok bool, err error) {
+	lower := strings.ToLower(key)
+	switch lower {
+	case "", "none":
+		return 0, false, nil
+	case "c-space", "c-@":
+		return 0x00, true, nil
+	case "c-\\":
+		return 0x1c, true, nil
+	case "c-]":
+		return 0x1d, true, nil
+	case "c-^":
+		return 0x1e, true, nil
+	case "c-_":
+		return 0x1f, true, nil
+	}
+
+	if len(lower) == 3 && strings.HasPrefix(lower, "c-") && lower[2] >= 'a' && lower[2] <= 'z' {
+		return lower[2] - 'a' + 1, true, nil
+	}
+
+	return 0, false, fmt.Errorf("invalid prefix key %q: expected C-a through C-z, C-space, C-\\, C-], C-^ or C-_", key)
+}
+
+// ParseSignal parses a signal name such as "SIGUSR1" or "hup"
+func ParseSignal(name string) (syscall.Signal, error) {
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+
+	sig := unix.SignalNum(upper)
+	if sig == 0 {
+		return 0, fmt.Errorf("unknown signal %q", name)
+	}
+	return sig, nil
+}
+
+// ParseStopSignal parses a signal name such as "SIGINT" or "hup". An empty
+// name means SIGTERM.
+func ParseStopSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return syscall.SIGTERM, nil
+	}
+
+	sig, err := ParseSignal(name)
+	if err != nil || sig == syscall.SIGSTOP || sig == syscall.SIGTSTP {
+		return 0, fmt.Errorf("invalid stop_signal %q", name)
+	}
+	return sig, nil
+}
+
+// ParseLifecycleSignal parses a lifecycle signal name; an empty name means
+// none (0). Signals that stop or kill the app are refused.
+func ParseLifecycleSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	sig, err := ParseSignal(name)
+	switch {
+	case err != nil, sig == syscall.SIGKILL, sig == syscall.SIGSTOP, sig == syscall.SIGTSTP, sig == syscall.SIGCONT:
+		return 0, fmt.Errorf("invalid lifecycle_signal %q", name)
+	}
+	return sig, nil
+}
+
+// ValidateBackground checks what an app does in the background
+func ValidateBackground(background string) error {
+	switch background {
+	case "", "suspend", "run":
+		return nil
+	}
+	return fmt.Errorf("invalid background %q: expected suspend or run", background)
+}
+
+func ValidateStopTimeout(timeout string) error {
+	if timeout == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return fmt.Errorf("invalid stop_timeout %q: %w", timeout, err)
+	}
+	if d < 0 {
+		return fmt.Errorf("invalid stop_timeout %q: must not be negative", timeout)
+	}
+	return nil
+}
+
+func ValidatePlaceholder(placeholder, text string) error {
+	switch placeholder {
+	case "", "text", "blank", "apps":
+	default:
+		return fmt.Errorf("invalid placeholder %q", placeholder)
+	}
+
+	if _, err := template.New("placeholder").Parse(text); err != nil {
+		return fmt.Errorf("invalid placeholder_text: %w", err)
+	}
 	return nil
 }
 
@@ -83,8 +352,26 @@ func ValidateRestartDelay(delay string) error {
 	if delay == "" {
 		return nil
 	}
-	if _, err := time.ParseDuration(delay); err != nil {
+	d, err := time.ParseDuration(delay)
+	if err != nil {
 		return fmt.Errorf("invalid restart_delay %q: %w", delay, err)
 	}
+	// A zero delay would restart a crashing app in a tight loop
+	if d <= 0 {
+		return fmt.Errorf("invalid restart_delay %q: must be positive", delay)
+	}
+	return nil
+}
+
+func validateRestart(policy, delay string, maxRestarts int) error {
+	if err := ValidateRestartPolicy(policy); err != nil {
+		return err
+	}
+	if err := ValidateRestartDelay(delay); err != nil {
+		return err
+	}
+	if maxRestarts < 0 {
+		return fmt.Errorf("invalid max_restarts %d: must not be negative", maxRestarts)
+	}
 	return nil
 }
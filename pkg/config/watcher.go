@@ -1,74 +1,299 @@
+// watcher.go watches the config for changes with inotify: shine.toml, every
+// prism directory in core.path, and the directories inside them that hold a
+// prism.toml, so that prism configs being edited, added or removed are
+// noticed as well. Directories are watched rather than files, since editors
+// commonly save by writing a new file and renaming it over the old one,
+// which a watch on the file itself would lose.
+//
+// Editors also touch a file several times per save, so changes are
+// debounced: the config is reloaded once nothing has changed for the
+// debounce delay. A config that fails to load or validate is never passed
+// on; it goes to the error handler instead.
+
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+	"unsafe"
+
+	"github.com/starbased-co/shine/pkg/paths"
+	"golang.org/x/sys/unix"
 )
 
+// DefaultWatchDebounce is how long the config must be left alone before a
+// change is reloaded
+const DefaultWatchDebounce = 300 * time.Millisecond
+
+// watchMask selects the events that can change what Load returns
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_CLOSE_WRITE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF | unix.IN_ONLYDIR
+
 type Watcher struct {
 	configPath string
-	lastMod    time.Time
 	onChange   func(*Config)
-	ticker     *time.Ticker
-	stop       chan bool
+	onError    func(error) // nil = log
+	debounce   time.Duration
+
+	fd      int      // inotify instance
+	file    *os.File // fd, read through the runtime poller so Stop can interrupt it
+	mu      sync.Mutex
+	watches map[int32]string // watch descriptor → directory
+	dirs    map[string]int32 // directory → watch descriptor
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
-func NewWatcher(configPath string, onChange func(*Config)) (*Watcher, error) {
-	info, err := os.Stat(configPath)
-	if err != nil {
+// WatcherOption configures a Watcher
+type WatcherOption func(*Watcher)
+
+// WithDebounce sets how long the config must be left alone before a change
+// is reloaded (default DefaultWatchDebounce)
+func WithDebounce(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// WithErrorHandler receives the errors of configs that failed to load or
+// validate, which are not passed to onChange
+func WithErrorHandler(onError func(error)) WatcherOption {
+	return func(w *Watcher) {
+		w.onError = onError
+	}
+}
+
+// NewWatcher watches the config at configPath and the prism directories it
+// names. onChange is called with each changed config once it has loaded and
+// validated.
+func NewWatcher(configPath string, onChange func(*Config), opts ...WatcherOption) (*Watcher, error) {
+	if _, err := os.Stat(configPath); err != nil {
 		return nil, err
 	}
 
-	return &Watcher{
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inotify instance: %w", err)
+	}
+
+	w := &Watcher{
 		configPath: configPath,
-		lastMod:    info.ModTime(),
 		onChange:   onChange,
-		stop:       make(chan bool),
-	}, nil
+		debounce:   DefaultWatchDebounce,
+		fd:         fd,
+		file:       os.NewFile(uintptr(fd), "inotify"),
+		watches:    make(map[int32]string),
+		dirs:       make(map[string]int32),
+		stop:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	// A config that does not load yet still has its own directory watched
+	cfg, _ := Load(configPath)
+	if err := w.updateWatches(cfg); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+
+	return w, nil
 }
 
-// Start begins watching the config file.
-// Checks for changes every second using stat polling.
+// Start begins watching for changes
 func (w *Watcher) Start() {
-	w.ticker = time.NewTicker(1 * time.Second)
+	changes := make(chan struct{}, 1)
+	go w.readEvents(changes)
+	go w.debounceChanges(changes)
+}
+
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.file.Close()
+	})
+}
+
+// watchedDirs returns the directories to watch for cfg: the one holding
+// shine.toml, the prism directories and the directories inside them
+func (w *Watcher) watchedDirs(cfg *Config) []string {
+	dirs := []string{filepath.Dir(w.configPath)}
+	if cfg == nil || cfg.Core == nil {
+		return dirs
+	}
+
+	for _, dir := range cfg.Core.GetPaths() {
+		dir = paths.ExpandHome(dir)
+		dirs = append(dirs, dir)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	return dirs
+}
+
+// updateWatches watches the directories of cfg and stops watching those it
+// no longer names. Prism directories that do not exist are skipped; they
+// are picked up once they appear in a watched directory.
+func (w *Watcher) updateWatches(cfg *Config) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	want := make(map[string]bool)
+	for i, dir := range w.watchedDirs(cfg) {
+		if want[dir] {
+			continue
+		}
+
+		if _, ok := w.dirs[dir]; !ok {
+			wd, err := unix.InotifyAddWatch(w.fd, dir, watchMask)
+			if err != nil {
+				if i == 0 {
+					return fmt.Errorf("failed to watch %s: %w", dir, err)
+				}
+				continue
+			}
+			w.watches[int32(wd)] = dir
+			w.dirs[dir] = int32(wd)
+		}
+		want[dir] = true
+	}
+
+	for dir, wd := range w.dirs {
+		if !want[dir] {
+			unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.watches, wd)
+			delete(w.dirs, dir)
+		}
+	}
+
+	return nil
+}
+
+// readEvents signals changes for every relevant inotify event until the
+// watcher is stopped
+func (w *Watcher) readEvents(changes chan<- struct{}) {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log.Printf("Error reading config events: %v", err)
+			}
+			return
+		}
+
+		changed := false
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00"))
+			offset = nameStart + int(event.Len)
+
+			if w.relevant(event.Wd, event.Mask, name) {
+				changed = true
+			}
+		}
 
-	go func() {
-		for {
+		if changed {
 			select {
-			case <-w.ticker.C:
-				w.checkForChanges()
-			case <-w.stop:
-				return
+			case changes <- struct{}{}:
+			default:
 			}
 		}
-	}()
+	}
 }
 
-func (w *Watcher) Stop() {
-	if w.ticker != nil {
-		w.ticker.Stop()
+// relevant reports whether an event can change the config. Editors' swap
+// and backup files are ignored.
+func (w *Watcher) relevant(wd int32, mask uint32, name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	dir, ok := w.watches[wd]
+	if mask&unix.IN_IGNORED != 0 && ok {
+		// The directory is gone
+		delete(w.watches, wd)
+		delete(w.dirs, dir)
+	}
+
+	switch {
+	case mask&unix.IN_Q_OVERFLOW != 0:
+		return true
+	case !ok:
+		return false
+	case name == "":
+		return mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0
+	case filepath.Join(dir, name) == filepath.Clean(w.configPath):
+		return true
+	case mask&unix.IN_ISDIR != 0:
+		return true
+	default:
+		return strings.HasSuffix(name, ".toml")
+	}
+}
+
+// debounceChanges reloads the config once changes have stopped for the
+// debounce delay
+func (w *Watcher) debounceChanges(changes <-chan struct{}) {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-changes:
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			w.reload()
+		}
 	}
-	close(w.stop)
 }
 
-func (w *Watcher) checkForChanges() {
-	info, err := os.Stat(w.configPath)
+// reload passes the config on if it loads and validates
+func (w *Watcher) reload() {
+	cfg, err := Load(w.configPath)
 	if err != nil {
-		log.Printf("Error checking config file: %v", err)
+		w.reportError(err)
 		return
 	}
 
-	if info.ModTime().After(w.lastMod) {
-		w.lastMod = info.ModTime()
+	if err := cfg.Validate(); err != nil {
+		w.reportError(fmt.Errorf("invalid configuration: %w", err))
+		return
+	}
 
-		cfg, err := Load(w.configPath)
-		if err != nil {
-			log.Printf("Error reloading config: %v", err)
-			return
-		}
+	if err := w.updateWatches(cfg); err != nil {
+		log.Printf("Error updating config watches: %v", err)
+	}
 
-		log.Printf("Config file changed, reloading...")
-		w.onChange(cfg)
+	log.Printf("Config changed, reloading...")
+	w.onChange(cfg)
+}
+
+func (w *Watcher) reportError(err error) {
+	if w.onError == nil {
+		log.Printf("Error reloading config: %v", err)
+		return
 	}
+	w.onError(err)
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -115,3 +116,88 @@ func TestWatcherStop(t *testing.T) {
 	// Change should not be detected
 	time.Sleep(1500 * time.Millisecond)
 }
+
+func TestWatcherDebouncesAndValidates(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "shine.toml")
+	prismDir := filepath.Join(tmpDir, "prisms")
+	if err := os.MkdirAll(filepath.Join(prismDir, "weather"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	write(configPath, "[core]\npath = \""+prismDir+"\"\n")
+
+	changes := make(chan *Config, 10)
+	errs := make(chan error, 10)
+	watcher, err := NewWatcher(configPath, func(cfg *Config) { changes <- cfg },
+		WithDebounce(50*time.Millisecond), WithErrorHandler(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+	watcher.Start()
+
+	expectChange := func() *Config {
+		t.Helper()
+		select {
+		case cfg := <-changes:
+			return cfg
+		case err := <-errs:
+			t.Fatalf("Unexpected reload error: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("Config change was not detected")
+		}
+		return nil
+	}
+	expectNothing := func() {
+		t.Helper()
+		select {
+		case <-changes:
+			t.Error("Unexpected extra reload")
+		case err := <-errs:
+			t.Errorf("Unexpected reload error: %v", err)
+		case <-time.After(150 * time.Millisecond):
+		}
+	}
+
+	// An editor saving through a temporary file, several times over, is
+	// reloaded once
+	for i := 0; i < 3; i++ {
+		tmp := configPath + ".tmp"
+		write(tmp, "[core]\npath = \""+prismDir+"\"\ntheme = \"nord\"\n")
+		if err := os.Rename(tmp, configPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cfg := expectChange(); cfg.Core.Theme != "nord" {
+		t.Errorf("Expected the saved theme, got %q", cfg.Core.Theme)
+	}
+	expectNothing()
+
+	// Swap files are ignored, a prism.toml in a prism directory is not
+	write(filepath.Join(prismDir, "weather", ".prism.toml.swp"), "x")
+	expectNothing()
+	write(filepath.Join(prismDir, "weather", "prism.toml"), "name = \"weather\"\n")
+	if cfg := expectChange(); cfg.Prisms["weather"] == nil {
+		t.Error("Expected the new prism to be discovered")
+	}
+
+	// A config that fails validation is reported, not applied
+	write(filepath.Join(prismDir, "weather", "prism.toml"), "name = \"weather\"\nrestart = \"sometimes\"\n")
+	select {
+	case cfg := <-changes:
+		t.Fatalf("Invalid config was applied: %+v", cfg.Prisms["weather"])
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "sometimes") {
+			t.Errorf("Expected the validation error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Invalid config was not reported")
+	}
+}
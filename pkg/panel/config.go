@@ -383,6 +383,35 @@ func (c *Config) ToPanelArgs(componentPath string) []string {
 		"--type=os-panel",
 	}
 
+	for _, prop := range c.panelProps() {
+		args = append(args, "--os-panel", prop)
+	}
+
+	if c.WindowTitle != "" {
+		args = append(args, "--title", c.WindowTitle)
+	}
+
+	args = append(args, componentPath)
+
+	return args
+}
+
+// ToResizeArgs returns the kitten arguments that apply this config to the
+// running panel in window windowID. Settings it leaves out are reset to
+// their defaults, as for a new panel.
+func (c *Config) ToResizeArgs(windowID string) []string {
+	args := []string{
+		"@",
+		"resize-os-window",
+		"--match", "id:" + windowID,
+		"--action=os-panel",
+	}
+
+	return append(args, c.panelProps()...)
+}
+
+// panelProps returns the os-panel settings of this config
+func (c *Config) panelProps() []string {
 	panelProps := []string{}
 
 	edgeStr := c.originToEdge()
@@ -421,16 +450,6 @@ func (c *Config) ToPanelArgs(componentPath string) []string {
 		panelProps = append(panelProps, fmt.Sprintf("output-name=%s", c.OutputName))
 	}
 
-	for _, prop := range panelProps {
-		args = append(args, "--os-panel", prop)
-	}
-
-	if c.WindowTitle != "" {
-		args = append(args, "--title", c.WindowTitle)
-	}
-
-	args = append(args, componentPath)
-
-	return args
+	return panelProps
 }
 
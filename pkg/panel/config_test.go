@@ -320,6 +320,28 @@ func TestToPanelArgs_Center(t *testing.T) {
 	}
 }
 
+func TestToResizeArgs(t *testing.T) {
+	cfg := &Config{
+		Type:        LayerShellPanel,
+		Origin:      OriginTopLeft,
+		Width:       Dimension{Value: 80, IsPixels: false},
+		Height:      Dimension{Value: 2, IsPixels: false},
+		FocusPolicy: FocusOnDemand,
+	}
+
+	args := cfg.ToResizeArgs("42")
+
+	want := []string{"@", "resize-os-window", "--match", "id:42", "--action=os-panel", "edge=top", "columns=80", "lines=2", "focus-policy=on-demand"}
+	if len(args) != len(want) {
+		t.Fatalf("ToResizeArgs() = %v, want %v", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("ToResizeArgs()[%d] = %q, want %q", i, args[i], want[i])
+		}
+	}
+}
+
 func TestToPanelArgs_TopRightCorner(t *testing.T) {
 	cfg := &Config{
 		Type:       LayerShellPanel,
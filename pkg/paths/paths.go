@@ -40,6 +40,10 @@ func LogDir() string {
 	return filepath.Join(DataDir(), "logs")
 }
 
+func RecordingDir() string {
+	return filepath.Join(DataDir(), "recordings")
+}
+
 func RuntimeDir() string {
 	uid := os.Getuid()
 	return filepath.Join("/run/user", fmt.Sprintf("%d", uid), "shine")
@@ -53,6 +57,14 @@ func PrismSocket(instance string) string {
 	return filepath.Join(RuntimeDir(), fmt.Sprintf("prism-%s.sock", instance))
 }
 
+func PrismAttachSocket(instance string) string {
+	return filepath.Join(RuntimeDir(), fmt.Sprintf("prism-%s.attach.sock", instance))
+}
+
+func PrismChildSocket(instance string) string {
+	return filepath.Join(RuntimeDir(), fmt.Sprintf("prism-%s.child.sock", instance))
+}
+
 func PrismState(instance string) string {
 	return filepath.Join(RuntimeDir(), fmt.Sprintf("prism-%s.state", instance))
 }
@@ -0,0 +1,65 @@
+// Package prism is the SDK for prism binaries. prismctl starts every prism
+// with its identity, theme and settings in the environment; this package
+// reads them, runs a bubbletea program the way a panel needs it (see Run),
+// and connects to prismctl's child socket (see Connect).
+//
+// Outside of shine, e.g. when a prism binary is run by hand in a terminal,
+// the identity falls back to the binary's name and settings are empty, so a
+// prism works the same minus the panel.
+package prism
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+// ErrNotSupervised is returned by Connect when the prism was not started by
+// prismctl
+var ErrNotSupervised = errors.New("not started by prismctl")
+
+// Instance returns the prism's instance ID, e.g. "clock" or "clock:2". It is
+// unique within the panel.
+func Instance() string {
+	if id := os.Getenv(rpc.EnvPrism); id != "" {
+		return id
+	}
+	return App()
+}
+
+// App returns the name of the app the prism runs, the name its settings
+// are configured under
+func App() string {
+	if app := os.Getenv(rpc.EnvApp); app != "" {
+		return app
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// Panel returns the instance name of the panel the prism runs in, empty
+// outside of shine
+func Panel() string {
+	return os.Getenv(rpc.EnvPanel)
+}
+
+// Theme returns the configured theme name, empty when none is set
+func Theme() string {
+	return os.Getenv(rpc.EnvTheme)
+}
+
+// Supervised reports whether the prism was started by prismctl
+func Supervised() bool {
+	return os.Getenv(rpc.EnvPrismctlSocket) != ""
+}
+
+// Connect connects to the prismctl running the prism. Every request on the
+// connection acts on the prism itself.
+func Connect(opts ...rpc.ClientOption) (*rpc.ChildClient, error) {
+	sockPath := os.Getenv(rpc.EnvPrismctlSocket)
+	if sockPath == "" {
+		return nil, ErrNotSupervised
+	}
+	return rpc.NewChildClient(sockPath, opts...)
+}
@@ -0,0 +1,161 @@
+package prism
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/sys/unix"
+)
+
+func TestIdentity(t *testing.T) {
+	t.Setenv("SHINE_PRISM", "weather:2")
+	t.Setenv("SHINE_APP", "weather")
+	t.Setenv("SHINE_PANEL", "panel-weather")
+	t.Setenv("SHINE_THEME", "nord")
+
+	if got := Instance(); got != "weather:2" {
+		t.Errorf("Instance() = %q, want weather:2", got)
+	}
+	if got := App(); got != "weather" {
+		t.Errorf("App() = %q, want weather", got)
+	}
+	if got := Panel(); got != "panel-weather" {
+		t.Errorf("Panel() = %q, want panel-weather", got)
+	}
+	if got := Theme(); got != "nord" {
+		t.Errorf("Theme() = %q, want nord", got)
+	}
+}
+
+func TestIdentity_Unsupervised(t *testing.T) {
+	for _, name := range []string{"SHINE_PRISM", "SHINE_APP", "SHINE_PRISMCTL_SOCKET"} {
+		t.Setenv(name, "")
+	}
+
+	if got := Instance(); got != "prism.test" {
+		t.Errorf("Instance() = %q, want the binary name", got)
+	}
+	if Supervised() {
+		t.Error("Supervised() = true without a child socket")
+	}
+	if _, err := Connect(); !errors.Is(err, ErrNotSupervised) {
+		t.Errorf("Connect() error = %v, want ErrNotSupervised", err)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	t.Setenv("SHINE_SETTINGS", `{"city":"Berlin","refresh":"10m","days":3,"ratio":0.5,"metric":true,"alerts":["rain","wind"]}`)
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error: %v", err)
+	}
+
+	if got := s.String("city", ""); got != "Berlin" {
+		t.Errorf("String(city) = %q", got)
+	}
+	if got := s.Duration("refresh", time.Minute); got != 10*time.Minute {
+		t.Errorf("Duration(refresh) = %v", got)
+	}
+	if got := s.Int("days", 1); got != 3 {
+		t.Errorf("Int(days) = %d", got)
+	}
+	if got := s.Int("ratio", 1); got != 1 {
+		t.Errorf("Int(ratio) = %d, want the default for a fraction", got)
+	}
+	if got := s.Float("ratio", 0); got != 0.5 {
+		t.Errorf("Float(ratio) = %v", got)
+	}
+	if !s.Bool("metric", false) {
+		t.Error("Bool(metric) = false")
+	}
+	if got := s.Strings("alerts", nil); len(got) != 2 || got[1] != "wind" {
+		t.Errorf("Strings(alerts) = %q", got)
+	}
+
+	// Missing keys and other types give the default
+	if got := s.String("days", "none"); got != "none" {
+		t.Errorf("String(days) = %q, want the default", got)
+	}
+	if got := s.Duration("city", time.Minute); got != time.Minute {
+		t.Errorf("Duration(city) = %v, want the default", got)
+	}
+	if s.Has("units") || !s.Has("city") {
+		t.Error("Has() reports the wrong keys")
+	}
+
+	cfg := struct {
+		City  string `json:"city"`
+		Units string `json:"units"`
+	}{Units: "metric"}
+	if err := s.Decode(&cfg); err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if cfg.City != "Berlin" || cfg.Units != "metric" {
+		t.Errorf("Decode() = %+v, want the city and the default units", cfg)
+	}
+
+	if _, err := ParseSettings("[1]"); err == nil {
+		t.Error("ParseSettings() should reject settings that are not an object")
+	}
+	if s, err := ParseSettings(""); err != nil || s.Has("city") {
+		t.Errorf("ParseSettings(\"\") = %v, %v, want empty settings", s, err)
+	}
+}
+
+type shutdownModel struct {
+	started  chan struct{}
+	shutdown os.Signal
+}
+
+func (m *shutdownModel) Init() tea.Cmd {
+	close(m.started)
+	return nil
+}
+
+func (m *shutdownModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(ShutdownMsg); ok {
+		m.shutdown = msg.Signal
+	}
+	return m, nil
+}
+
+func (m *shutdownModel) View() string { return "" }
+
+func TestRun_ShutdownOnSIGTERM(t *testing.T) {
+	model := &shutdownModel{started: make(chan struct{})}
+
+	type result struct {
+		model tea.Model
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		final, err := Run(model, WithTitle(""), WithProgramOptions(tea.WithInput(nil), tea.WithOutput(io.Discard)))
+		done <- result{final, err}
+	}()
+
+	<-model.started
+	if err := unix.Kill(os.Getpid(), unix.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Errorf("Run() error: %v", r.err)
+		}
+		if r.model != model {
+			t.Errorf("Run() returned %T, want the prism's model", r.model)
+		}
+		if model.shutdown != unix.SIGTERM {
+			t.Errorf("model saw shutdown signal %v, want SIGTERM", model.shutdown)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after SIGTERM")
+	}
+}
@@ -0,0 +1,177 @@
+package prism
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/sys/unix"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+// ShutdownMsg is sent to the model when the prism is asked to stop, by
+// prismctl's stop signal or an interrupt. The program quits once the
+// command the model returns for it has run, so the model can save its state
+// first; a model that ignores it simply quits.
+type ShutdownMsg struct {
+	Signal os.Signal
+}
+
+// ResumeMsg is sent to the model when the prism is resumed after being
+// suspended in the background. Nothing ran while it was suspended, so a
+// model showing the time or polling something should refresh.
+type ResumeMsg struct{}
+
+// heartbeatMsg schedules the next child/heartbeat
+type heartbeatMsg struct{}
+
+// Option configures Run
+type Option func(*options)
+
+type options struct {
+	altScreen bool
+	title     string
+	program   []tea.ProgramOption
+}
+
+// WithAltScreen runs the program in the alternate screen, for prisms that
+// fill their panel. Thin bars are better off without it.
+func WithAltScreen() Option {
+	return func(o *options) {
+		o.altScreen = true
+	}
+}
+
+// WithTitle sets the window title (default: the instance ID)
+func WithTitle(title string) Option {
+	return func(o *options) {
+		o.title = title
+	}
+}
+
+// WithProgramOptions passes further options to tea.NewProgram
+func WithProgramOptions(opts ...tea.ProgramOption) Option {
+	return func(o *options) {
+		o.program = append(o.program, opts...)
+	}
+}
+
+// Run runs model as the prism's bubbletea program until it quits or the
+// prism is stopped, and returns the final model. On top of tea.Program it
+// sets the window title, keeps the model's size current after the prism is
+// resumed, sends ResumeMsg then, and turns SIGTERM, SIGINT and SIGHUP into
+// a ShutdownMsg instead of quitting under the model's feet. A prism stopped
+// this way returns a nil error. Focus reporting is on, so the model gets
+// tea.FocusMsg and tea.BlurMsg as the prism gains and loses focus in its
+// panel. Under prismctl it calls child/heartbeat every
+// rpc.HeartbeatInterval from the program's event loop, so a model that
+// stops processing messages fails the heartbeat liveness probe.
+func Run(model tea.Model, opts ...Option) (tea.Model, error) {
+	o := options{title: Instance()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	programOpts := []tea.ProgramOption{tea.WithoutSignalHandler(), tea.WithReportFocus()}
+	if o.altScreen {
+		programOpts = append(programOpts, tea.WithAltScreen())
+	}
+	programOpts = append(programOpts, o.program...)
+
+	m := programModel{model: model, title: o.title}
+	if child, err := Connect(); err == nil {
+		defer child.Close()
+		m.child = child
+	}
+
+	p := tea.NewProgram(m, programOpts...)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, unix.SIGTERM, unix.SIGINT, unix.SIGHUP, unix.SIGCONT)
+	done := make(chan struct{})
+	defer func() {
+		signal.Stop(signals)
+		close(done)
+	}()
+	go forwardSignals(p, signals, done)
+
+	final, err := p.Run()
+	if m, ok := final.(programModel); ok {
+		final = m.model
+	}
+	return final, err
+}
+
+// forwardSignals turns signals into messages for p until done is closed
+func forwardSignals(p *tea.Program, signals <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-signals:
+			if sig == unix.SIGCONT {
+				// The panel may have been resized while the prism was suspended
+				p.Send(tea.WindowSize()())
+				p.Send(ResumeMsg{})
+				continue
+			}
+			p.Send(ShutdownMsg{Signal: sig})
+		}
+	}
+}
+
+// programModel wraps the prism's model to set the title, send heartbeats
+// and quit after a ShutdownMsg
+type programModel struct {
+	model tea.Model
+	title string
+	child *rpc.ChildClient // nil when not started by prismctl
+}
+
+func (m programModel) Init() tea.Cmd {
+	cmds := []tea.Cmd{m.model.Init(), m.nextHeartbeat()}
+	if m.title != "" {
+		cmds = append(cmds, tea.SetWindowTitle(m.title))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m programModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(heartbeatMsg); ok {
+		return m, tea.Batch(m.sendHeartbeat, m.nextHeartbeat())
+	}
+
+	var cmd tea.Cmd
+	m.model, cmd = m.model.Update(msg)
+
+	if _, ok := msg.(ShutdownMsg); ok {
+		return m, tea.Sequence(cmd, tea.Quit)
+	}
+	return m, cmd
+}
+
+func (m programModel) View() string {
+	return m.model.View()
+}
+
+// nextHeartbeat schedules a heartbeat, nil when not started by prismctl.
+// Only Update schedules the next one, so heartbeats stop with the event loop.
+func (m programModel) nextHeartbeat() tea.Cmd {
+	if m.child == nil {
+		return nil
+	}
+	return tea.Tick(rpc.HeartbeatInterval, func(time.Time) tea.Msg {
+		return heartbeatMsg{}
+	})
+}
+
+func (m programModel) sendHeartbeat() tea.Msg {
+	ctx, cancel := context.WithTimeout(context.Background(), rpc.HeartbeatInterval)
+	defer cancel()
+
+	m.child.Heartbeat(ctx)
+	return nil
+}
@@ -0,0 +1,118 @@
+package prism
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/starbased-co/shine/pkg/rpc"
+)
+
+// Settings are the values configured for the app in shine.toml:
+//
+//	[prisms.weather.settings]
+//	city = "Berlin"
+//	refresh = "10m"
+//
+// Accessors return def when a key is missing or holds a value of another
+// type; Decode fills a struct instead.
+type Settings struct {
+	values map[string]any
+	raw    []byte
+}
+
+// LoadSettings reads the settings prismctl passed to the prism. Without any
+// they are empty.
+func LoadSettings() (Settings, error) {
+	return ParseSettings(os.Getenv(rpc.EnvSettings))
+}
+
+// ParseSettings parses settings encoded as a JSON object, as found in
+// SHINE_SETTINGS
+func ParseSettings(data string) (Settings, error) {
+	if data == "" {
+		return Settings{}, nil
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return Settings{}, fmt.Errorf("invalid %s: %w", rpc.EnvSettings, err)
+	}
+	return Settings{values: values, raw: []byte(data)}, nil
+}
+
+// Has reports whether key is set
+func (s Settings) Has(key string) bool {
+	_, ok := s.values[key]
+	return ok
+}
+
+func (s Settings) String(key, def string) string {
+	if v, ok := s.values[key].(string); ok {
+		return v
+	}
+	return def
+}
+
+func (s Settings) Bool(key string, def bool) bool {
+	if v, ok := s.values[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// Int returns an integer setting; a number with a fraction is not one
+func (s Settings) Int(key string, def int) int {
+	if v, ok := s.values[key].(float64); ok && v == math.Trunc(v) {
+		return int(v)
+	}
+	return def
+}
+
+func (s Settings) Float(key string, def float64) float64 {
+	if v, ok := s.values[key].(float64); ok {
+		return v
+	}
+	return def
+}
+
+// Duration returns a setting written as a Go duration such as "10m"
+func (s Settings) Duration(key string, def time.Duration) time.Duration {
+	if v, ok := s.values[key].(string); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// Strings returns a list of strings; any element of another type makes it
+// def
+func (s Settings) Strings(key string, def []string) []string {
+	list, ok := s.values[key].([]any)
+	if !ok {
+		return def
+	}
+
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		str, ok := item.(string)
+		if !ok {
+			return def
+		}
+		out = append(out, str)
+	}
+	return out
+}
+
+// Decode unmarshals the settings into v, a pointer to a struct whose json
+// tags name the settings. Fields of missing settings are left alone, so v
+// can hold the defaults.
+func (s Settings) Decode(v any) error {
+	if len(s.raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(s.raw, v)
+}
@@ -15,6 +15,8 @@ type Client struct {
 	conn     net.Conn
 	client   *jrpc2.Client
 	timeout  time.Duration
+	onEvent  func(*Event)
+	done     chan struct{}
 }
 
 type ClientOption func(*Client)
@@ -25,10 +27,19 @@ func WithTimeout(d time.Duration) ClientOption {
 	}
 }
 
+// WithEventHandler receives the events pushed by shined once subscribed
+// with ShinedClient.SubscribeEvents
+func WithEventHandler(fn func(*Event)) ClientOption {
+	return func(c *Client) {
+		c.onEvent = fn
+	}
+}
+
 func NewClient(sockPath string, opts ...ClientOption) (*Client, error) {
 	c := &Client{
 		sockPath: sockPath,
 		timeout:  5 * time.Second,
+		done:     make(chan struct{}),
 	}
 
 	for _, opt := range opts {
@@ -42,11 +53,32 @@ func NewClient(sockPath string, opts ...ClientOption) (*Client, error) {
 
 	ch := channel.Line(conn, conn)
 	c.conn = conn
-	c.client = jrpc2.NewClient(ch, nil)
+	c.client = jrpc2.NewClient(ch, &jrpc2.ClientOptions{
+		OnNotify: c.handleNotification,
+		OnStop: func(*jrpc2.Client, error) {
+			close(c.done)
+		},
+	})
 
 	return c, nil
 }
 
+func (c *Client) handleNotification(req *jrpc2.Request) {
+	if c.onEvent == nil || req.Method() != "event" {
+		return
+	}
+
+	var event Event
+	if err := req.UnmarshalParams(&event); err == nil {
+		c.onEvent(&event)
+	}
+}
+
+// Done is closed once the connection is closed, by either side
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
 func (c *Client) Close() error {
 	if c.client != nil {
 		c.client.Close()
@@ -83,12 +115,25 @@ func (c *PrismClient) Up(ctx context.Context, name string) (*UpResult, error) {
 	return &result, err
 }
 
+// UpNew launches another instance of app, even if one is already running
+func (c *PrismClient) UpNew(ctx context.Context, app string) (*UpResult, error) {
+	var result UpResult
+	err := c.Call(ctx, "prism/up", &UpRequest{Name: app, NewInstance: true}, &result)
+	return &result, err
+}
+
 func (c *PrismClient) Down(ctx context.Context, name string) (*DownResult, error) {
 	var result DownResult
 	err := c.Call(ctx, "prism/down", &DownRequest{Name: name}, &result)
 	return &result, err
 }
 
+func (c *PrismClient) DownWait(ctx context.Context, name string) (*DownResult, error) {
+	var result DownResult
+	err := c.Call(ctx, "prism/down", &DownRequest{Name: name, Wait: true}, &result)
+	return &result, err
+}
+
 func (c *PrismClient) Fg(ctx context.Context, name string) (*FgResult, error) {
 	var result FgResult
 	err := c.Call(ctx, "prism/fg", &FgRequest{Name: name}, &result)
@@ -107,6 +152,42 @@ func (c *PrismClient) List(ctx context.Context) (*ListResult, error) {
 	return &result, err
 }
 
+func (c *PrismClient) Capture(ctx context.Context, req *CaptureRequest) (*CaptureResult, error) {
+	var result CaptureResult
+	err := c.Call(ctx, "prism/capture", req, &result)
+	return &result, err
+}
+
+func (c *PrismClient) SendKeys(ctx context.Context, req *SendKeysRequest) (*SendKeysResult, error) {
+	var result SendKeysResult
+	err := c.Call(ctx, "prism/send-keys", req, &result)
+	return &result, err
+}
+
+func (c *PrismClient) Signal(ctx context.Context, name, signal string) (*SignalResult, error) {
+	var result SignalResult
+	err := c.Call(ctx, "prism/signal", &SignalRequest{Name: name, Signal: signal}, &result)
+	return &result, err
+}
+
+func (c *PrismClient) History(ctx context.Context, name string) (*HistoryResult, error) {
+	var result HistoryResult
+	err := c.Call(ctx, "prism/history", &HistoryRequest{Name: name}, &result)
+	return &result, err
+}
+
+func (c *PrismClient) Record(ctx context.Context, name string, enabled bool) (*RecordResult, error) {
+	var result RecordResult
+	err := c.Call(ctx, "prism/record", &RecordRequest{Name: name, Enabled: enabled}, &result)
+	return &result, err
+}
+
+func (c *PrismClient) SetVisibility(ctx context.Context, hidden bool) (*VisibilityResult, error) {
+	var result VisibilityResult
+	err := c.Call(ctx, "prism/visibility", &VisibilityRequest{Hidden: hidden}, &result)
+	return &result, err
+}
+
 func (c *PrismClient) Health(ctx context.Context) (*HealthResult, error) {
 	var result HealthResult
 	err := c.Call(ctx, "service/health", nil, &result)
@@ -119,9 +200,15 @@ func (c *PrismClient) Shutdown(ctx context.Context, graceful bool) (*ShutdownRes
 	return &result, err
 }
 
-func (c *PrismClient) Configure(ctx context.Context, apps []AppInfo) (*ConfigureResult, error) {
+func (c *PrismClient) Configure(ctx context.Context, req *ConfigureRequest) (*ConfigureResult, error) {
 	var result ConfigureResult
-	err := c.Call(ctx, "prism/configure", &ConfigureRequest{Apps: apps}, &result)
+	err := c.Call(ctx, "prism/configure", req, &result)
+	return &result, err
+}
+
+func (c *PrismClient) Reconfigure(ctx context.Context, req *ConfigureRequest) (*ReconfigureResult, error) {
+	var result ReconfigureResult
+	err := c.Call(ctx, "prism/reconfigure", req, &result)
 	return &result, err
 }
 
@@ -155,6 +242,12 @@ func (c *ShinedClient) KillPanel(ctx context.Context, instance string) (*PanelKi
 	return &result, err
 }
 
+func (c *ShinedClient) ResetFailed(ctx context.Context, instance, name string) (*PanelResetFailedResult, error) {
+	var result PanelResetFailedResult
+	err := c.Call(ctx, "panel/reset-failed", &PanelResetFailedRequest{Instance: instance, Name: name}, &result)
+	return &result, err
+}
+
 func (c *ShinedClient) Status(ctx context.Context) (*ServiceStatusResult, error) {
 	var result ServiceStatusResult
 	err := c.Call(ctx, "service/status", nil, &result)
@@ -167,6 +260,12 @@ func (c *ShinedClient) Reload(ctx context.Context) (*ConfigReloadResult, error)
 	return &result, err
 }
 
+func (c *ShinedClient) SubscribeEvents(ctx context.Context) (*EventsSubscribeResult, error) {
+	var result EventsSubscribeResult
+	err := c.Call(ctx, "events/subscribe", nil, &result)
+	return &result, err
+}
+
 func (c *ShinedClient) NotifyPrismStarted(ctx context.Context, panel, name string, pid int) error {
 	return c.Notify(ctx, "prism/started", &PrismStartedNotification{
 		Panel: panel,
@@ -175,11 +274,12 @@ func (c *ShinedClient) NotifyPrismStarted(ctx context.Context, panel, name strin
 	})
 }
 
-func (c *ShinedClient) NotifyPrismStopped(ctx context.Context, panel, name string, exitCode int) error {
+func (c *ShinedClient) NotifyPrismStopped(ctx context.Context, panel, name string, exitCode int, requested bool) error {
 	return c.Notify(ctx, "prism/stopped", &PrismStoppedNotification{
-		Panel:    panel,
-		Name:     name,
-		ExitCode: exitCode,
+		Panel:     panel,
+		Name:      name,
+		ExitCode:  exitCode,
+		Requested: requested,
 	})
 }
 
@@ -192,6 +292,15 @@ func (c *ShinedClient) NotifyPrismCrashed(ctx context.Context, panel, name strin
 	})
 }
 
+func (c *ShinedClient) NotifyPrismHealth(ctx context.Context, panel, name string, healthy bool, reason string) error {
+	return c.Notify(ctx, "prism/health", &PrismHealthNotification{
+		Panel:   panel,
+		Name:    name,
+		Healthy: healthy,
+		Reason:  reason,
+	})
+}
+
 func (c *ShinedClient) NotifyForegroundChanged(ctx context.Context, panel, from, to string) error {
 	return c.Notify(ctx, "foreground/changed", &ForegroundChangedNotification{
 		Panel: panel,
@@ -199,3 +308,65 @@ func (c *ShinedClient) NotifyForegroundChanged(ctx context.Context, panel, from,
 		To:    to,
 	})
 }
+
+// ChildClient is used by a prism to talk to the prismctl supervising it.
+// prismctl identifies the prism by the calling process, so every method
+// acts on the caller itself.
+type ChildClient struct {
+	*Client
+}
+
+// HeartbeatInterval is how often prisms built with prism.Run call
+// child/heartbeat, for the heartbeat liveness probe
+const HeartbeatInterval = time.Second
+
+func NewChildClient(sockPath string, opts ...ClientOption) (*ChildClient, error) {
+	c, err := NewClient(sockPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildClient{Client: c}, nil
+}
+
+func (c *ChildClient) Foreground(ctx context.Context) (*FgResult, error) {
+	var result FgResult
+	err := c.Call(ctx, "child/foreground", nil, &result)
+	return &result, err
+}
+
+func (c *ChildClient) Yield(ctx context.Context) (*BgResult, error) {
+	var result BgResult
+	err := c.Call(ctx, "child/yield", nil, &result)
+	return &result, err
+}
+
+func (c *ChildClient) SetStatus(ctx context.Context, status string) (*ChildResult, error) {
+	var result ChildResult
+	err := c.Call(ctx, "child/status", &ChildStatusRequest{Status: status}, &result)
+	return &result, err
+}
+
+func (c *ChildClient) RequestAttention(ctx context.Context, message string) (*ChildResult, error) {
+	var result ChildResult
+	err := c.Call(ctx, "child/attention", &ChildAttentionRequest{Message: message}, &result)
+	return &result, err
+}
+
+func (c *ChildClient) ExitPanel(ctx context.Context) (*ChildResult, error) {
+	var result ChildResult
+	err := c.Call(ctx, "child/exit-panel", nil, &result)
+	return &result, err
+}
+
+func (c *ChildClient) Lifecycle(ctx context.Context) (*LifecycleResult, error) {
+	var result LifecycleResult
+	err := c.Call(ctx, "child/lifecycle", nil, &result)
+	return &result, err
+}
+
+// Heartbeat tells prismctl the prism is still processing events
+func (c *ChildClient) Heartbeat(ctx context.Context) (*ChildResult, error) {
+	var result ChildResult
+	err := c.Call(ctx, "child/heartbeat", nil, &result)
+	return &result, err
+}
@@ -22,6 +22,7 @@ const (
 	CodeResourceBusy     = -32007 // Resource is busy
 	CodeOperationFailed  = -32008 // Operation failed
 	CodeNotImplemented   = -32009 // Method not implemented
+	CodePermissionDenied = -32010 // Caller may not use the method
 )
 
 func ErrPrismNotFound(name string) error {
@@ -67,3 +68,7 @@ func ErrInternal(err error) error {
 func ErrNotImplemented(method string) error {
 	return jrpc2.Errorf(CodeNotImplemented, "method not implemented: %s", method)
 }
+
+func ErrPermissionDenied(msg string) error {
+	return jrpc2.Errorf(CodePermissionDenied, "permission denied: %s", msg)
+}
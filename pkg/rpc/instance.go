@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Instance IDs address prisms in RPC methods. The first instance of an app
+// is named after the app; prism/up with new_instance launches further ones
+// named "<app>:2", "<app>:3" and so on.
+
+// instanceSeparator separates an app's name from an instance number
+const instanceSeparator = ":"
+
+// InstanceID returns the ID of instance n of app, counting from 1
+func InstanceID(app string, n int) string {
+	if n <= 1 {
+		return app
+	}
+	return app + instanceSeparator + strconv.Itoa(n)
+}
+
+// SplitInstanceID returns the app and number of an instance ID of the form
+// "<app>:N" with N > 1. ok is false for any other ID, which names the first
+// instance of an app: an app's own name may contain ":".
+func SplitInstanceID(id string) (app string, n int, ok bool) {
+	i := strings.LastIndex(id, instanceSeparator)
+	if i <= 0 {
+		return "", 0, false
+	}
+
+	n, err := strconv.Atoi(id[i+1:])
+	if err != nil || n <= 1 {
+		return "", 0, false
+	}
+	return id[:i], n, true
+}
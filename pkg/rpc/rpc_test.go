@@ -2,11 +2,14 @@ package rpc
 
 import (
 	"context"
+	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/creachadair/jrpc2/handler"
+	"golang.org/x/sys/unix"
 )
 
 func TestServerClientRoundtrip(t *testing.T) {
@@ -375,6 +378,90 @@ func TestShinedClientMethods(t *testing.T) {
 	}
 }
 
+func TestServerPeerCred(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+
+	mux := handler.Map{
+		"whoami": handler.New(func(ctx context.Context) (int, error) {
+			cred, ok := PeerCred(ctx)
+			if !ok {
+				return 0, ErrPermissionDenied("no credentials")
+			}
+			return int(cred.Pid), nil
+		}),
+	}
+
+	srv := NewServer(sockPath, mux, nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := NewClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	var pid int
+	if err := client.Call(context.Background(), "whoami", nil, &pid); err != nil {
+		t.Fatalf("whoami call error: %v", err)
+	}
+	if pid != os.Getpid() {
+		t.Errorf("peer PID = %d, want %d", pid, os.Getpid())
+	}
+}
+
+func TestServerPeerContext(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+
+	type pidKey struct{}
+	var calls atomic.Int32
+
+	mux := handler.Map{
+		"whoami": handler.New(func(ctx context.Context) (int, error) {
+			pid, ok := ctx.Value(pidKey{}).(int32)
+			if !ok {
+				return 0, ErrPermissionDenied("no peer")
+			}
+			return int(pid), nil
+		}),
+	}
+
+	srv := NewServer(sockPath, mux, nil, WithPeerContext(func(ctx context.Context, cred *unix.Ucred) context.Context {
+		calls.Add(1)
+		return context.WithValue(ctx, pidKey{}, cred.Pid)
+	}))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+
+	client, err := NewClient(sockPath)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	for range 3 {
+		var pid int
+		if err := client.Call(context.Background(), "whoami", nil, &pid); err != nil {
+			t.Fatalf("whoami call error: %v", err)
+		}
+		if pid != os.Getpid() {
+			t.Errorf("peer PID = %d, want %d", pid, os.Getpid())
+		}
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("peer context built %d times, want once per connection", n)
+	}
+}
+
 func TestClientTimeout(t *testing.T) {
 	tmpDir := t.TempDir()
 	sockPath := filepath.Join(tmpDir, "nonexistent.sock")
@@ -402,6 +489,7 @@ func TestErrorCodes(t *testing.T) {
 		{"InvalidParams", ErrInvalidParams("missing name"), CodeInvalidParams},
 		{"Internal", ErrInternal(nil), CodeInternal},
 		{"NotImplemented", ErrNotImplemented("method"), CodeNotImplemented},
+		{"PermissionDenied", ErrPermissionDenied("not a prism"), CodePermissionDenied},
 	}
 
 	for _, tt := range tests {
@@ -412,3 +500,33 @@ func TestErrorCodes(t *testing.T) {
 		})
 	}
 }
+
+func TestInstanceIDs(t *testing.T) {
+	tests := []struct {
+		id  string
+		app string
+		n   int
+		ok  bool
+	}{
+		{"clock", "", 0, false},
+		{"clock:2", "clock", 2, true},
+		{"clock:utc:3", "clock:utc", 3, true},
+		{"clock:utc", "", 0, false},
+		{"clock:1", "", 0, false},
+		{"clock:", "", 0, false},
+		{":2", "", 0, false},
+	}
+	for _, tt := range tests {
+		app, n, ok := SplitInstanceID(tt.id)
+		if app != tt.app || n != tt.n || ok != tt.ok {
+			t.Errorf("SplitInstanceID(%q) = %q, %d, %v, want %q, %d, %v", tt.id, app, n, ok, tt.app, tt.n, tt.ok)
+		}
+	}
+
+	if id := InstanceID("clock", 1); id != "clock" {
+		t.Errorf("InstanceID(clock, 1) = %q, want clock", id)
+	}
+	if id := InstanceID("clock", 3); id != "clock:3" {
+		t.Errorf("InstanceID(clock, 3) = %q, want clock:3", id)
+	}
+}
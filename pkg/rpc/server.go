@@ -11,6 +11,7 @@ import (
 	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/channel"
 	"github.com/creachadair/jrpc2/handler"
+	"golang.org/x/sys/unix"
 )
 
 type Server struct {
@@ -19,23 +20,40 @@ type Server struct {
 	mux      handler.Map
 	opts     *jrpc2.ServerOptions
 
+	peerContext func(ctx context.Context, cred *unix.Ucred) context.Context
+
 	mu       sync.Mutex
 	running  bool
 	servers  map[net.Conn]*jrpc2.Server
 	shutdown chan struct{}
 }
 
-func NewServer(sockPath string, mux handler.Map, opts *jrpc2.ServerOptions) *Server {
+type ServerOption func(*Server)
+
+// WithPeerContext sets a function that adds to a connection's context what
+// it derives from the peer's credentials. It runs once, when the connection
+// is accepted, and every request on the connection sees the values it adds.
+func WithPeerContext(fn func(ctx context.Context, cred *unix.Ucred) context.Context) ServerOption {
+	return func(s *Server) {
+		s.peerContext = fn
+	}
+}
+
+func NewServer(sockPath string, mux handler.Map, opts *jrpc2.ServerOptions, options ...ServerOption) *Server {
 	if opts == nil {
 		opts = &jrpc2.ServerOptions{}
 	}
-	return &Server{
+	s := &Server{
 		sockPath: sockPath,
 		mux:      mux,
 		opts:     opts,
 		servers:  make(map[net.Conn]*jrpc2.Server),
 		shutdown: make(chan struct{}),
 	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Server) Start() error {
@@ -83,7 +101,23 @@ func (s *Server) serveConn(conn net.Conn) {
 
 	ch := channel.Line(conn, conn)
 
-	srv := jrpc2.NewServer(s.mux, s.opts)
+	opts := *s.opts
+	if cred, err := peerCred(conn); err == nil {
+		connCtx := context.WithValue(context.Background(), peerCredKey{}, cred)
+		if s.peerContext != nil {
+			connCtx = s.peerContext(connCtx, cred)
+		}
+
+		newContext := opts.NewContext
+		opts.NewContext = func() context.Context {
+			if newContext == nil {
+				return connCtx
+			}
+			return connValues{Context: newContext(), conn: connCtx}
+		}
+	}
+
+	srv := jrpc2.NewServer(s.mux, &opts)
 
 	s.mu.Lock()
 	s.servers[conn] = srv
@@ -103,6 +137,50 @@ func (s *Server) serveConn(conn net.Conn) {
 	}
 }
 
+// connValues is a request context that also carries the values of the
+// connection the request arrived on
+type connValues struct {
+	context.Context
+	conn context.Context
+}
+
+func (c connValues) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.conn.Value(key)
+}
+
+type peerCredKey struct{}
+
+// PeerCred returns the credentials of the process that opened the
+// connection a request arrived on, as reported by SO_PEERCRED
+func PeerCred(ctx context.Context) (*unix.Ucred, bool) {
+	cred, ok := ctx.Value(peerCredKey{}).(*unix.Ucred)
+	return cred, ok
+}
+
+func peerCred(conn net.Conn) (*unix.Ucred, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("not a unix socket connection")
+	}
+
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	return cred, credErr
+}
+
 func (s *Server) Stop(ctx context.Context) error {
 	s.mu.Lock()
 	if !s.running {
@@ -1,11 +1,26 @@
 package rpc
 
 type PrismInfo struct {
-	Name     string `json:"name"`
+	Name     string `json:"name"` // instance ID
+	App      string `json:"app"`  // app the instance runs
 	PID      int    `json:"pid"`
 	State    string `json:"state"`     // "fg" or "bg"
 	UptimeMs int64  `json:"uptime_ms"` // milliseconds since start
 	Restarts int    `json:"restarts"`  // restart count
+
+	// Usage of the prism's process tree, from the last sample
+	Sampled    bool    `json:"sampled"`     // false until the first sample
+	CPUPercent float64 `json:"cpu_percent"` // percent of one core
+	RSSBytes   uint64  `json:"rss_bytes"`   // resident memory
+	Threads    int     `json:"threads"`
+
+	// Liveness probe result, empty Health when the app has no probe
+	Health       string `json:"health,omitempty"`        // "healthy" or "unhealthy"
+	HealthReason string `json:"health_reason,omitempty"` // last probe failure
+
+	// Set by the prism itself over the child socket
+	Status    string `json:"status,omitempty"`
+	Attention bool   `json:"attention,omitempty"` // requested while in the background
 }
 
 type PanelInfo struct {
@@ -14,23 +29,32 @@ type PanelInfo struct {
 	PID      int    `json:"pid"`      // prismctl process PID
 	Socket   string `json:"socket"`   // path to prismctl socket
 	Healthy  bool   `json:"healthy"`  // health check status
+
+	Unhealthy []string `json:"unhealthy,omitempty"` // prisms failing their liveness probe
+	Failed    []string `json:"failed,omitempty"`    // prisms that exceeded max_restarts
 }
 
 type UpRequest struct {
-	Name string `json:"name"`
+	Name        string `json:"name"`                   // instance ID, or app name with NewInstance
+	NewInstance bool   `json:"new_instance,omitempty"` // launch another copy even if one is running
 }
 
 type UpResult struct {
+	ID    string `json:"id"` // instance ID
 	PID   int    `json:"pid"`
 	State string `json:"state"` // "fg" or "bg"
 }
 
 type DownRequest struct {
 	Name string `json:"name"`
+	Wait bool   `json:"wait,omitempty"` // block until the process has exited and been reaped
 }
 
 type DownResult struct {
-	Stopped bool `json:"stopped"`
+	Stopped  bool `json:"stopped"`
+	Exited   bool `json:"exited,omitempty"`    // set when wait was requested
+	ExitCode int  `json:"exit_code,omitempty"` // exit status, 128+N when killed by signal N
+	Killed   bool `json:"killed,omitempty"`    // stop_timeout expired and SIGKILL was sent
 }
 
 type FgRequest struct {
@@ -47,18 +71,125 @@ type BgRequest struct {
 }
 
 type BgResult struct {
-	OK    bool `json:"ok"`
-	WasBg bool `json:"was_bg"` // true if already background (idempotent)
+	OK         bool   `json:"ok"`
+	WasBg      bool   `json:"was_bg"`               // true if already background (idempotent)
+	Foreground string `json:"foreground,omitempty"` // prism promoted in its place, empty = idle
 }
 
 type AppInfo struct {
+	Name    string            `json:"name"`
+	Path    string            `json:"path"` // resolved binary path
+	Enabled bool              `json:"enabled"`
+	Args    []string          `json:"args,omitempty"`   // argv after the binary, ~ and $VAR not yet expanded
+	Env     map[string]string `json:"env,omitempty"`    // added to the inherited environment
+	Cwd     string            `json:"cwd,omitempty"`    // working directory, empty = inherit
+	Size    int               `json:"size,omitempty"`   // fixed pane size in cells (split layouts)
+	Weight  int               `json:"weight,omitempty"` // share of remaining cells (split layouts)
+
+	StopSignal  string `json:"stop_signal,omitempty"`  // signal name, empty = SIGTERM
+	StopTimeout string `json:"stop_timeout,omitempty"` // duration before SIGKILL, e.g. "5s"
+
+	Liveness *LivenessInfo `json:"liveness,omitempty"` // nil = not probed
+
+	Background      string `json:"background,omitempty"`       // "suspend" (default) or "run"
+	LifecycleSignal string `json:"lifecycle_signal,omitempty"` // signal name, empty = none
+
+	// Passed to the app in SHINE_THEME and, as JSON, SHINE_SETTINGS
+	Theme    string                 `json:"theme,omitempty"`
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// VisibilityRequest reports the panel being hidden or shown, e.g. by a
+// keybinding that toggles it
+type VisibilityRequest struct {
+	Hidden bool `json:"hidden"`
+}
+
+type VisibilityResult struct {
+	Hidden  bool `json:"hidden"`
+	Changed bool `json:"changed"` // false when the panel already was
+}
+
+// LivenessInfo is an app's liveness probe, see config.LivenessConfig
+type LivenessInfo struct {
+	Probe    string   `json:"probe"`              // "output", "heartbeat" or "exec"
+	Command  []string `json:"command,omitempty"`  // exec probe argv
+	Interval string   `json:"interval,omitempty"` // duration, empty = 10s
+	Timeout  string   `json:"timeout,omitempty"`  // duration, empty = 5s
+	Failures int      `json:"failures,omitempty"` // 0 = 3
+	Action   string   `json:"action,omitempty"`   // "unhealthy" (default), "notify" or "restart"
+}
+
+type CaptureRequest struct {
+	Name   string `json:"name"`
+	Lines  int    `json:"lines,omitempty"`  // last N lines, 0 = everything kept
+	Bytes  int    `json:"bytes,omitempty"`  // last N bytes, takes precedence over lines
+	Strip  bool   `json:"strip,omitempty"`  // remove escape sequences
+	Screen bool   `json:"screen,omitempty"` // visible screen as text instead of output history
+}
+
+type CaptureResult struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+type SendKeysRequest struct {
+	Name    string   `json:"name"`
+	Keys    []string `json:"keys"`              // key names like "Enter" or "C-c", or literal text
+	Literal bool     `json:"literal,omitempty"` // send every key as literal text
+}
+
+type SendKeysResult struct {
+	Bytes int `json:"bytes"` // bytes written to the prism's PTY
+}
+
+type SignalRequest struct {
+	Name   string `json:"name"`
+	Signal string `json:"signal"` // signal name like "SIGUSR1" or "hup"
+}
+
+type SignalResult struct {
+	PID    int    `json:"pid"`    // process group that was signaled
+	Signal string `json:"signal"` // canonical signal name
+}
+
+type HistoryRequest struct {
+	Name string `json:"name,omitempty"` // only this prism's exits, empty = all
+}
+
+type ExitInfo struct {
+	Name       string `json:"name"`
+	PID        int    `json:"pid"`
+	ExitCode   int    `json:"exit_code"`             // exit status, 128+N when killed by signal N
+	Signal     string `json:"signal,omitempty"`      // signal that killed the prism, empty for a normal exit
+	CoreDumped bool   `json:"core_dumped,omitempty"` // the kernel wrote a core dump
+	RuntimeMs  int64  `json:"runtime_ms"`            // milliseconds the prism ran
+	ExitedMs   int64  `json:"exited_ms"`             // unix ms of the exit
+}
+
+type HistoryResult struct {
+	Exits []ExitInfo `json:"exits"` // most recent first
+}
+
+type RecordRequest struct {
 	Name    string `json:"name"`
-	Path    string `json:"path"`    // resolved binary path
-	Enabled bool   `json:"enabled"`
+	Enabled bool   `json:"enabled"` // true starts recording, false stops it
+}
+
+type RecordResult struct {
+	Recording bool   `json:"recording"`
+	Path      string `json:"path,omitempty"` // asciicast file being written or just finished
 }
 
 type ConfigureRequest struct {
-	Apps []AppInfo `json:"apps"`
+	Apps   []AppInfo `json:"apps"`             // in layout order
+	Layout string    `json:"layout,omitempty"` // "single", "hsplit" or "vsplit"
+	Prefix string    `json:"prefix,omitempty"` // prefix key like "C-a", empty = none
+	Record bool      `json:"record,omitempty"` // record every app to an asciicast file
+
+	KeepAlive       bool   `json:"keep_alive,omitempty"`       // stay open after the last app exits
+	Placeholder     string `json:"placeholder,omitempty"`      // "text" (default), "blank" or "apps"
+	PlaceholderText string `json:"placeholder_text,omitempty"` // text/template for the "text" placeholder
 }
 
 type ConfigureResult struct {
@@ -66,13 +197,23 @@ type ConfigureResult struct {
 	Failed  []string `json:"failed"`  // apps that failed to start
 }
 
+// ReconfigureResult reports what prism/reconfigure changed. Apps that are
+// in none of the lists were left alone.
+type ReconfigureResult struct {
+	Started []string `json:"started"` // added apps that were started
+	Stopped []string `json:"stopped"` // instances of removed apps that were stopped
+	Updated []string `json:"updated"` // apps with new settings, running instances are restarted
+	Failed  []string `json:"failed"`  // added apps that failed to start
+}
+
 type ListResult struct {
 	Prisms []PrismInfo `json:"prisms"`
 }
 
 type HealthResult struct {
-	Healthy    bool `json:"healthy"`
-	PrismCount int  `json:"prism_count"`
+	Healthy    bool     `json:"healthy"`
+	PrismCount int      `json:"prism_count"`
+	Unhealthy  []string `json:"unhealthy,omitempty"` // prisms failing their liveness probe
 }
 
 type ShutdownRequest struct {
@@ -104,6 +245,29 @@ type PanelKillResult struct {
 	Killed bool `json:"killed"`
 }
 
+// PanelResetFailedRequest clears the failed state of a panel's prism, or of
+// all its failed prisms when Name is empty
+type PanelResetFailedRequest struct {
+	Instance string `json:"instance"`
+	Name     string `json:"name,omitempty"`
+}
+
+type PanelResetFailedResult struct {
+	Reset []string `json:"reset"` // prisms cleared and started again
+}
+
+type EventsSubscribeResult struct {
+	Subscribed bool `json:"subscribed"`
+}
+
+// Event is pushed by shined as an "event" notification to clients that
+// called events/subscribe
+type Event struct {
+	Type    string `json:"type"`              // "config/reloaded" or "config/invalid"
+	Time    int64  `json:"time"`              // Unix time in milliseconds
+	Message string `json:"message,omitempty"` // what went wrong, for config/invalid
+}
+
 type ServiceStatusResult struct {
 	Panels  []PanelInfo `json:"panels"`
 	Uptime  int64       `json:"uptime_ms"`
@@ -122,9 +286,10 @@ type PrismStartedNotification struct {
 }
 
 type PrismStoppedNotification struct {
-	Panel    string `json:"panel"`
-	Name     string `json:"name"`
-	ExitCode int    `json:"exit_code"`
+	Panel     string `json:"panel"`
+	Name      string `json:"name"`
+	ExitCode  int    `json:"exit_code"`
+	Requested bool   `json:"requested,omitempty"` // stopped by prism/down or a key, whatever the exit code
 }
 
 type PrismCrashedNotification struct {
@@ -134,8 +299,49 @@ type PrismCrashedNotification struct {
 	Signal   int    `json:"signal,omitempty"`
 }
 
+type PrismHealthNotification struct {
+	Panel   string `json:"panel"`
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Reason  string `json:"reason,omitempty"` // why the liveness probe failed
+}
+
 type ForegroundChangedNotification struct {
 	Panel string `json:"panel"`
 	From  string `json:"from"` // previous foreground prism
 	To    string `json:"to"`   // new foreground prism
 }
+
+// Environment variables prismctl sets for every prism it starts
+const (
+	EnvPrism          = "SHINE_PRISM"           // the prism's instance ID
+	EnvApp            = "SHINE_APP"             // the app the instance runs
+	EnvPanel          = "SHINE_PANEL"           // the panel's instance name
+	EnvTheme          = "SHINE_THEME"           // unset when no theme is configured
+	EnvSettings       = "SHINE_SETTINGS"        // JSON object, unset when empty
+	EnvPrismctlSocket = "SHINE_PRISMCTL_SOCKET" // child socket, see ChildClient
+)
+
+type ChildStatusRequest struct {
+	Status string `json:"status"` // empty clears it
+}
+
+type ChildAttentionRequest struct {
+	Message string `json:"message,omitempty"`
+}
+
+// ChildResult names the prism a child socket request was attributed to
+type ChildResult struct {
+	Name string `json:"name"`
+}
+
+// LifecycleResult is where the calling prism stands: Foreground when it is
+// shown (in the foreground or a split layout pane), Focused when it also
+// has input and the panel has keyboard focus, Hidden when it is not on
+// screen, including while the panel is hidden
+type LifecycleResult struct {
+	Name       string `json:"name"`
+	Foreground bool   `json:"foreground"`
+	Focused    bool   `json:"focused"`
+	Hidden     bool   `json:"hidden"`
+}
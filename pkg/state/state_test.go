@@ -212,6 +212,49 @@ func TestPrismStateWriterSetForeground(t *testing.T) {
 	}
 }
 
+func TestPrismStateWriterSetUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "test.state")
+
+	writer, err := NewPrismStateWriter(statePath)
+	if err != nil {
+		t.Fatalf("NewPrismStateWriter() error: %v", err)
+	}
+	defer writer.Remove()
+
+	writer.AddPrism("clock", 1001, true)
+	writer.AddPrism("bar", 1002, false)
+
+	writer.SetUsage("bar", 12.34, 48*1024*1024, 5)
+
+	reader, err := OpenPrismStateReader(statePath)
+	if err != nil {
+		t.Fatalf("OpenPrismStateReader() error: %v", err)
+	}
+	defer reader.Close()
+
+	state, _ := reader.Read()
+	prisms := state.ActivePrisms()
+
+	if prisms[0].HasUsage() {
+		t.Error("clock should not have a usage sample yet")
+	}
+
+	bar := prisms[1]
+	if !bar.HasUsage() {
+		t.Fatal("bar should have a usage sample")
+	}
+	if got := bar.CPUPercent(); got != 12.3 {
+		t.Errorf("CPUPercent() = %v, want 12.3", got)
+	}
+	if got := bar.RSSBytes(); got != 48*1024*1024 {
+		t.Errorf("RSSBytes() = %d, want 48 MiB", got)
+	}
+	if bar.Threads != 5 {
+		t.Errorf("Threads = %d, want 5", bar.Threads)
+	}
+}
+
 func TestPrismStateWriterRemovePrism(t *testing.T) {
 	tmpDir := t.TempDir()
 	statePath := filepath.Join(tmpDir, "test.state")
@@ -450,8 +493,8 @@ func TestStructSizes(t *testing.T) {
 		size int
 		want int
 	}{
-		{"PrismEntry", int(PrismEntrySize), 80},
-		{"PrismRuntimeState", int(PrismRuntimeStateSize), 1424},
+		{"PrismEntry", int(PrismEntrySize), 96},
+		{"PrismRuntimeState", int(PrismRuntimeStateSize), 1680},
 		{"PanelEntry", int(PanelEntrySize), 136},
 		{"ShinedState", int(ShinedStateSize), 4368},
 	}
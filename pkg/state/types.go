@@ -7,9 +7,9 @@ import (
 )
 
 const (
-	PrismEntrySize        = 80   // bytes per prism entry
+	PrismEntrySize        = 96   // bytes per prism entry
 	MaxPrisms             = 16   // max prisms per prismctl instance
-	PrismRuntimeStateSize = 1424 // total size of PrismRuntimeState (includes alignment padding)
+	PrismRuntimeStateSize = 1680 // total size of PrismRuntimeState (includes alignment padding)
 
 	PanelEntrySize       = 136  // bytes per panel entry
 	MaxPanels            = 32   // max panels
@@ -37,13 +37,17 @@ func (s PrismEntryState) String() string {
 }
 
 type PrismEntry struct {
-	NameLen  uint8     // 1 byte: length of name
-	Name     [63]byte  // 63 bytes: name (null-padded)
-	PID      int32     // 4 bytes: process ID
-	State    uint8     // 1 byte: 0=bg, 1=fg
-	Restarts uint8     // 1 byte: restart count (capped at 255)
-	_padding [2]byte   // 2 bytes: padding for alignment
-	StartMs  int64     // 8 bytes: unix ms when started
+	NameLen   uint8     // 1 byte: length of name
+	Name      [63]byte  // 63 bytes: name (null-padded)
+	PID       int32     // 4 bytes: process ID
+	State     uint8     // 1 byte: 0=bg, 1=fg
+	Restarts  uint8     // 1 byte: restart count (capped at 255)
+	_padding  [2]byte   // 2 bytes: padding for alignment
+	StartMs   int64     // 8 bytes: unix ms when started
+	RSSKB     uint32    // 4 bytes: resident memory of the process tree in KiB
+	CPUTenths uint16    // 2 bytes: CPU usage of the process tree in 0.1% of one core
+	Threads   uint16    // 2 bytes: threads in the process tree
+	SampleMs  int64     // 8 bytes: unix ms of the last usage sample, 0 = not sampled yet
 }
 
 func (e *PrismEntry) GetName() string {
@@ -76,6 +80,21 @@ func (e *PrismEntry) IsActive() bool {
 	return e.PID != 0
 }
 
+// HasUsage reports whether CPU and memory usage have been sampled
+func (e *PrismEntry) HasUsage() bool {
+	return e.SampleMs != 0
+}
+
+// CPUPercent returns CPU usage in percent of one core
+func (e *PrismEntry) CPUPercent() float64 {
+	return float64(e.CPUTenths) / 10
+}
+
+// RSSBytes returns resident memory in bytes
+func (e *PrismEntry) RSSBytes() uint64 {
+	return uint64(e.RSSKB) * 1024
+}
+
 type PrismRuntimeState struct {
 	Version     uint64           // 8 bytes: sequence counter (odd=writing, even=complete)
 	InstanceLen uint8            // 1 byte: length of instance name
@@ -84,7 +103,7 @@ type PrismRuntimeState struct {
 	FgPrism     [63]byte         // 63 bytes: foreground prism name
 	PrismCount  uint8            // 1 byte: number of active prisms
 	_padding    [3]byte          // 3 bytes: padding for alignment
-	Prisms      [16]PrismEntry   // 16 * 80 = 1280 bytes
+	Prisms      [16]PrismEntry   // 16 * 96 = 1536 bytes
 }
 
 func (s *PrismRuntimeState) GetInstance() string {
@@ -2,6 +2,7 @@ package state
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -147,6 +148,28 @@ func (w *PrismStateWriter) AddPrism(name string, pid int32, fg bool) (int, error
 	return idx, nil
 }
 
+// SetUsage records a CPU and memory usage sample for the named prism
+func (w *PrismStateWriter) SetUsage(name string, cpuPercent float64, rssBytes uint64, threads int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.beginWrite()
+
+	for i := 0; i < int(w.ptr.PrismCount); i++ {
+		entry := &w.ptr.Prisms[i]
+		if entry.GetName() != name {
+			continue
+		}
+		entry.CPUTenths = uint16(min(cpuPercent*10+0.5, math.MaxUint16))
+		entry.RSSKB = uint32(min(rssBytes/1024, math.MaxUint32))
+		entry.Threads = uint16(min(threads, math.MaxUint16))
+		entry.SampleMs = time.Now().UnixMilli()
+		break
+	}
+
+	w.endWrite()
+}
+
 func (w *PrismStateWriter) beginWrite() {
 	v := atomic.LoadUint64(&w.ptr.Version)
 	atomic.StoreUint64(&w.ptr.Version, v+1)